@@ -0,0 +1,251 @@
+package bulk
+
+import (
+	"ah/database"
+	"ah/memory"
+	"context"
+	"errors"
+	. "github.com/onsi/gomega"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var expectedProviders = []database.Provider{
+	{ExternalID: "e1", Name: "p1", Address: database.Address{Lat: -26.66119, Long: 40.95858}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4.5, Materials: []database.FloorMaterial{database.FloorWood, database.FloorTile}},
+	{Name: "p2", Address: database.Address{Lat: 10, Long: -20}, Radius: 500, RadiusUnit: database.Metre},
+}
+
+func readAll(reader Reader) ([]database.Provider, []error) {
+	var (
+		providers []database.Provider
+		errs      []error
+	)
+	for {
+		_, p, err := reader.Read()
+		if err == io.EOF {
+			return providers, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		providers = append(providers, p)
+	}
+}
+
+func TestReadFormats(t *testing.T) {
+	RegisterTestingT(t)
+	files := map[Format]string{
+		CSV: `external_id,name,lat,long,operating_radius,radius_unit,rating,experience
+e1,p1,-26.66119,40.95858,10,km,4.5,wood;tile
+,p2,10,-20,500,m,,
+`,
+		JSON: `[
+  {"external_id":"e1","name":"p1","address":{"lat":-26.66119,"long":40.95858},"operating_radius":10,"rating":4.5,"experience":["wood","tile"]},
+  {"name":"p2","address":{"lat":10,"long":-20},"operating_radius":500,"radius_unit":"m"}
+]`,
+		NDJSON: `{"external_id":"e1","name":"p1","address":{"lat":-26.66119,"long":40.95858},"operating_radius":10,"radius_unit":"km","rating":4.5,"experience":["wood","tile"]}
+{"name":"p2","address":{"lat":10,"long":-20},"operating_radius":500,"radius_unit":"m"}
+`,
+		GeoJSON: `{"type":"FeatureCollection","features":[
+  {"type":"Feature","geometry":{"type":"Point","coordinates":[40.95858,-26.66119]},"properties":{"external_id":"e1","name":"p1","operating_radius":10,"rating":4.5,"experience":["wood","tile"]}},
+  {"type":"Feature","geometry":{"type":"Point","coordinates":[-20,10]},"properties":{"name":"p2","operating_radius":500,"radius_unit":"m"}}
+],"name":"providers"}`,
+	}
+	for format, file := range files {
+		reader, err := NewReader(strings.NewReader(file), format)
+		Expect(err).To(BeNil())
+		providers, errs := readAll(reader)
+		Expect(errs).To(BeEmpty(), string(format))
+		Expect(providers).To(HaveLen(2), string(format))
+		Expect(providers[0]).To(Equal(expectedProviders[0]), string(format))
+		Expect(providers[1].Address).To(Equal(expectedProviders[1].Address), string(format))
+		Expect(providers[1].Radius).To(Equal(expectedProviders[1].Radius), string(format))
+		Expect(providers[1].RadiusUnit).To(Equal(expectedProviders[1].RadiusUnit), string(format))
+	}
+}
+
+func TestReadRowErrors(t *testing.T) {
+	RegisterTestingT(t)
+	reader, err := NewReader(strings.NewReader(`name,lat,long,operating_radius,experience,rates,material_areas
+p1,north,40,10,,,
+p2,10,20
+p3,10,20,5,wood,wood:20,wood:50-
+p4,10,20,5,wood,wood=20,
+p5,10,20,5,wood,,wood:50
+`), CSV)
+	Expect(err).To(BeNil())
+	providers, errs := readAll(reader)
+	Expect(providers).To(HaveLen(1))
+	Expect(providers[0].Rates).To(Equal(map[database.FloorMaterial]float64{database.FloorWood: 20}))
+	Expect(providers[0].MaterialJobAreas).To(Equal(map[database.FloorMaterial]database.AreaRange{database.FloorWood: {Min: 50}}))
+	Expect(errs).To(HaveLen(4))
+	Expect(errs[0].(*RowError).Row).To(Equal(1))
+	Expect(errs[1].(*RowError).Row).To(Equal(2))
+	Expect(errs[2].(*RowError).Row).To(Equal(4))
+	Expect(errs[3].(*RowError).Row).To(Equal(5))
+
+	reader, err = NewReader(strings.NewReader(`{"name":"p1","address":{"lat":"north","long":40},"operating_radius":10}
+{"name":"p2","operating_radius":10}
+{"name":"p3","address":{"lat":10,"long":40},"operating_radius":10}
+{"name":"p4","address":{"lat":10,"long":40},"operating_radius":10,"blackouts":[{"from":"2024-07-01","to":"soon"}]}
+{"name":"p5","address":{"lat":10,"long":40},"operating_radius":10,"working_hours":[{"weekday":"someday","start":"08:00","end":"17:00"}]}
+`), NDJSON)
+	Expect(err).To(BeNil())
+	providers, errs = readAll(reader)
+	Expect(providers).To(HaveLen(1))
+	Expect(errs).To(HaveLen(4))
+
+	// resume time is RFC 3339 text kept in UTC
+	reader, err = NewReader(strings.NewReader(`name,lat,long,operating_radius,status,resume_at
+p1,10,20,5,paused,2024-08-15T06:30:00+02:00
+p2,10,20,5,paused,tomorrow
+p3,10,20,5,,
+`), CSV)
+	Expect(err).To(BeNil())
+	providers, errs = readAll(reader)
+	Expect(providers).To(HaveLen(2))
+	Expect(providers[0].Status).To(Equal(database.StatusPaused))
+	Expect(providers[0].ResumeAt).To(Equal(time.Date(2024, 8, 15, 4, 30, 0, 0, time.UTC)))
+	Expect(providers[1].Status).To(BeEmpty())
+	Expect(errs).To(HaveLen(1))
+	Expect(errs[0].(*RowError).Row).To(Equal(2))
+
+	_, err = NewReader(strings.NewReader("name,lat\n"), CSV)
+	Expect(errors.Is(err, ErrMalformedFile)).To(BeTrue())
+}
+
+func TestImport(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+	storage := memory.New()
+	file := `[
+  {"external_id":"e1","name":"p1","address":{"lat":1,"long":1},"operating_radius":10,"experience":["wood"]},
+  {"external_id":"e2","name":"p2","address":{"lat":2,"long":2},"operating_radius":10,"experience":["marble"]},
+  {"external_id":"e3","name":"","address":{"lat":3,"long":3},"operating_radius":10},
+  {"external_id":"e1","name":"p4","address":{"lat":4,"long":4},"operating_radius":10}
+]`
+	importFile := func(options database.ImportOptions) Report {
+		reader, err := NewReader(strings.NewReader(file), JSON)
+		Expect(err).To(BeNil())
+		report, err := Import(ctx, storage, reader, options)
+		Expect(err).To(BeNil())
+		return report
+	}
+
+	report := importFile(database.ImportOptions{DryRun: true})
+	Expect(report.Rows).To(Equal(4))
+	Expect(report.Created).To(Equal(1))
+	Expect(report.Failed).To(Equal(3))
+	Expect(report.Errors[0].Row).To(Equal(3))
+	Expect(errors.Is(report.Errors[0], database.ErrInvalid)).To(BeTrue())
+	Expect(report.Errors[1].Row).To(Equal(2))
+	Expect(errors.Is(report.Errors[1], database.ErrInvalid)).To(BeTrue())
+	Expect(report.Errors[2].Row).To(Equal(4))
+	Expect(report.Errors[2].ExternalID).To(Equal("e1"))
+	Expect(errors.Is(report.Errors[2], database.ErrDuplicateEntry)).To(BeTrue())
+	providers, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 1, Long: 1}})
+	Expect(err).To(BeNil())
+	Expect(providers).To(BeEmpty())
+
+	report = importFile(database.ImportOptions{})
+	Expect(report.Created).To(Equal(1))
+	Expect(report.Failed).To(Equal(3))
+
+	// with upsert the last row updates the first one
+	report = importFile(database.ImportOptions{Upsert: true})
+	Expect(report.Created).To(Equal(0))
+	Expect(report.Updated).To(Equal(2))
+	Expect(report.Failed).To(Equal(2))
+	provider, err := storage.GetProvider(ctx, 1)
+	Expect(err).To(BeNil())
+	Expect(provider.Name).To(Equal("p4"))
+	Expect(provider.Materials).To(BeEmpty())
+}
+
+func TestImportBatches(t *testing.T) {
+	RegisterTestingT(t)
+	var b strings.Builder
+	for i := 0; i < BatchSize*2+1; i++ {
+		b.WriteString(`{"name":"p","address":{"lat":1,"long":1},"operating_radius":10}` + "\n")
+	}
+	reader, err := NewReader(strings.NewReader(b.String()), NDJSON)
+	Expect(err).To(BeNil())
+	report, err := Import(context.Background(), memory.New(), reader, database.ImportOptions{})
+	Expect(err).To(BeNil())
+	Expect(report.Created).To(Equal(BatchSize*2 + 1))
+	Expect(report.Failed).To(BeZero())
+}
+
+func TestRoundTrip(t *testing.T) {
+	RegisterTestingT(t)
+	providers := []database.Provider{
+		{ID: 3, ExternalID: "e1", Name: `p1, "quoted"`, Address: database.Address{Lat: -26.66119, Long: 40.95858}, Radius: 10.25, RadiusUnit: database.Kilometre, Rating: 4.5, Materials: []database.FloorMaterial{database.FloorWood, database.FloorTile},
+			Rates: map[database.FloorMaterial]float64{database.FloorWood: 25.5, database.FloorTile: 40}, MinimumCharge: 300, TravelRate: 1.25,
+			JobArea: database.AreaRange{Min: 10, Max: 1000}, MaterialJobAreas: map[database.FloorMaterial]database.AreaRange{database.FloorWood: {Min: 50}, database.FloorTile: {Min: 5, Max: 200}},
+			MaterialRadii:   map[database.FloorMaterial]database.MaterialRadius{database.FloorTile: {Radius: 800, RadiusUnit: database.Metre}},
+			MaterialRatings: map[database.FloorMaterial]float64{database.FloorWood: 3.5, database.FloorTile: 5},
+			ServiceArea:     database.ServiceArea{{{{Lat: -27, Long: 40.5}, {Lat: -27, Long: 41.25}, {Lat: -26.5, Long: 41.25}, {Lat: -27, Long: 40.5}}}},
+			Branches:        []database.Branch{{Name: "depot, north", Address: database.Address{Lat: -26, Long: 41}, Radius: 25, RadiusUnit: database.Mile}},
+			Availability:    []database.DateRange{{From: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC)}},
+			Blackouts:       []database.DateRange{{From: time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC)}, {From: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 8, 7, 0, 0, 0, 0, time.UTC)}},
+			TimeZone:        "Africa/Maputo",
+			WorkingHours:    []database.WorkingHours{{Weekday: time.Monday, Start: 8 * 60, End: 17 * 60}, {Weekday: time.Saturday, Start: 9*60 + 30, End: 12 * 60}},
+			Status:          database.StatusPaused,
+			ResumeAt:        time.Date(2024, 8, 15, 6, 30, 0, 0, time.UTC)},
+		{ID: 7, Name: "p2", Address: database.Address{Lat: 89.9, Long: -179.99999}, Radius: 500, RadiusUnit: database.Mile, Status: database.StatusSuspended},
+	}
+	for _, format := range []Format{CSV, JSON, NDJSON, GeoJSON} {
+		var b strings.Builder
+		writer, err := NewWriter(&b, format)
+		Expect(err).To(BeNil())
+		for _, p := range providers {
+			Expect(writer.Write(p)).To(BeNil())
+		}
+		Expect(writer.Close()).To(BeNil())
+
+		reader, err := NewReader(strings.NewReader(b.String()), format)
+		Expect(err).To(BeNil())
+		res, errs := readAll(reader)
+		Expect(errs).To(BeEmpty(), string(format))
+		Expect(res).To(HaveLen(len(providers)), string(format))
+		for i, p := range providers {
+			// ids are not imported, providers are matched by external id
+			p.ID = 0
+			Expect(res[i]).To(Equal(p), string(format))
+		}
+
+		// an empty export is a valid empty file
+		b.Reset()
+		writer, err = NewWriter(&b, format)
+		Expect(err).To(BeNil())
+		Expect(writer.Close()).To(BeNil())
+		reader, err = NewReader(strings.NewReader(b.String()), format)
+		Expect(err).To(BeNil())
+		res, errs = readAll(reader)
+		Expect(errs).To(BeEmpty(), string(format))
+		Expect(res).To(BeEmpty(), string(format))
+	}
+}
+
+func TestExport(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+	storage := memory.New()
+	for _, p := range expectedProviders {
+		_, err := storage.AddProvider(ctx, p)
+		Expect(err).To(BeNil())
+	}
+	var b strings.Builder
+	writer, err := NewWriter(&b, CSV)
+	Expect(err).To(BeNil())
+	count, err := Export(ctx, storage, writer)
+	Expect(err).To(BeNil())
+	Expect(count).To(Equal(2))
+	Expect(b.String()).To(Equal(`id,external_id,name,lat,long,operating_radius,radius_unit,rating,experience,rates,minimum_charge,travel_rate,min_area,max_area,material_areas,material_radii,material_ratings,service_area,branches,availability,blackouts,time_zone,working_hours,status,resume_at
+1,e1,p1,-26.66119,40.95858,10,km,4.5,wood;tile,,0,0,0,0,,,,,,,,,,active,
+2,,p2,10,-20,500,m,0,,,0,0,0,0,,,,,,,,,,active,
+`))
+}
@@ -0,0 +1,25 @@
+package bulk
+
+import (
+	"ah/database"
+	"context"
+)
+
+// Exporter streams all providers ordered by id, implemented by storages
+type Exporter interface {
+	ExportProviders(ctx context.Context, f func(database.Provider) error) error
+}
+
+// Export writes every provider to writer and finishes the file, returning number of written providers.
+// the file is left unfinished when an error is returned
+func Export(ctx context.Context, exporter Exporter, writer Writer) (int, error) {
+	count := 0
+	err := exporter.ExportProviders(ctx, func(p database.Provider) error {
+		count++
+		return writer.Write(p)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
@@ -0,0 +1,381 @@
+// Package bulk reads and writes providers in portable file formats for import and export
+package bulk
+
+import (
+	"ah/database"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Format is a file format providers are stored in
+type Format string
+
+// supported formats
+const (
+	// CSV is comma separated values with a header row, materials are separated by semicolons
+	// and rates are given as material:rate pairs separated by semicolons, material areas as material:min-max
+	// separated by semicolons where max is left empty when there is no upper limit, material radii as
+	// material:radius:unit where unit may be left out and material ratings as material:rating. service area
+	// is GeoJSON text, branches are a json array and availability and blackouts are from/to date ranges
+	// separated by semicolons, working hours are given as weekday start-end separated by semicolons and resume time
+	// of a paused provider is RFC 3339 text
+	CSV Format = "csv"
+	// JSON is an array of provider objects
+	JSON Format = "json"
+	// NDJSON is one provider object per line
+	NDJSON Format = "ndjson"
+	// GeoJSON is a FeatureCollection of points with provider fields as properties
+	GeoJSON Format = "geojson"
+)
+
+// defaultRadiusUnit is unit of operating radius when not specified, same as api
+const defaultRadiusUnit = database.Kilometre
+
+// ParseFormat returns format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case CSV, JSON, NDJSON, GeoJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, supported formats: csv, json, ndjson, geojson", name)
+	}
+}
+
+// FormatOf guesses format of a file by its extension
+func FormatOf(fileName string) (Format, error) {
+	ext := strings.TrimPrefix(path.Ext(fileName), ".")
+	if ext == "jsonl" {
+		return NDJSON, nil
+	}
+	return ParseFormat(ext)
+}
+
+// ContentType returns media type of files in format
+func (format Format) ContentType() string {
+	switch format {
+	case CSV:
+		return "text/csv"
+	case NDJSON:
+		return "application/x-ndjson"
+	case GeoJSON:
+		return "application/geo+json"
+	default:
+		return "application/json"
+	}
+}
+
+// Record is a provider as stored in files, fields are named the same as api
+type Record struct {
+	ID              database.ID `json:"id,omitempty"`
+	ExternalID      string      `json:"external_id,omitempty"`
+	Name            string      `json:"name"`
+	Experience      []string    `json:"experience"`
+	Address         *Address    `json:"address,omitempty"`
+	OperatingRadius float64     `json:"operating_radius"`
+	RadiusUnit      string      `json:"radius_unit,omitempty"`
+	Rating          float64     `json:"rating"`
+	// Rates are prices per square metre by material
+	Rates         map[string]float64 `json:"rates,omitempty"`
+	MinimumCharge float64            `json:"minimum_charge,omitempty"`
+	TravelRate    float64            `json:"travel_rate,omitempty"`
+	MinArea       float64            `json:"min_area,omitempty"`
+	MaxArea       float64            `json:"max_area,omitempty"`
+	// MaterialAreas replace min and max area for jobs with some materials
+	MaterialAreas map[string]JobArea `json:"material_areas,omitempty"`
+	// MaterialRadii and MaterialRatings replace operating radius and rating for jobs with some materials
+	MaterialRadii   map[string]MaterialRadius `json:"material_radii,omitempty"`
+	MaterialRatings map[string]float64        `json:"material_ratings,omitempty"`
+	// ServiceArea is a GeoJSON Polygon or MultiPolygon geometry
+	ServiceArea json.RawMessage `json:"service_area,omitempty"`
+	Branches    []Branch        `json:"branches,omitempty"`
+	// Availability are date ranges provider can start jobs in, Blackouts are ones it can not
+	Availability []DateRange `json:"availability,omitempty"`
+	Blackouts    []DateRange `json:"blackouts,omitempty"`
+	// WorkingHours are weekly hours in TimeZone customers can book appointments in
+	TimeZone     string         `json:"time_zone,omitempty"`
+	WorkingHours []WorkingHours `json:"working_hours,omitempty"`
+	// Status is kept by existing providers and pending for new ones when empty, ResumeAt is only set for paused ones
+	Status   string     `json:"status,omitempty"`
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+}
+
+// WorkingHours are hours of a weekday, times are formatted as 15:04
+type WorkingHours struct {
+	Weekday string `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// DateRange is a range of days with both ends included, dates are formatted as 2006-01-02
+type DateRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Branch is a depot of a provider with its own operating radius
+type Branch struct {
+	Name            string  `json:"name"`
+	Address         Address `json:"address"`
+	OperatingRadius float64 `json:"operating_radius"`
+	RadiusUnit      string  `json:"radius_unit,omitempty"`
+}
+
+// MaterialRadius is operating radius for jobs with a material
+type MaterialRadius struct {
+	OperatingRadius float64 `json:"operating_radius"`
+	RadiusUnit      string  `json:"radius_unit,omitempty"`
+}
+
+// JobArea limits area of jobs in square metres, zero max area means no upper limit
+type JobArea struct {
+	MinArea float64 `json:"min_area"`
+	MaxArea float64 `json:"max_area,omitempty"`
+}
+
+// Address is a location on map
+type Address struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
+}
+
+// toProvider converts record to a provider, id is not kept since providers are matched by external id
+func (r Record) toProvider() (database.Provider, error) {
+	if r.Address == nil {
+		return database.Provider{}, fmt.Errorf("%w: address is required", database.ErrInvalid)
+	}
+	p := database.Provider{
+		ExternalID:    r.ExternalID,
+		Name:          r.Name,
+		Address:       database.Address{Lat: r.Address.Lat, Long: r.Address.Long},
+		Radius:        r.OperatingRadius,
+		RadiusUnit:    database.DistanceUnit(r.RadiusUnit),
+		Rating:        r.Rating,
+		MinimumCharge: r.MinimumCharge,
+		TravelRate:    r.TravelRate,
+		JobArea:       database.AreaRange{Min: r.MinArea, Max: r.MaxArea},
+	}
+	if p.RadiusUnit == "" {
+		p.RadiusUnit = defaultRadiusUnit
+	}
+	for _, material := range r.Experience {
+		p.Materials = append(p.Materials, database.FloorMaterial(material))
+	}
+	if len(r.Rates) != 0 {
+		p.Rates = make(map[database.FloorMaterial]float64, len(r.Rates))
+		for material, rate := range r.Rates {
+			p.Rates[database.FloorMaterial(material)] = rate
+		}
+	}
+	if len(r.MaterialAreas) != 0 {
+		p.MaterialJobAreas = make(map[database.FloorMaterial]database.AreaRange, len(r.MaterialAreas))
+		for material, area := range r.MaterialAreas {
+			p.MaterialJobAreas[database.FloorMaterial(material)] = database.AreaRange{Min: area.MinArea, Max: area.MaxArea}
+		}
+	}
+	if len(r.MaterialRadii) != 0 {
+		p.MaterialRadii = make(map[database.FloorMaterial]database.MaterialRadius, len(r.MaterialRadii))
+		for material, radius := range r.MaterialRadii {
+			unit := database.DistanceUnit(radius.RadiusUnit)
+			if unit == "" {
+				unit = defaultRadiusUnit
+			}
+			p.MaterialRadii[database.FloorMaterial(material)] = database.MaterialRadius{Radius: radius.OperatingRadius, RadiusUnit: unit}
+		}
+	}
+	if len(r.MaterialRatings) != 0 {
+		p.MaterialRatings = make(map[database.FloorMaterial]float64, len(r.MaterialRatings))
+		for material, rating := range r.MaterialRatings {
+			p.MaterialRatings[database.FloorMaterial(material)] = rating
+		}
+	}
+	if len(r.ServiceArea) != 0 && string(r.ServiceArea) != "null" {
+		area, err := database.ParseServiceArea(r.ServiceArea)
+		if err != nil {
+			return database.Provider{}, err
+		}
+		p.ServiceArea = area
+	}
+	for _, branch := range r.Branches {
+		dbBranch := database.Branch{
+			Name:       branch.Name,
+			Address:    database.Address{Lat: branch.Address.Lat, Long: branch.Address.Long},
+			Radius:     branch.OperatingRadius,
+			RadiusUnit: database.DistanceUnit(branch.RadiusUnit),
+		}
+		if dbBranch.RadiusUnit == "" {
+			dbBranch.RadiusUnit = defaultRadiusUnit
+		}
+		p.Branches = append(p.Branches, dbBranch)
+	}
+	var err error
+	if p.Availability, err = toDateRanges(r.Availability); err != nil {
+		return database.Provider{}, err
+	}
+	if p.Blackouts, err = toDateRanges(r.Blackouts); err != nil {
+		return database.Provider{}, err
+	}
+	p.TimeZone = r.TimeZone
+	if p.WorkingHours, err = toWorkingHours(r.WorkingHours); err != nil {
+		return database.Provider{}, err
+	}
+	p.Status = database.ProviderStatus(r.Status)
+	if r.ResumeAt != nil {
+		p.ResumeAt = r.ResumeAt.UTC()
+	}
+	return p, nil
+}
+
+func toWorkingHours(hours []WorkingHours) ([]database.WorkingHours, error) {
+	var res []database.WorkingHours
+	for _, h := range hours {
+		weekday, ok := database.ParseWeekday(h.Weekday)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid weekday %q", database.ErrInvalid, h.Weekday)
+		}
+		start, err := database.ParseClock(h.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := database.ParseClock(h.End)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, database.WorkingHours{Weekday: weekday, Start: start, End: end})
+	}
+	return res, nil
+}
+
+func fromWorkingHours(hours []database.WorkingHours) []WorkingHours {
+	var res []WorkingHours
+	for _, h := range hours {
+		res = append(res, WorkingHours{Weekday: strings.ToLower(h.Weekday.String()), Start: database.FormatClock(h.Start), End: database.FormatClock(h.End)})
+	}
+	return res
+}
+
+func toDateRanges(ranges []DateRange) ([]database.DateRange, error) {
+	var res []database.DateRange
+	for _, r := range ranges {
+		from, err := time.Parse(database.DateLayout, r.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %q", database.ErrInvalid, r.From)
+		}
+		to, err := time.Parse(database.DateLayout, r.To)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %q", database.ErrInvalid, r.To)
+		}
+		res = append(res, database.DateRange{From: from, To: to})
+	}
+	return res, nil
+}
+
+func fromDateRanges(ranges []database.DateRange) []DateRange {
+	var res []DateRange
+	for _, r := range ranges {
+		res = append(res, DateRange{From: r.From.Format(database.DateLayout), To: r.To.Format(database.DateLayout)})
+	}
+	return res
+}
+
+// fromProvider converts a provider to a record
+func fromProvider(p database.Provider) Record {
+	r := Record{
+		ID:              p.ID,
+		ExternalID:      p.ExternalID,
+		Name:            p.Name,
+		Experience:      []string{},
+		Address:         &Address{Lat: p.Address.Lat, Long: p.Address.Long},
+		OperatingRadius: p.Radius,
+		RadiusUnit:      string(p.RadiusUnit),
+		Rating:          p.Rating,
+		MinimumCharge:   p.MinimumCharge,
+		TravelRate:      p.TravelRate,
+		MinArea:         p.JobArea.Min,
+		MaxArea:         p.JobArea.Max,
+	}
+	for _, material := range p.Materials {
+		r.Experience = append(r.Experience, string(material))
+	}
+	if len(p.Rates) != 0 {
+		r.Rates = make(map[string]float64, len(p.Rates))
+		for material, rate := range p.Rates {
+			r.Rates[string(material)] = rate
+		}
+	}
+	if len(p.MaterialJobAreas) != 0 {
+		r.MaterialAreas = make(map[string]JobArea, len(p.MaterialJobAreas))
+		for material, area := range p.MaterialJobAreas {
+			r.MaterialAreas[string(material)] = JobArea{MinArea: area.Min, MaxArea: area.Max}
+		}
+	}
+	if len(p.MaterialRadii) != 0 {
+		r.MaterialRadii = make(map[string]MaterialRadius, len(p.MaterialRadii))
+		for material, radius := range p.MaterialRadii {
+			r.MaterialRadii[string(material)] = MaterialRadius{OperatingRadius: radius.Radius, RadiusUnit: string(radius.RadiusUnit)}
+		}
+	}
+	if len(p.MaterialRatings) != 0 {
+		r.MaterialRatings = make(map[string]float64, len(p.MaterialRatings))
+		for material, rating := range p.MaterialRatings {
+			r.MaterialRatings[string(material)] = rating
+		}
+	}
+	if len(p.ServiceArea) != 0 {
+		r.ServiceArea = p.ServiceArea.GeoJSON()
+	}
+	for _, branch := range p.Branches {
+		r.Branches = append(r.Branches, Branch{
+			Name:            branch.Name,
+			Address:         Address{Lat: branch.Address.Lat, Long: branch.Address.Long},
+			OperatingRadius: branch.Radius,
+			RadiusUnit:      string(branch.RadiusUnit),
+		})
+	}
+	r.Availability = fromDateRanges(p.Availability)
+	r.Blackouts = fromDateRanges(p.Blackouts)
+	r.TimeZone = p.TimeZone
+	r.WorkingHours = fromWorkingHours(p.WorkingHours)
+	r.Status = string(p.Status)
+	if !p.ResumeAt.IsZero() {
+		resumeAt := p.ResumeAt.UTC()
+		r.ResumeAt = &resumeAt
+	}
+	return r
+}
+
+// feature is a GeoJSON feature holding a provider
+type feature struct {
+	Type     string   `json:"type"`
+	Geometry geometry `json:"geometry"`
+	// Properties has no address, location is kept in geometry
+	Properties Record `json:"properties"`
+}
+
+// geometry is a GeoJSON point, coordinates are longitude then latitude
+type geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// toFeature moves address of record to geometry of a feature
+func (r Record) toFeature() feature {
+	f := feature{
+		Type:     "Feature",
+		Geometry: geometry{Type: "Point", Coordinates: []float64{r.Address.Long, r.Address.Lat}},
+	}
+	r.Address = nil
+	f.Properties = r
+	return f
+}
+
+func (f feature) toRecord() (Record, error) {
+	if f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) != 2 {
+		return Record{}, fmt.Errorf("%w: geometry should be a point", database.ErrInvalid)
+	}
+	r := f.Properties
+	r.Address = &Address{Lat: f.Geometry.Coordinates[1], Long: f.Geometry.Coordinates[0]}
+	return r, nil
+}
@@ -0,0 +1,97 @@
+package bulk
+
+import (
+	"ah/database"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMalformedFile is returned when a file can not be read any further
+var ErrMalformedFile = errors.New("malformed file")
+
+// BatchSize is number of providers saved in each transaction
+const BatchSize = 500
+
+// Importer saves batches of providers, implemented by storages
+type Importer interface {
+	ImportProviders(ctx context.Context, providers []database.Provider, options database.ImportOptions) ([]database.ImportResult, error)
+}
+
+// Report summarizes an import, every failed row has an entry in Errors
+type Report struct {
+	Rows    int         `json:"rows"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Failed  int         `json:"failed"`
+	DryRun  bool        `json:"dry_run"`
+	Errors  []*RowError `json:"errors"`
+}
+
+func (report *Report) fail(err *RowError) {
+	report.Failed++
+	report.Errors = append(report.Errors, err)
+}
+
+// Import reads all providers from reader and saves them in batches, invalid rows are reported and skipped.
+// an error is returned if reading or saving can not continue, batches saved before it are kept
+func Import(ctx context.Context, importer Importer, reader Reader, options database.ImportOptions) (Report, error) {
+	report := Report{DryRun: options.DryRun, Errors: []*RowError{}}
+	var (
+		batch []database.Provider
+		rows  []int
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := importer.ImportProviders(ctx, batch, options)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			switch {
+			case result.Err != nil:
+				report.fail(&RowError{Row: rows[i], ExternalID: batch[i].ExternalID, Err: result.Err})
+			case result.Updated:
+				report.Updated++
+			default:
+				report.Created++
+			}
+		}
+		batch, rows = batch[:0], rows[:0]
+		return nil
+	}
+
+	for {
+		row, p, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var rowErr *RowError
+			if !errors.As(err, &rowErr) {
+				return report, fmt.Errorf("%w: %v", ErrMalformedFile, err)
+			}
+			report.Rows++
+			report.fail(rowErr)
+			continue
+		}
+		report.Rows++
+		err = p.Validate()
+		if err != nil {
+			report.fail(&RowError{Row: row, ExternalID: p.ExternalID, Err: err})
+			continue
+		}
+		batch = append(batch, p)
+		rows = append(rows, row)
+		if len(batch) == BatchSize {
+			err = flush()
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, flush()
+}
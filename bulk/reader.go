@@ -0,0 +1,476 @@
+package bulk
+
+import (
+	"ah/database"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reader streams providers from a file
+type Reader interface {
+	// Read returns next provider and its row number starting from 1, io.EOF is returned after the last row.
+	// a *RowError is returned for an invalid row and reading can continue, other errors are fatal
+	Read() (int, database.Provider, error)
+}
+
+// RowError is an error in a single row of a file
+type RowError struct {
+	Row        int
+	ExternalID string
+	Err        error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON formats error as an entry of import report
+func (e *RowError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Row        int    `json:"row"`
+		ExternalID string `json:"external_id,omitempty"`
+		Error      string `json:"error"`
+	}{Row: e.Row, ExternalID: e.ExternalID, Error: e.Err.Error()})
+}
+
+// NewReader returns a reader of providers stored in format
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case CSV:
+		reader, err := newCSVReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedFile, err)
+		}
+		return reader, nil
+	case JSON:
+		return &jsonReader{decoder: json.NewDecoder(r), array: true}, nil
+	case NDJSON:
+		return &jsonReader{decoder: json.NewDecoder(r)}, nil
+	case GeoJSON:
+		return &geoJSONReader{decoder: json.NewDecoder(r)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// recordToProvider converts a decoded record, reporting conversion errors as row errors
+func recordToProvider(row int, record Record, err error) (int, database.Provider, error) {
+	if err != nil {
+		return row, database.Provider{}, &RowError{Row: row, ExternalID: record.ExternalID, Err: err}
+	}
+	p, err := record.toProvider()
+	if err != nil {
+		return row, database.Provider{}, &RowError{Row: row, ExternalID: record.ExternalID, Err: err}
+	}
+	return row, p, nil
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header failed: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "lat", "long", "operating_radius"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header has no %s column", name)
+		}
+	}
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvReader) Read() (int, database.Provider, error) {
+	fields, err := r.reader.Read()
+	if err == io.EOF {
+		return 0, database.Provider{}, io.EOF
+	}
+	r.row++
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			return r.row, database.Provider{}, &RowError{Row: r.row, Err: err}
+		}
+		return r.row, database.Provider{}, err
+	}
+	record, err := r.parse(fields)
+	return recordToProvider(r.row, record, err)
+}
+
+func (r *csvReader) parse(fields []string) (Record, error) {
+	get := func(name string) string {
+		i, ok := r.columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+	number := func(name string, required bool) (float64, error) {
+		value := get(name)
+		if value == "" && !required {
+			return 0, nil
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid %s %q", database.ErrInvalid, name, value)
+		}
+		return n, nil
+	}
+
+	record := Record{
+		ExternalID: get("external_id"),
+		Name:       get("name"),
+		RadiusUnit: get("radius_unit"),
+		Address:    &Address{},
+		Experience: []string{},
+	}
+	var err error
+	if record.Address.Lat, err = number("lat", true); err != nil {
+		return record, err
+	}
+	if record.Address.Long, err = number("long", true); err != nil {
+		return record, err
+	}
+	if record.OperatingRadius, err = number("operating_radius", true); err != nil {
+		return record, err
+	}
+	if record.Rating, err = number("rating", false); err != nil {
+		return record, err
+	}
+	if record.MinimumCharge, err = number("minimum_charge", false); err != nil {
+		return record, err
+	}
+	if record.TravelRate, err = number("travel_rate", false); err != nil {
+		return record, err
+	}
+	if record.MinArea, err = number("min_area", false); err != nil {
+		return record, err
+	}
+	if record.MaxArea, err = number("max_area", false); err != nil {
+		return record, err
+	}
+	for _, material := range strings.Split(get("experience"), ";") {
+		if material = strings.TrimSpace(material); material != "" {
+			record.Experience = append(record.Experience, material)
+		}
+	}
+	for _, pair := range strings.Split(get("rates"), ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.Index(pair, ":")
+		if i < 0 {
+			return record, fmt.Errorf("%w: invalid rate %q, expected material:rate", database.ErrInvalid, pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(pair[i+1:]), 64)
+		if err != nil {
+			return record, fmt.Errorf("%w: invalid rate %q", database.ErrInvalid, pair)
+		}
+		if record.Rates == nil {
+			record.Rates = map[string]float64{}
+		}
+		record.Rates[strings.TrimSpace(pair[:i])] = rate
+	}
+	for _, pair := range strings.Split(get("material_areas"), ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		material, area, err := parseMaterialArea(pair)
+		if err != nil {
+			return record, err
+		}
+		if record.MaterialAreas == nil {
+			record.MaterialAreas = map[string]JobArea{}
+		}
+		record.MaterialAreas[material] = area
+	}
+	for _, pair := range strings.Split(get("material_radii"), ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		material, radius, err := parseMaterialRadius(pair)
+		if err != nil {
+			return record, err
+		}
+		if record.MaterialRadii == nil {
+			record.MaterialRadii = map[string]MaterialRadius{}
+		}
+		record.MaterialRadii[material] = radius
+	}
+	for _, pair := range strings.Split(get("material_ratings"), ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.Index(pair, ":")
+		if i < 0 {
+			return record, fmt.Errorf("%w: invalid material rating %q, expected material:rating", database.ErrInvalid, pair)
+		}
+		rating, err := strconv.ParseFloat(strings.TrimSpace(pair[i+1:]), 64)
+		if err != nil {
+			return record, fmt.Errorf("%w: invalid material rating %q", database.ErrInvalid, pair)
+		}
+		if record.MaterialRatings == nil {
+			record.MaterialRatings = map[string]float64{}
+		}
+		record.MaterialRatings[strings.TrimSpace(pair[:i])] = rating
+	}
+	if area := get("service_area"); area != "" {
+		record.ServiceArea = json.RawMessage(area)
+	}
+	if branches := get("branches"); branches != "" {
+		if err := json.Unmarshal([]byte(branches), &record.Branches); err != nil {
+			return record, fmt.Errorf("%w: invalid branches: %v", database.ErrInvalid, err)
+		}
+	}
+	if record.Availability, err = parseDateRanges(get("availability")); err != nil {
+		return record, err
+	}
+	if record.Blackouts, err = parseDateRanges(get("blackouts")); err != nil {
+		return record, err
+	}
+	record.TimeZone = get("time_zone")
+	if record.WorkingHours, err = parseWorkingHours(get("working_hours")); err != nil {
+		return record, err
+	}
+	record.Status = get("status")
+	if value := get("resume_at"); value != "" {
+		resumeAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return record, fmt.Errorf("%w: invalid resume_at %q", database.ErrInvalid, value)
+		}
+		record.ResumeAt = &resumeAt
+	}
+	return record, nil
+}
+
+// parseWorkingHours parses weekday start-end hours separated by semicolons, times are checked on conversion to provider
+func parseWorkingHours(value string) ([]WorkingHours, error) {
+	var hours []WorkingHours
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		fields := strings.Fields(item)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: invalid working hours %q, expected weekday start-end", database.ErrInvalid, item)
+		}
+		times := strings.Split(fields[1], "-")
+		if len(times) != 2 {
+			return nil, fmt.Errorf("%w: invalid working hours %q, expected weekday start-end", database.ErrInvalid, item)
+		}
+		hours = append(hours, WorkingHours{Weekday: fields[0], Start: times[0], End: times[1]})
+	}
+	return hours, nil
+}
+
+// parseDateRanges parses from/to date ranges separated by semicolons, dates are checked on conversion to provider
+func parseDateRanges(value string) ([]DateRange, error) {
+	var ranges []DateRange
+	for _, pair := range strings.Split(value, ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		dates := strings.Split(pair, "/")
+		if len(dates) != 2 {
+			return nil, fmt.Errorf("%w: invalid date range %q, expected from/to", database.ErrInvalid, pair)
+		}
+		ranges = append(ranges, DateRange{From: strings.TrimSpace(dates[0]), To: strings.TrimSpace(dates[1])})
+	}
+	return ranges, nil
+}
+
+// parseMaterialArea parses a material:min-max pair, max is empty when there is no upper limit
+func parseMaterialArea(pair string) (string, JobArea, error) {
+	invalid := fmt.Errorf("%w: invalid material area %q, expected material:min-max", database.ErrInvalid, pair)
+	i := strings.Index(pair, ":")
+	if i < 0 {
+		return "", JobArea{}, invalid
+	}
+	limits := strings.SplitN(pair[i+1:], "-", 2)
+	if len(limits) != 2 {
+		return "", JobArea{}, invalid
+	}
+	var area JobArea
+	var err error
+	if area.MinArea, err = strconv.ParseFloat(strings.TrimSpace(limits[0]), 64); err != nil {
+		return "", JobArea{}, invalid
+	}
+	if upper := strings.TrimSpace(limits[1]); upper != "" {
+		if area.MaxArea, err = strconv.ParseFloat(upper, 64); err != nil {
+			return "", JobArea{}, invalid
+		}
+	}
+	return strings.TrimSpace(pair[:i]), area, nil
+}
+
+// parseMaterialRadius parses a material:radius:unit triple, unit may be left out
+func parseMaterialRadius(pair string) (string, MaterialRadius, error) {
+	parts := strings.Split(pair, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", MaterialRadius{}, fmt.Errorf("%w: invalid material radius %q, expected material:radius:unit", database.ErrInvalid, pair)
+	}
+	var radius MaterialRadius
+	var err error
+	if radius.OperatingRadius, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		return "", MaterialRadius{}, fmt.Errorf("%w: invalid material radius %q", database.ErrInvalid, pair)
+	}
+	if len(parts) == 3 {
+		radius.RadiusUnit = strings.TrimSpace(parts[2])
+	}
+	return strings.TrimSpace(parts[0]), radius, nil
+}
+
+// jsonReader reads a json array of records or one record per line
+type jsonReader struct {
+	decoder *json.Decoder
+	array   bool
+	started bool
+	row     int
+}
+
+func (r *jsonReader) Read() (int, database.Provider, error) {
+	if r.array && !r.started {
+		r.started = true
+		err := expectDelim(r.decoder, '[')
+		if err != nil {
+			return 0, database.Provider{}, err
+		}
+	}
+	if r.array && !r.decoder.More() {
+		err := expectDelim(r.decoder, ']')
+		if err != nil {
+			return 0, database.Provider{}, err
+		}
+		return 0, database.Provider{}, io.EOF
+	}
+	var record Record
+	err := r.decoder.Decode(&record)
+	if err == io.EOF && !r.array {
+		return 0, database.Provider{}, io.EOF
+	}
+	r.row++
+	if err != nil && !isValueError(err) {
+		return r.row, database.Provider{}, err
+	}
+	return recordToProvider(r.row, record, err)
+}
+
+// geoJSONReader reads features of a FeatureCollection
+type geoJSONReader struct {
+	decoder    *json.Decoder
+	inFeatures bool
+	done       bool
+	row        int
+}
+
+func (r *geoJSONReader) Read() (int, database.Provider, error) {
+	if r.done {
+		return 0, database.Provider{}, io.EOF
+	}
+	if !r.inFeatures {
+		err := r.findFeatures()
+		if err != nil {
+			return 0, database.Provider{}, err
+		}
+	}
+	if !r.decoder.More() {
+		// rest of collection after features is skipped
+		err := expectDelim(r.decoder, ']')
+		if err == nil {
+			err = r.skipMembers()
+		}
+		if err != nil {
+			return 0, database.Provider{}, err
+		}
+		r.done = true
+		return 0, database.Provider{}, io.EOF
+	}
+	var f feature
+	err := r.decoder.Decode(&f)
+	r.row++
+	if err != nil && !isValueError(err) {
+		return r.row, database.Provider{}, err
+	}
+	var record Record
+	if err == nil {
+		record, err = f.toRecord()
+	}
+	return recordToProvider(r.row, record, err)
+}
+
+// findFeatures advances decoder to the first feature, members of collection before features are skipped
+func (r *geoJSONReader) findFeatures() error {
+	err := expectDelim(r.decoder, '{')
+	if err != nil {
+		return err
+	}
+	for r.decoder.More() {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if token == "features" {
+			r.inFeatures = true
+			return expectDelim(r.decoder, '[')
+		}
+		var skipped json.RawMessage
+		err = r.decoder.Decode(&skipped)
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("geojson has no features")
+}
+
+// skipMembers skips members of collection after features until its end
+func (r *geoJSONReader) skipMembers() error {
+	for r.decoder.More() {
+		_, err := r.decoder.Token()
+		if err != nil {
+			return err
+		}
+		var skipped json.RawMessage
+		err = r.decoder.Decode(&skipped)
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(r.decoder, '}')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %s but found %v", delim, token)
+	}
+	return nil
+}
+
+// isValueError reports whether err is caused by a value of wrong type, decoder can continue after such errors
+func isValueError(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr)
+}
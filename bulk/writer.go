@@ -0,0 +1,198 @@
+package bulk
+
+import (
+	"ah/database"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writer writes providers to a file in a format readers accept
+type Writer interface {
+	Write(p database.Provider) error
+	// Close finishes the file and flushes buffered data, underlying writer is not closed
+	Close() error
+}
+
+// csvColumns are columns of exported csv files, id is informational and ignored on import
+var csvColumns = []string{"id", "external_id", "name", "lat", "long", "operating_radius", "radius_unit", "rating", "experience", "rates", "minimum_charge", "travel_rate", "min_area", "max_area", "material_areas", "material_radii", "material_ratings", "service_area", "branches", "availability", "blackouts", "time_zone", "working_hours", "status", "resume_at"}
+
+// NewWriter returns a writer of providers in format, nothing is written to w before the first provider or Close
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	buffer := bufio.NewWriter(w)
+	switch format {
+	case CSV:
+		return &csvWriter{buffer: buffer, writer: csv.NewWriter(buffer)}, nil
+	case JSON:
+		return &jsonWriter{buffer: buffer, open: "[", close: "]"}, nil
+	case NDJSON:
+		return &jsonWriter{buffer: buffer}, nil
+	case GeoJSON:
+		return &jsonWriter{buffer: buffer, open: `{"type":"FeatureCollection","features":[`, close: "]}", features: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvWriter struct {
+	buffer  *bufio.Writer
+	writer  *csv.Writer
+	started bool
+}
+
+func (w *csvWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.writer.Write(csvColumns)
+}
+
+func (w *csvWriter) Write(p database.Provider) error {
+	err := w.start()
+	if err != nil {
+		return err
+	}
+	number := func(n float64) string {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	r := fromProvider(p)
+	// rates and areas are written in order of materials, so output is stable
+	rates, areas, radii, ratings := []string{}, []string{}, []string{}, []string{}
+	for _, material := range r.Experience {
+		if rate, ok := r.Rates[material]; ok {
+			rates = append(rates, material+":"+number(rate))
+		}
+		if area, ok := r.MaterialAreas[material]; ok {
+			upper := ""
+			if area.MaxArea != 0 {
+				upper = number(area.MaxArea)
+			}
+			areas = append(areas, material+":"+number(area.MinArea)+"-"+upper)
+		}
+		if radius, ok := r.MaterialRadii[material]; ok {
+			radii = append(radii, material+":"+number(radius.OperatingRadius)+":"+radius.RadiusUnit)
+		}
+		if rating, ok := r.MaterialRatings[material]; ok {
+			ratings = append(ratings, material+":"+number(rating))
+		}
+	}
+	branches := ""
+	if len(r.Branches) != 0 {
+		b, err := json.Marshal(r.Branches)
+		if err != nil {
+			return err
+		}
+		branches = string(b)
+	}
+	dateRanges := func(ranges []DateRange) string {
+		pairs := make([]string, 0, len(ranges))
+		for _, r := range ranges {
+			pairs = append(pairs, r.From+"/"+r.To)
+		}
+		return strings.Join(pairs, ";")
+	}
+	hours := make([]string, 0, len(r.WorkingHours))
+	for _, h := range r.WorkingHours {
+		hours = append(hours, h.Weekday+" "+h.Start+"-"+h.End)
+	}
+	resumeAt := ""
+	if r.ResumeAt != nil {
+		resumeAt = r.ResumeAt.Format(time.RFC3339)
+	}
+	return w.writer.Write([]string{
+		strconv.FormatInt(int64(r.ID), 10),
+		r.ExternalID,
+		r.Name,
+		number(r.Address.Lat),
+		number(r.Address.Long),
+		number(r.OperatingRadius),
+		r.RadiusUnit,
+		number(r.Rating),
+		strings.Join(r.Experience, ";"),
+		strings.Join(rates, ";"),
+		number(r.MinimumCharge),
+		number(r.TravelRate),
+		number(r.MinArea),
+		number(r.MaxArea),
+		strings.Join(areas, ";"),
+		strings.Join(radii, ";"),
+		strings.Join(ratings, ";"),
+		string(r.ServiceArea),
+		branches,
+		dateRanges(r.Availability),
+		dateRanges(r.Blackouts),
+		r.TimeZone,
+		strings.Join(hours, ";"),
+		r.Status,
+		resumeAt,
+	})
+}
+
+func (w *csvWriter) Close() error {
+	err := w.start()
+	if err != nil {
+		return err
+	}
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
+
+// jsonWriter writes one json value per line, values are wrapped in an array or collection unless open is empty
+type jsonWriter struct {
+	buffer   *bufio.Writer
+	open     string
+	close    string
+	features bool
+	count    int
+}
+
+func (w *jsonWriter) Write(p database.Provider) error {
+	var value interface{} = fromProvider(p)
+	if w.features {
+		value = fromProvider(p).toFeature()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	switch {
+	case w.open == "":
+	case w.count == 0:
+		_, err = w.buffer.WriteString(w.open + "\n")
+	default:
+		_, err = w.buffer.WriteString(",\n")
+	}
+	if err != nil {
+		return err
+	}
+	w.count++
+	_, err = w.buffer.Write(data)
+	if err == nil && w.open == "" {
+		err = w.buffer.WriteByte('\n')
+	}
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	var err error
+	switch {
+	case w.open == "":
+	case w.count == 0:
+		_, err = w.buffer.WriteString(w.open + w.close + "\n")
+	default:
+		_, err = w.buffer.WriteString("\n" + w.close + "\n")
+	}
+	if err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
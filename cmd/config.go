@@ -2,4 +2,6 @@ package main
 
 // Config is used to get configs from environment variables
 type Config struct {
+	Storage     string `env:"AH_FLOORS_STORAGE" env-default:"database"`
+	AutoMigrate bool   `env:"AH_FLOORS_AUTO_MIGRATE" env-default:"false"`
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"ah/bulk"
+	"ah/database"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const exportUsage = "usage: floor-service export [-format csv|json|ndjson|geojson] [FILE]"
+
+// runExport handles export subcommand, providers are written to standard output without FILE or with FILE -
+func runExport(db *database.DataBase, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatName := flags.String("format", "", "file format, guessed from file extension by default, geojson for standard output")
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() > 1 {
+		return errors.New(exportUsage)
+	}
+	fileName := flags.Arg(0)
+	if fileName == "" {
+		fileName = "-"
+	}
+
+	format := bulk.GeoJSON
+	switch {
+	case *formatName != "":
+		format, err = bulk.ParseFormat(*formatName)
+	case fileName != "-":
+		format, err = bulk.FormatOf(fileName)
+	}
+	if err != nil {
+		return err
+	}
+
+	var file io.Writer = os.Stdout
+	if fileName != "-" {
+		f, err := os.Create(fileName)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		file = f
+	}
+	writer, err := bulk.NewWriter(file, format)
+	if err != nil {
+		return err
+	}
+	count, err := bulk.Export(context.Background(), db, writer)
+	if err != nil {
+		return err
+	}
+	// summary goes to standard error so it does not end up in exported data
+	fmt.Fprintf(os.Stderr, "exported %d providers\n", count)
+	return nil
+}
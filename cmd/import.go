@@ -0,0 +1,67 @@
+package main
+
+import (
+	"ah/bulk"
+	"ah/database"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const importUsage = "usage: floor-service import [-format csv|json|ndjson|geojson] [-upsert] [-dry-run] FILE"
+
+// runImport handles import subcommand, FILE - reads from standard input
+func runImport(db *database.DataBase, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := flags.String("format", "", "file format, guessed from file extension by default")
+	upsert := flags.Bool("upsert", false, "update providers with an existing external id")
+	dryRun := flags.Bool("dry-run", false, "validate file against database without saving providers")
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+	fileName := flags.Arg(0)
+
+	var format bulk.Format
+	if *formatName != "" {
+		format, err = bulk.ParseFormat(*formatName)
+	} else {
+		format, err = bulk.FormatOf(fileName)
+	}
+	if err != nil {
+		return err
+	}
+
+	var file io.Reader = os.Stdin
+	if fileName != "-" {
+		f, err := os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		file = f
+	}
+	reader, err := bulk.NewReader(file, format)
+	if err != nil {
+		return err
+	}
+	report, err := bulk.Import(context.Background(), db, reader, database.ImportOptions{Upsert: *upsert, DryRun: *dryRun, Now: time.Now().UTC().Truncate(time.Second)})
+	for _, rowErr := range report.Errors {
+		fmt.Println(rowErr)
+	}
+	fmt.Printf("rows: %d, created: %d, updated: %d, failed: %d\n", report.Rows, report.Created, report.Updated, report.Failed)
+	if *dryRun {
+		fmt.Println("dry run, nothing is saved")
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d rows failed", report.Failed)
+	}
+	return nil
+}
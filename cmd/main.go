@@ -3,10 +3,13 @@ package main
 import (
 	"ah/database"
 	"ah/logger"
+	"ah/memory"
 	"ah/server"
+	"context"
 	"github.com/ilyakaznacheev/cleanenv"
 	"go.uber.org/zap"
 	"log"
+	"os"
 )
 
 func main() {
@@ -24,14 +27,54 @@ func main() {
 
 	zap.ReplaceGlobals(errorLogger)
 
-	db, err := database.Connect()
-	if err != nil {
-		log.Fatal(err)
-	}
+	var storage interface{}
+	switch config.Storage {
+	case "memory":
+		if len(os.Args) > 1 {
+			log.Fatalf("command %s is not available with memory storage", os.Args[1])
+		}
+		storage = memory.New()
+	case "database":
+		db, err := database.Connect()
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	db.WaitUntilAvailable()
+		// subcommands run to completion instead of starting the server
+		if len(os.Args) > 1 {
+			switch os.Args[1] {
+			case "migrate":
+				err = runMigrate(db, os.Args[2:])
+			case "import":
+				err = runImport(db, os.Args[2:])
+			case "export":
+				err = runExport(db, os.Args[2:])
+			default:
+				log.Fatalf("unknown command %s, available commands: migrate, import, export", os.Args[1])
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		err = db.WaitUntilAvailable(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if config.AutoMigrate {
+			err = db.MigrateUp()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		storage = db
+	default:
+		log.Fatalf("unknown storage %s, available storages: database, memory", config.Storage)
+	}
 
-	httpServer, err := server.NewServer(accessLogger, db)
+	httpServer, err := server.NewServer(accessLogger, storage)
 	if err != nil {
 		log.Fatal(err)
 	}
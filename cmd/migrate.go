@@ -0,0 +1,80 @@
+package main
+
+import (
+	"ah/database"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: floor-service migrate up|down|status|to N"
+
+// runMigrate handles migrate subcommand
+func runMigrate(db *database.DataBase, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		err := db.MigrateUp()
+		if err != nil {
+			return err
+		}
+	case "down":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		err := db.MigrateDown()
+		if err != nil {
+			return err
+		}
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %s: %w", args[1], err)
+		}
+		err = db.MigrateTo(version)
+		if err != nil {
+			return err
+		}
+	case "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		return printMigrationStatus(db)
+	default:
+		return errors.New(migrateUsage)
+	}
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema is at version %d\n", version)
+	return nil
+}
+
+func printMigrationStatus(db *database.DataBase) error {
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// AppointmentStatus is a state of an appointment, a booked appointment is either rescheduled or canceled
+type AppointmentStatus string
+
+const (
+	// AppointmentBooked appointment takes its time in calendar of provider
+	AppointmentBooked AppointmentStatus = "booked"
+	// AppointmentCanceled appointment was canceled and its time is free again
+	AppointmentCanceled AppointmentStatus = "canceled"
+)
+
+// Appointment is an on-site measurement a customer booked with a provider matched for its lead
+type Appointment struct {
+	ID         ID
+	ProviderID ID
+	// LeadID is id of the lead customer picked provider for, its address and phone number are ones of the customer
+	LeadID       ID
+	CustomerName string
+	StartsAt     time.Time
+	EndsAt       time.Time
+	Status       AppointmentStatus
+	// Sequence counts changes of appointment, calendars replace an event with one of a higher sequence
+	Sequence  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate checks fields of appointment are in range, provider and lead are checked by storage
+func (a Appointment) Validate() error {
+	switch {
+	case a.ProviderID <= 0 || a.LeadID <= 0:
+		return fmt.Errorf("%w: invalid provider or lead id", ErrInvalid)
+	case len(a.CustomerName) > 45:
+		return fmt.Errorf("%w: customer name should have at most 45 characters", ErrInvalid)
+	case a.StartsAt.IsZero() || !a.EndsAt.After(a.StartsAt):
+		return fmt.Errorf("%w: appointment should end after it starts", ErrInvalid)
+	case a.Status != AppointmentBooked:
+		return fmt.Errorf("%w: a new appointment should be booked", ErrInvalid)
+	}
+	return nil
+}
+
+// Overlaps reports whether a booked appointment takes any time between start and end
+func (a Appointment) Overlaps(start, end time.Time) bool {
+	return a.Status == AppointmentBooked && a.StartsAt.Before(end) && start.Before(a.EndsAt)
+}
+
+// overlapsAny reports whether any of appointments but one with id except overlaps time between start and end
+func overlapsAny(appointments []Appointment, start, end time.Time, except ID) bool {
+	for _, a := range appointments {
+		if a.ID != except && a.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reschedule moves a booked appointment to a new time, ErrConflict is returned when it is canceled
+func (a *Appointment) Reschedule(start, end, now time.Time) error {
+	if a.Status != AppointmentBooked {
+		return fmt.Errorf("%w: %s appointment can not be rescheduled", ErrConflict, a.Status)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("%w: appointment should end after it starts", ErrInvalid)
+	}
+	a.StartsAt, a.EndsAt = start.UTC(), end.UTC()
+	a.Sequence++
+	a.UpdatedAt = now.UTC()
+	return nil
+}
+
+// Cancel cancels a booked appointment, ErrConflict is returned when it is already canceled
+func (a *Appointment) Cancel(now time.Time) error {
+	if a.Status != AppointmentBooked {
+		return fmt.Errorf("%w: %s appointment can not be canceled", ErrConflict, a.Status)
+	}
+	a.Status = AppointmentCanceled
+	a.Sequence++
+	a.UpdatedAt = now.UTC()
+	return nil
+}
+
+// BookAppointment saves a booked appointment, CreatedAt and UpdatedAt are set by caller.
+// ErrNotFound is returned for an unknown provider and ErrConflict when it overlaps another booked appointment of provider
+func (db *DataBase) BookAppointment(ctx context.Context, a Appointment) (ID, error) {
+	err := a.Validate()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var id ID
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		// provider row is locked, so concurrent bookings of the same provider are checked one after another
+		var providerID ID
+		err := tx.QueryRowContext(ctx, db.dialect.rebind("select Id from Provider where Id = ? for update"), a.ProviderID).Scan(&providerID)
+		if err != nil {
+			return err
+		}
+		err = db.checkOverlap(ctx, tx, a)
+		if err != nil {
+			return err
+		}
+		query := "insert into Appointment (ProviderId, LeadId, CustomerName, StartsAt, EndsAt, Status, Sequence, CreatedAt, UpdatedAt) values (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		inserted, err := db.dialect.insert(ctx, tx, db.dialect.rebind(query), a.ProviderID, a.LeadID, a.CustomerName, a.StartsAt.UTC(), a.EndsAt.UTC(), a.Status, a.Sequence, a.CreatedAt.UTC(), a.UpdatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		id = ID(inserted)
+		return nil
+	})
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// GetAppointment returns an appointment by its id
+func (db *DataBase) GetAppointment(ctx context.Context, id ID) (Appointment, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	a, err := db.getAppointment(ctx, db.db, id, false)
+	if err != nil {
+		return Appointment{}, queryError(ctx, err)
+	}
+	return a, nil
+}
+
+// GetAppointments returns booked appointments of a provider taking any time between from and to, ordered by start.
+// ErrNotFound is returned for an unknown provider
+func (db *DataBase) GetAppointments(ctx context.Context, providerID ID, from, to time.Time) ([]Appointment, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var exists int
+	err := db.db.QueryRowContext(ctx, db.dialect.rebind("select 1 from Provider where Id = ?"), providerID).Scan(&exists)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	query := "select " + appointmentColumns + " from Appointment where ProviderId = ? and Status = ? and StartsAt < ? and EndsAt > ? order by StartsAt, Id"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), providerID, AppointmentBooked, to.UTC(), from.UTC())
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+	res := []Appointment{}
+	for rows.Next() {
+		a, err := scanAppointment(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		res = append(res, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return res, nil
+}
+
+// RescheduleAppointment moves a booked appointment to a new time at time now and saves the result, see Appointment.Reschedule.
+// ErrConflict is returned when new time overlaps another booked appointment of provider
+func (db *DataBase) RescheduleAppointment(ctx context.Context, id ID, start, end, now time.Time) (Appointment, error) {
+	return db.changeAppointment(ctx, id, func(tx *sql.Tx, a *Appointment) error {
+		err := a.Reschedule(start, end, now)
+		if err != nil {
+			return err
+		}
+		// provider row is locked as in BookAppointment, so a booking can not take the new time meanwhile
+		var providerID ID
+		err = tx.QueryRowContext(ctx, db.dialect.rebind("select Id from Provider where Id = ? for update"), a.ProviderID).Scan(&providerID)
+		if err != nil {
+			return err
+		}
+		return db.checkOverlap(ctx, tx, *a)
+	})
+}
+
+// CancelAppointment cancels a booked appointment at time now and saves the result, see Appointment.Cancel
+func (db *DataBase) CancelAppointment(ctx context.Context, id ID, now time.Time) (Appointment, error) {
+	return db.changeAppointment(ctx, id, func(tx *sql.Tx, a *Appointment) error {
+		return a.Cancel(now)
+	})
+}
+
+// changeAppointment locks an appointment, applies change to it and saves the result
+func (db *DataBase) changeAppointment(ctx context.Context, id ID, change func(tx *sql.Tx, a *Appointment) error) (Appointment, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var a Appointment
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		a, err = db.getAppointment(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = change(tx, &a)
+		if err != nil {
+			return err
+		}
+		query := "update Appointment set StartsAt = ?, EndsAt = ?, Status = ?, Sequence = ?, UpdatedAt = ? where Id = ?"
+		_, err = tx.ExecContext(ctx, db.dialect.rebind(query), a.StartsAt, a.EndsAt, a.Status, a.Sequence, a.UpdatedAt, a.ID)
+		return err
+	})
+	if err != nil {
+		return Appointment{}, queryError(ctx, err)
+	}
+	return a, nil
+}
+
+// checkOverlap returns ErrConflict when a overlaps another booked appointment of its provider
+func (db *DataBase) checkOverlap(ctx context.Context, tx *sql.Tx, a Appointment) error {
+	var count int
+	query := "select count(*) from Appointment where ProviderId = ? and Status = ? and StartsAt < ? and EndsAt > ? and Id <> ?"
+	err := tx.QueryRowContext(ctx, db.dialect.rebind(query), a.ProviderID, AppointmentBooked, a.EndsAt.UTC(), a.StartsAt.UTC(), a.ID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: provider has another appointment at that time", ErrConflict)
+	}
+	return nil
+}
+
+const appointmentColumns = "Id, ProviderId, LeadId, CustomerName, StartsAt, EndsAt, Status, Sequence, CreatedAt, UpdatedAt"
+
+func (db *DataBase) getAppointment(ctx context.Context, q querier, id ID, lock bool) (Appointment, error) {
+	query := "select " + appointmentColumns + " from Appointment where Id = ?"
+	if lock {
+		query += " for update"
+	}
+	return scanAppointment(q.QueryRowContext(ctx, db.dialect.rebind(query), id))
+}
+
+// scanAppointment reads an appointment selected with appointmentColumns
+func scanAppointment(row interface{ Scan(...interface{}) error }) (Appointment, error) {
+	var a Appointment
+	err := row.Scan(&a.ID, &a.ProviderID, &a.LeadID, &a.CustomerName, &a.StartsAt, &a.EndsAt, &a.Status, &a.Sequence, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return Appointment{}, err
+	}
+	a.StartsAt, a.EndsAt, a.CreatedAt, a.UpdatedAt = a.StartsAt.UTC(), a.EndsAt.UTC(), a.CreatedAt.UTC(), a.UpdatedAt.UTC()
+	return a, nil
+}
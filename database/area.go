@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Polygon is an outer ring followed by rings of holes, each ring is closed with its first point repeated last
+type Polygon [][]Address
+
+// ServiceArea is a multipolygon a provider works in, it replaces operating radius in matching when set.
+// Contains treats edges as straight lines in longitude and latitude while sql servers follow earth surface,
+// so results may differ close to long edges. polygons crossing the antimeridian should be split at it
+type ServiceArea []Polygon
+
+// Validate checks polygons have closed rings of at least four points within coordinate range
+func (a ServiceArea) Validate() error {
+	for _, polygon := range a {
+		if len(polygon) == 0 {
+			return fmt.Errorf("%w: service area polygon should have an outer ring", ErrInvalid)
+		}
+		for _, ring := range polygon {
+			if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+				return fmt.Errorf("%w: service area rings should be closed and have at least 4 points", ErrInvalid)
+			}
+			for _, point := range ring {
+				if point.Lat < -90 || point.Lat > 90 || point.Long < -180 || point.Long > 180 {
+					return fmt.Errorf("%w: service area point is out of range", ErrInvalid)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Contains reports whether location is inside one of polygons and outside of its holes
+func (a ServiceArea) Contains(location Address) bool {
+	for _, polygon := range a {
+		// with even-odd rule crossing a hole ring counts as leaving the polygon
+		inside := false
+		for _, ring := range polygon {
+			for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+				p, q := ring[i], ring[j]
+				if (p.Lat > location.Lat) != (q.Lat > location.Lat) &&
+					location.Long < (q.Long-p.Long)*(location.Lat-p.Lat)/(q.Lat-p.Lat)+p.Long {
+					inside = !inside
+				}
+			}
+		}
+		if inside {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether provider works at location, inside its service area when there is one
+// and closer than operating radius otherwise. distance is distance from provider address to location in meters
+func (p Provider) Covers(location Address, distance float64) bool {
+	if len(p.ServiceArea) != 0 {
+		return p.ServiceArea.Contains(location)
+	}
+	return distance < p.RadiusUnit.ToMeters(p.Radius)
+}
+
+// geoJSONGeometry is a GeoJSON geometry, positions are longitude then latitude
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// GeoJSON formats area as a GeoJSON MultiPolygon geometry
+func (a ServiceArea) GeoJSON() []byte {
+	coordinates := make([][][][2]float64, 0, len(a))
+	for _, polygon := range a {
+		rings := make([][][2]float64, 0, len(polygon))
+		for _, ring := range polygon {
+			positions := make([][2]float64, 0, len(ring))
+			for _, point := range ring {
+				positions = append(positions, [2]float64{point.Long, point.Lat})
+			}
+			rings = append(rings, positions)
+		}
+		coordinates = append(coordinates, rings)
+	}
+	raw, _ := json.Marshal(coordinates)
+	data, _ := json.Marshal(geoJSONGeometry{Type: "MultiPolygon", Coordinates: raw})
+	return data
+}
+
+// ParseServiceArea reads a GeoJSON Polygon or MultiPolygon geometry and validates it
+func ParseServiceArea(data []byte) (ServiceArea, error) {
+	var geometry geoJSONGeometry
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, fmt.Errorf("%w: service area is not a GeoJSON geometry: %v", ErrInvalid, err)
+	}
+	var (
+		polygons [][][][]float64
+		err      error
+	)
+	switch geometry.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		err = json.Unmarshal(geometry.Coordinates, &polygon)
+		polygons = [][][][]float64{polygon}
+	case "MultiPolygon":
+		err = json.Unmarshal(geometry.Coordinates, &polygons)
+	default:
+		return nil, fmt.Errorf("%w: service area should be a Polygon or MultiPolygon, got %q", ErrInvalid, geometry.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed service area coordinates: %v", ErrInvalid, err)
+	}
+	area := make(ServiceArea, 0, len(polygons))
+	for _, rings := range polygons {
+		polygon := make(Polygon, 0, len(rings))
+		for _, positions := range rings {
+			ring := make([]Address, 0, len(positions))
+			for _, position := range positions {
+				// altitude is ignored
+				if len(position) < 2 {
+					return nil, fmt.Errorf("%w: service area position should have longitude and latitude", ErrInvalid)
+				}
+				ring = append(ring, Address{Lat: position[1], Long: position[0]})
+			}
+			polygon = append(polygon, ring)
+		}
+		area = append(area, polygon)
+	}
+	if err := area.Validate(); err != nil {
+		return nil, err
+	}
+	return area, nil
+}
+
+// Value stores area as GeoJSON text, an empty area is null
+func (a ServiceArea) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	return string(a.GeoJSON()), nil
+}
+
+// Scan reads area from GeoJSON text, null is an empty area
+func (a *ServiceArea) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("unsupported service area value %T", src)
+	}
+	area, err := ParseServiceArea(data)
+	if err != nil {
+		return err
+	}
+	*a = area
+	return nil
+}
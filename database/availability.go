@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DateLayout is format of dates in api and files
+const DateLayout = "2006-01-02"
+
+// Day returns midnight UTC of the day t is in
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DateRange is a range of days with both ends included, ends are midnight UTC
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Valid reports whether ends are whole days and To is not before From
+func (r DateRange) Valid() bool {
+	return !r.From.IsZero() && r.From.Equal(Day(r.From)) && r.To.Equal(Day(r.To)) && !r.To.Before(r.From)
+}
+
+// Contains reports whether day is in range
+func (r DateRange) Contains(day time.Time) bool {
+	return !day.Before(r.From) && !day.After(r.To)
+}
+
+// validateAvailability checks availability windows and blackouts are valid ranges
+func (p Provider) validateAvailability() error {
+	for _, r := range p.Availability {
+		if !r.Valid() {
+			return fmt.Errorf("%w: availability should be whole days with end not before start", ErrInvalid)
+		}
+	}
+	for _, r := range p.Blackouts {
+		if !r.Valid() {
+			return fmt.Errorf("%w: blackouts should be whole days with end not before start", ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// NextAvailableDay returns the first day from the day of t provider can start a job on, false when there is none.
+// a provider without availability windows is available on any day out of its blackouts
+func (p Provider) NextAvailableDay(t time.Time) (time.Time, bool) {
+	day := Day(t)
+	for {
+		if len(p.Availability) != 0 {
+			next, ok := firstDayFrom(p.Availability, day)
+			if !ok {
+				return time.Time{}, false
+			}
+			day = next
+		}
+		// every blackout moves day past its end, so the loop ends once no blackout is left ahead
+		blocked := false
+		for _, b := range p.Blackouts {
+			if b.Contains(day) {
+				day, blocked = b.To.AddDate(0, 0, 1), true
+			}
+		}
+		if !blocked {
+			return day, true
+		}
+	}
+}
+
+// firstDayFrom returns the first day from day in any of ranges
+func firstDayFrom(ranges []DateRange, day time.Time) (time.Time, bool) {
+	var first time.Time
+	for _, r := range ranges {
+		if r.To.Before(day) {
+			continue
+		}
+		next := r.From
+		if next.Before(day) {
+			next = day
+		}
+		if first.IsZero() || next.Before(first) {
+			first = next
+		}
+	}
+	return first, !first.IsZero()
+}
+
+// setAvailability replaces availability windows and blackouts of a provider
+func (db *DataBase) setAvailability(ctx context.Context, tx *sql.Tx, id ID, availability, blackouts []DateRange) error {
+	_, err := tx.ExecContext(ctx, db.dialect.rebind("delete from ProviderAvailability where ProviderId = ?"), id)
+	if err != nil {
+		return err
+	}
+	query := db.dialect.rebind("insert into ProviderAvailability (ProviderId, Blackout, FromDate, ToDate) values (?, ?, ?, ?)")
+	insert := func(ranges []DateRange, blackout bool) error {
+		for _, r := range ranges {
+			if !r.Valid() {
+				return ErrInvalid
+			}
+			_, err := tx.ExecContext(ctx, query, id, blackout, r.From.Format(DateLayout), r.To.Format(DateLayout))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = insert(availability, false)
+	if err != nil {
+		return err
+	}
+	return insert(blackouts, true)
+}
+
+// loadAvailability fills availability windows and blackouts of given providers in order they were added
+func (db *DataBase) loadAvailability(ctx context.Context, providers []Provider) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	index := make(map[ID]int, len(providers))
+	args := make([]interface{}, 0, len(providers))
+	for i := range providers {
+		index[providers[i].ID] = i
+		args = append(args, providers[i].ID)
+	}
+	query := "select ProviderId, Blackout, FromDate, ToDate from ProviderAvailability where ProviderId in (" + placeholders(len(args)) + ") order by Id"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			providerID ID
+			blackout   bool
+			r          DateRange
+		)
+		err := rows.Scan(&providerID, &blackout, &r.From, &r.To)
+		if err != nil {
+			return err
+		}
+		r.From, r.To = Day(r.From), Day(r.To)
+		i := index[providerID]
+		if blackout {
+			providers[i].Blackouts = append(providers[i].Blackouts, r)
+		} else {
+			providers[i].Availability = append(providers[i].Availability, r)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"math"
+	"strconv"
+)
+
+// earthRadius is mean radius of earth in meters, same value used by mysql st_distance_sphere
+const earthRadius = 6370986
+
+// boundingBox is area between two latitudes and two longitudes, in degrees
+type boundingBox struct {
+	MinLat, MinLong float64
+	MaxLat, MaxLong float64
+}
+
+// boundingBoxes returns boxes covering all points closer than distance meters to location.
+// a box crossing the antimeridian is split in two. nil is returned if the area contains a pole,
+// in that case every longitude is covered and boxes can not narrow the search.
+func boundingBoxes(location Address, distance float64) []boundingBox {
+	// a small margin keeps points on the circle inside boxes despite rounding errors
+	angle := distance / earthRadius * 1.001
+	lat := location.Lat * math.Pi / 180
+	if math.Abs(lat)+angle >= math.Pi/2 {
+		return nil
+	}
+	// widest longitude difference is reached where the circle touches a meridian, not on the latitude of location
+	deltaLong := math.Asin(math.Sin(angle)/math.Cos(lat)) * 180 / math.Pi
+	if deltaLong >= 90 {
+		return nil
+	}
+	deltaLat := angle * 180 / math.Pi
+	box := boundingBox{
+		MinLat:  location.Lat - deltaLat,
+		MinLong: location.Long - deltaLong,
+		MaxLat:  location.Lat + deltaLat,
+		MaxLong: location.Long + deltaLong,
+	}
+	switch {
+	case box.MinLong < -180:
+		return []boundingBox{
+			{MinLat: box.MinLat, MinLong: box.MinLong + 360, MaxLat: box.MaxLat, MaxLong: 180},
+			{MinLat: box.MinLat, MinLong: -180, MaxLat: box.MaxLat, MaxLong: box.MaxLong},
+		}
+	case box.MaxLong > 180:
+		return []boundingBox{
+			{MinLat: box.MinLat, MinLong: box.MinLong, MaxLat: box.MaxLat, MaxLong: 180},
+			{MinLat: box.MinLat, MinLong: -180, MaxLat: box.MaxLat, MaxLong: box.MaxLong - 360},
+		}
+	default:
+		return []boundingBox{box}
+	}
+}
+
+// polygonWKT formats box as a well-known text polygon with longitude first, counter-clockwise
+func (box boundingBox) polygonWKT() string {
+	corners := [][2]float64{
+		{box.MinLong, box.MinLat},
+		{box.MaxLong, box.MinLat},
+		{box.MaxLong, box.MaxLat},
+		{box.MinLong, box.MaxLat},
+		{box.MinLong, box.MinLat},
+	}
+	res := "POLYGON(("
+	for i, corner := range corners {
+		if i > 0 {
+			res += ", "
+		}
+		res += strconv.FormatFloat(corner[0], 'f', -1, 64) + " " + strconv.FormatFloat(corner[1], 'f', -1, 64)
+	}
+	return res + "))"
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkProviders is default size of synthetic data set, overridden by AH_FLOORS_BENCHMARK_PROVIDERS
+const benchmarkProviders = 1000000
+
+// BenchmarkGetProviders compares matching through spatial index with a full table scan
+// and reports median and 99th percentile latency of each
+func BenchmarkGetProviders(b *testing.B) {
+	if testing.Short() {
+		b.Skip("seeding synthetic providers is slow")
+	}
+	count := benchmarkProviders
+	if value := os.Getenv("AH_FLOORS_BENCHMARK_PROVIDERS"); value != "" {
+		var err error
+		count, err = strconv.Atoi(value)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	seedProviders(b, count)
+	defer func() { _ = db.Clear() }()
+
+	radii, err := db.searchRadii(context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, bm := range []struct {
+		name  string
+		radii searchRadii
+	}{
+		{name: "index", radii: radii},
+		// zero radii do not limit the search, so every provider is scanned
+		{name: "scan", radii: searchRadii{}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			r := rand.New(rand.NewSource(2))
+			latencies := make([]time.Duration, 0, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				query, args := db.matchQuery(Criteria{Material: FloorWood, Location: randomLocation(r), Now: time.Now()}, bm.radii)
+				_, err := db.matchProviders(context.Background(), query, args)
+				latencies = append(latencies, time.Since(start))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			b.ReportMetric(percentile(latencies, 0.5).Seconds()*1000, "p50-ms")
+			b.ReportMetric(percentile(latencies, 0.99).Seconds()*1000, "p99-ms")
+		})
+	}
+}
+
+// seedProviders replaces all providers with count random wood providers
+func seedProviders(b *testing.B, count int) {
+	err := db.Clear()
+	if err != nil {
+		b.Fatal(err)
+	}
+	const batchSize = 1000
+	r := rand.New(rand.NewSource(1))
+	for inserted := 0; inserted < count; inserted += batchSize {
+		var (
+			values []string
+			args   []interface{}
+		)
+		for i := inserted; i < inserted+batchSize && i < count; i++ {
+			point, pointArgs := db.dialect.pointExpr(randomLocation(r))
+			values = append(values, "(?, "+point+", ?, ?, ?)")
+			args = append(append(append(args, "p"+strconv.Itoa(i)), pointArgs...), 1+r.Float64()*49, Kilometre, math.Round(r.Float64()*50)/10)
+		}
+		_, err := db.db.Exec(db.dialect.rebind("insert into Provider (Name, Address, Radius, RadiusUnit, Rating) values "+strings.Join(values, ", ")), args...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	_, err = db.db.Exec(db.dialect.rebind("insert into ProviderMaterial (ProviderId, MaterialId) select p.Id, m.Id from Provider p cross join Material m where m.Name = ?"), FloorWood)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+// randomLocation returns a location on populated latitudes
+func randomLocation(r *rand.Rand) Address {
+	return Address{Lat: r.Float64()*120 - 60, Long: r.Float64()*360 - 180}
+}
+
+// percentile returns the smallest latency greater than or equal to fraction p of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,31 @@
+package database
+
+import "fmt"
+
+// Branch is a depot of a provider besides its address, with its own operating radius
+type Branch struct {
+	Name       string
+	Address    Address
+	Radius     float64
+	RadiusUnit DistanceUnit
+}
+
+// Validate checks fields of branch are in range
+func (b Branch) Validate() error {
+	switch {
+	case b.Name == "" || len(b.Name) > 45:
+		return fmt.Errorf("%w: branch name should have 1 to 45 characters", ErrInvalid)
+	case b.Address.Lat < -90 || b.Address.Lat > 90 || b.Address.Long < -180 || b.Address.Long > 180:
+		return fmt.Errorf("%w: branch address is out of range", ErrInvalid)
+	case !(b.Radius > 0):
+		return fmt.Errorf("%w: branch radius should be positive", ErrInvalid)
+	case !b.RadiusUnit.Valid():
+		return fmt.Errorf("%w: unknown branch radius unit %q", ErrInvalid, b.RadiusUnit)
+	}
+	return nil
+}
+
+// Covers reports whether a location distance meters away from branch is within its radius
+func (b Branch) Covers(distance float64) bool {
+	return distance < b.RadiusUnit.ToMeters(b.Radius)
+}
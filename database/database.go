@@ -1,18 +1,23 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/go-sql-driver/mysql"
 	"github.com/ilyakaznacheev/cleanenv"
-	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
-// DataBase implements Storage interface for mysql
+// DataBase implements Storage interface for sql servers, mysql and postgres are supported
 type DataBase struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
+	// queryTimeout limits duration of each storage call, zero means no limit
+	queryTimeout time.Duration
+	// waitTimeout limits how long WaitUntilAvailable retries
+	waitTimeout time.Duration
 }
 
 var (
@@ -22,27 +27,33 @@ var (
 	ErrNotFound = errors.New("not found")
 	// ErrInvalid invalid input data
 	ErrInvalid = errors.New("invalid operation")
+	// ErrConflict operation is not allowed in current state of an entity
+	ErrConflict = errors.New("conflict")
 )
 
+// queryError is parseError for queries run with ctx, drivers report interrupted queries differently
+// so ctx error is returned whenever ctx is done
+func queryError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return parseError(err)
+}
+
 func parseError(err error) error {
-	var mysqlErr *mysql.MySQLError
 	if errors.Is(err, sql.ErrNoRows) {
 		return ErrNotFound
 	}
-	if errors.As(err, &mysqlErr) {
-		switch mysqlErr.Number {
-		case 1062:
-			return ErrDuplicateEntry
-		case 1452:
-			return ErrInvalid
-		default:
-			return err
-		}
+	if parsed, ok := parseMySQLError(err); ok {
+		return parsed
+	}
+	if parsed, ok := parsePostgresError(err); ok {
+		return parsed
 	}
 	return err
 }
 
-// Connect to database server
+// Connect to database server, server type is chosen by connection string scheme
 func Connect() (*DataBase, error) {
 	var config Config
 	err := cleanenv.ReadEnv(&config)
@@ -50,78 +61,774 @@ func Connect() (*DataBase, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("mysql", config.ConnectionString)
+	var (
+		db      *sql.DB
+		dialect dialect
+	)
+	connectionString := config.ConnectionString
+	switch {
+	case strings.HasPrefix(connectionString, "postgres://"), strings.HasPrefix(connectionString, "postgresql://"):
+		dialect = postgresDialect{}
+		db, err = sql.Open(dialect.driverName(), connectionString)
+	case strings.HasPrefix(connectionString, "mysql://"):
+		dialect = mysqlDialect{}
+		db, err = openMySQL(strings.TrimPrefix(connectionString, "mysql://"))
+	case strings.Contains(connectionString, "://"):
+		return nil, fmt.Errorf("unsupported database scheme in %s", connectionString[:strings.Index(connectionString, "://")+3])
+	default:
+		// connection strings without scheme are mysql data source names
+		dialect = mysqlDialect{}
+		db, err = openMySQL(connectionString)
+	}
 	if err != nil {
 		return nil, parseError(err)
 	}
-	return &DataBase{db}, nil
+	db.SetMaxOpenConns(config.MaxOpenConnections)
+	db.SetMaxIdleConns(config.MaxIdleConnections)
+	db.SetConnMaxLifetime(time.Duration(config.ConnectionMaxLifetime) * time.Second)
+	return &DataBase{
+		db:           db,
+		dialect:      dialect,
+		queryTimeout: time.Duration(config.QueryTimeout) * time.Second,
+		waitTimeout:  time.Duration(config.WaitTimeout) * time.Second,
+	}, nil
 }
 
 // Clear remove all data from database
 func (db *DataBase) Clear() error {
 	_, err := db.db.Exec("delete from Provider")
+	if err != nil {
+		return parseError(err)
+	}
+	_, err = db.db.Exec("delete from CustomerLead")
 	return parseError(err)
 }
 
-// WaitUntilAvailable pings database every 5 second until a valid connection is available
-func (db *DataBase) WaitUntilAvailable() {
-	for {
-		err := db.db.Ping()
-		if err == nil {
-			break
-		}
-		zap.L().Error("db connection failed", zap.Error(err))
-		time.Sleep(time.Second * 5)
-	}
-}
-
-// GetProviders get a list of providers matching the criteria, ordered by rating first then distance
-func (db *DataBase) GetProviders(material FloorMaterial, location Address) ([]Provider, error) {
-	query := "select p.Id, p.Name, ST_X(p.Address) AS Latitude, ST_Y(p.Address) AS Longitude, p.Radius, p.Rating, p.Wood, p.Carpet, p.Tile, st_distance_sphere(point(?, ?), p.Address) as dist from Provider p"
-	filter := " where "
-	switch material {
-	case FloorWood:
-		filter += "p.Wood = 1"
-	case FloorCarpet:
-		filter += "p.Carpet = 1"
-	case FloorTile:
-		filter += "p.Tile = 1"
-	default:
-		filter = ""
+// GetProviders get a list of providers matching the criteria, ordered by rating first then distance, ties are broken by id.
+// radius and rating of the requested material replace ones of provider when set
+func (db *DataBase) GetProviders(ctx context.Context, criteria Criteria) ([]Provider, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	// no address or branch can cover a location farther than the largest radius, so only that area is searched
+	radii, err := db.searchRadii(ctx)
+	if err != nil {
+		return nil, queryError(ctx, err)
 	}
-	limitAndOrder := " having dist < Radius order by Rating desc"
-	rows, err := db.db.Query(query+filter+limitAndOrder, location.Lat, location.Long)
+	query, args := db.matchQuery(criteria, radii)
+	return db.matchProviders(ctx, query, args)
+}
+
+// areaColumn selects GeoJSON text of an area column, null when it holds no area
+func (db *DataBase) areaColumn(column string) string {
+	return "case when " + db.dialect.emptyAreaExpr(column) + " then null else ST_AsGeoJSON(" + column + ") end"
+}
+
+// matchQuery builds query of GetProviders, only addresses and branches within radii of location are searched
+func (db *DataBase) matchQuery(criteria Criteria, radii searchRadii) (string, []interface{}) {
+	// every address and branch covering the location is a row with its distance, branch id of an address is zero
+	location := criteria.Location
+	distance, distanceArgs := db.dialect.distanceExpr("p.Address", location)
+	covers, coversArgs := db.dialect.coversExpr("p.ServiceArea", location)
+	branchDistance, branchDistanceArgs := db.dialect.distanceExpr("b.Address", location)
+	byRadius := "select p.Id as ProviderId, 0 as BranchId, " + distance + " as dist from Provider p" +
+		" join ProviderMaterial pm on pm.ProviderId = p.Id join Material m on m.Id = pm.MaterialId and m.Name = ?" +
+		" where " + db.dialect.emptyAreaExpr("p.ServiceArea") + " and " + distance + " < coalesce(pm.RadiusMeters, p.RadiusMeters)"
+	byArea := "select p.Id as ProviderId, 0 as BranchId, " + distance + " as dist from Provider p where " + covers + " and not " + db.dialect.emptyAreaExpr("p.ServiceArea")
+	byBranch := "select b.ProviderId, b.Id as BranchId, " + branchDistance + " as dist from ProviderLocation b where " + branchDistance + " < b.RadiusMeters"
+	var args []interface{}
+	args = append(append(append(args, distanceArgs...), criteria.Material), distanceArgs...)
+	if within, withinArgs := db.dialect.withinExpr("p.Address", location, radii.address); within != "" && radii.address > 0 {
+		byRadius += " and " + within
+		args = append(args, withinArgs...)
+	}
+	args = append(append(args, distanceArgs...), coversArgs...)
+	args = append(append(args, branchDistanceArgs...), branchDistanceArgs...)
+	if within, withinArgs := db.dialect.withinExpr("b.Address", location, radii.branch); within != "" && radii.branch > 0 {
+		byBranch += " and " + within
+		args = append(args, withinArgs...)
+	}
+
+	query := "select p.Id, coalesce(p.ExternalId, ''), p.Name, " + db.dialect.addressColumns("p.Address") + ", coalesce(pm.Radius, p.Radius), coalesce(pm.RadiusUnit, p.RadiusUnit), coalesce(pm.Rating, p.Rating), p.ReviewCount, p.MinimumCharge, p.TravelRate, p.MinArea, p.MaxArea, " + db.areaColumn("p.ServiceArea") + ", p.TimeZone, p.Status, p.ResumeAt, c.BranchId, c.dist" +
+		" from (" + byRadius + " union all " + byArea + " union all " + byBranch + ") c join Provider p on p.Id = c.ProviderId" +
+		" join ProviderMaterial pm on pm.ProviderId = p.Id join Material m on m.Id = pm.MaterialId where m.Name = ?"
+	args = append(args, criteria.Material)
+	if criteria.Area > 0 {
+		// job area of the material replaces job area of provider when set
+		query += " and coalesce(pm.MinArea, p.MinArea) <= ? and (coalesce(pm.MaxArea, p.MaxArea) = 0 or coalesce(pm.MaxArea, p.MaxArea) >= ?)"
+		args = append(args, criteria.Area, criteria.Area)
+	}
+	// paused providers are matched again from their resume time
+	query += " and (p.Status = ? or p.Status = ? and p.ResumeAt <= ?)"
+	args = append(args, StatusActive, StatusPaused, criteria.Now.UTC())
+	// the first row of a provider is its closest location, an address wins a tie with its branches
+	query += " order by coalesce(pm.Rating, p.Rating) desc, c.dist, p.Id, c.BranchId"
+	return db.dialect.rebind(query), args
+}
+
+// matchProviders runs a query built by matchQuery, returning matched providers with all their details
+func (db *DataBase) matchProviders(ctx context.Context, query string, args []interface{}) ([]Provider, error) {
+	rows, err := db.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+		return nil, queryError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+	res := []Provider{}
+	closest := map[ID]ID{}
+	for rows.Next() {
+		var (
+			item     Provider
+			resumeAt sql.NullTime
+			branchID ID
+		)
+		err := rows.Scan(&item.ID, &item.ExternalID, &item.Name, &item.Address.Lat, &item.Address.Long, &item.Radius, &item.RadiusUnit, &item.Rating, &item.ReviewCount, &item.MinimumCharge, &item.TravelRate, &item.JobArea.Min, &item.JobArea.Max, &item.ServiceArea, &item.TimeZone, &item.Status, &resumeAt, &branchID, &item.Distance)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		item.ResumeAt = fromNullTime(resumeAt)
+		if _, ok := closest[item.ID]; ok {
+			continue
+		}
+		closest[item.ID] = branchID
+		res = append(res, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadMaterials(ctx, res)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadBranches(ctx, res, closest)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadAvailability(ctx, res)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadWorkingHours(ctx, res)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return res, nil
+}
+
+// loadMaterials fills materials of given providers in catalogue order with their rates, job areas, radii and ratings
+func (db *DataBase) loadMaterials(ctx context.Context, providers []Provider) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	index := make(map[ID]int, len(providers))
+	args := make([]interface{}, 0, len(providers))
+	for i := range providers {
+		index[providers[i].ID] = i
+		args = append(args, providers[i].ID)
+	}
+	query := "select pm.ProviderId, m.Name, pm.PricePerSquareMetre, pm.MinArea, pm.MaxArea, pm.Radius, pm.RadiusUnit, pm.Rating from ProviderMaterial pm join Material m on m.Id = pm.MaterialId where pm.ProviderId in (" + placeholders(len(args)) + ") order by m.Id"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			providerID ID
+			material   FloorMaterial
+			rate       sql.NullFloat64
+			minArea    sql.NullFloat64
+			maxArea    sql.NullFloat64
+			radius     sql.NullFloat64
+			radiusUnit sql.NullString
+			rating     sql.NullFloat64
+		)
+		err := rows.Scan(&providerID, &material, &rate, &minArea, &maxArea, &radius, &radiusUnit, &rating)
+		if err != nil {
+			return err
+		}
+		i := index[providerID]
+		providers[i].Materials = append(providers[i].Materials, material)
+		if rate.Valid {
+			if providers[i].Rates == nil {
+				providers[i].Rates = map[FloorMaterial]float64{}
+			}
+			providers[i].Rates[material] = rate.Float64
+		}
+		if minArea.Valid {
+			if providers[i].MaterialJobAreas == nil {
+				providers[i].MaterialJobAreas = map[FloorMaterial]AreaRange{}
+			}
+			providers[i].MaterialJobAreas[material] = AreaRange{Min: minArea.Float64, Max: maxArea.Float64}
+		}
+		if radius.Valid {
+			if providers[i].MaterialRadii == nil {
+				providers[i].MaterialRadii = map[FloorMaterial]MaterialRadius{}
+			}
+			providers[i].MaterialRadii[material] = MaterialRadius{Radius: radius.Float64, RadiusUnit: DistanceUnit(radiusUnit.String)}
+		}
+		if rating.Valid {
+			if providers[i].MaterialRatings == nil {
+				providers[i].MaterialRatings = map[FloorMaterial]float64{}
+			}
+			providers[i].MaterialRatings[material] = rating.Float64
+		}
+	}
+	return rows.Err()
+}
+
+// loadBranches fills branches of given providers in order they were added, closest maps ids of matched providers
+// to ids of their closest branches, it is nil when providers are not matched
+func (db *DataBase) loadBranches(ctx context.Context, providers []Provider, closest map[ID]ID) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	index := make(map[ID]int, len(providers))
+	args := make([]interface{}, 0, len(providers))
+	for i := range providers {
+		index[providers[i].ID] = i
+		args = append(args, providers[i].ID)
+	}
+	query := "select b.Id, b.ProviderId, b.Name, " + db.dialect.addressColumns("b.Address") + ", b.Radius, b.RadiusUnit from ProviderLocation b where b.ProviderId in (" + placeholders(len(args)) + ") order by b.Id"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			id, providerID ID
+			branch         Branch
+		)
+		err := rows.Scan(&id, &providerID, &branch.Name, &branch.Address.Lat, &branch.Address.Long, &branch.Radius, &branch.RadiusUnit)
+		if err != nil {
+			return err
+		}
+		i := index[providerID]
+		providers[i].Branches = append(providers[i].Branches, branch)
+		if closest[providerID] == id {
+			closestBranch := branch
+			providers[i].ClosestBranch = &closestBranch
 		}
-		return nil, err
+	}
+	return rows.Err()
+}
+
+// exportPageSize is number of providers read by each query of an export
+const exportPageSize = 500
+
+// ExportProviders calls f for every provider ordered by id, an error returned by f stops the export.
+// providers are read in pages, so f may take long without holding a connection or running into query timeout
+func (db *DataBase) ExportProviders(ctx context.Context, f func(Provider) error) error {
+	var lastID ID
+	for {
+		page, err := db.providersAfter(ctx, lastID)
+		if err != nil {
+			return err
+		}
+		for _, p := range page {
+			err = f(p)
+			if err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// providersAfter returns a page of providers with ids greater than id
+func (db *DataBase) providersAfter(ctx context.Context, id ID) ([]Provider, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	query := "select p.Id, coalesce(p.ExternalId, ''), p.Name, " + db.dialect.addressColumns("p.Address") + ", p.Radius, p.RadiusUnit, p.Rating, p.ReviewCount, p.MinimumCharge, p.TravelRate, p.MinArea, p.MaxArea, " + db.areaColumn("p.ServiceArea") + ", p.TimeZone, p.Status, p.ResumeAt from Provider p where p.Id > ? order by p.Id limit ?"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), id, exportPageSize)
+	if err != nil {
+		return nil, queryError(ctx, err)
 	}
 	defer func() { _ = rows.Close() }()
 	res := []Provider{}
 	for rows.Next() {
 		var (
 			item     Provider
-			distance float64
+			resumeAt sql.NullTime
 		)
-		err := rows.Scan(&item.ID, &item.Name, &item.Address.Lat, &item.Address.Long, &item.Radius, &item.Rating, &item.Wood, &item.Carpet, &item.Tile, &distance)
+		err := rows.Scan(&item.ID, &item.ExternalID, &item.Name, &item.Address.Lat, &item.Address.Long, &item.Radius, &item.RadiusUnit, &item.Rating, &item.ReviewCount, &item.MinimumCharge, &item.TravelRate, &item.JobArea.Min, &item.JobArea.Max, &item.ServiceArea, &item.TimeZone, &item.Status, &resumeAt)
 		if err != nil {
-			return nil, err
+			return nil, queryError(ctx, err)
 		}
+		item.ResumeAt = fromNullTime(resumeAt)
 		res = append(res, item)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadMaterials(ctx, res)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadBranches(ctx, res, nil)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadAvailability(ctx, res)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	err = db.loadWorkingHours(ctx, res)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
 	return res, nil
 }
 
+// GetMaterials get all materials in catalogue
+func (db *DataBase) GetMaterials(ctx context.Context) ([]Material, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	rows, err := db.db.QueryContext(ctx, "select Id, Name from Material order by Id")
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+	res := []Material{}
+	for rows.Next() {
+		var item Material
+		err := rows.Scan(&item.ID, &item.Name)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		res = append(res, item)
+	}
+	return res, queryError(ctx, rows.Err())
+}
+
+// GetProvider get a single provider by its id
+func (db *DataBase) GetProvider(ctx context.Context, id ID) (Provider, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	query := "select p.Id, coalesce(p.ExternalId, ''), p.Name, " + db.dialect.addressColumns("p.Address") + ", p.Radius, p.RadiusUnit, p.Rating, p.ReviewCount, p.MinimumCharge, p.TravelRate, p.MinArea, p.MaxArea, " + db.areaColumn("p.ServiceArea") + ", p.TimeZone, p.Status, p.ResumeAt from Provider p where p.Id = ?"
+	var (
+		item     Provider
+		resumeAt sql.NullTime
+	)
+	err := db.db.QueryRowContext(ctx, db.dialect.rebind(query), id).Scan(&item.ID, &item.ExternalID, &item.Name, &item.Address.Lat, &item.Address.Long, &item.Radius, &item.RadiusUnit, &item.Rating, &item.ReviewCount, &item.MinimumCharge, &item.TravelRate, &item.JobArea.Min, &item.JobArea.Max, &item.ServiceArea, &item.TimeZone, &item.Status, &resumeAt)
+	if err != nil {
+		return Provider{}, queryError(ctx, err)
+	}
+	item.ResumeAt = fromNullTime(resumeAt)
+	res := []Provider{item}
+	err = db.loadMaterials(ctx, res)
+	if err != nil {
+		return Provider{}, queryError(ctx, err)
+	}
+	err = db.loadBranches(ctx, res, nil)
+	if err != nil {
+		return Provider{}, queryError(ctx, err)
+	}
+	err = db.loadAvailability(ctx, res)
+	if err != nil {
+		return Provider{}, queryError(ctx, err)
+	}
+	err = db.loadWorkingHours(ctx, res)
+	if err != nil {
+		return Provider{}, queryError(ctx, err)
+	}
+	return res[0], nil
+}
+
 // AddProvider adds a new provider
-func (db *DataBase) AddProvider(p Provider) (ID, error) {
-	pointStr := fmt.Sprintf("POINT(%f %f)", p.Address.Lat, p.Address.Long)
-	query := `insert into Provider values(NULL, ?, ST_GeomFromText(?), ?, ?, ?, ?, ?)`
-	result, err := db.db.Exec(query, p.Name, pointStr, p.Radius, p.Rating, p.Wood, p.Carpet, p.Tile)
+func (db *DataBase) AddProvider(ctx context.Context, p Provider) (ID, error) {
+	if !p.RadiusUnit.Valid() || !p.JobArea.Valid() || p.ServiceArea.Validate() != nil || (p.Status != "" && !p.Status.Valid()) || p.validateSchedule() != nil {
+		return 0, ErrInvalid
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var id ID
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = db.insertProvider(ctx, tx, p)
+		return err
+	})
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// UpdateProvider replaces all fields of an existing provider but its status, see ChangeProviderStatus
+func (db *DataBase) UpdateProvider(ctx context.Context, p Provider) error {
+	if !p.RadiusUnit.Valid() || !p.JobArea.Valid() || p.ServiceArea.Validate() != nil || p.validateSchedule() != nil {
+		return ErrInvalid
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		return db.updateProvider(ctx, tx, p)
+	})
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
+
+// DeleteProvider removes a provider
+func (db *DataBase) DeleteProvider(ctx context.Context, id ID) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	result, err := db.db.ExecContext(ctx, db.dialect.rebind("delete from Provider where Id = ?"), id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
 
+// ImportProviders saves a batch of providers in a single transaction, a failing provider is reported in its result
+// without affecting others. with upsert a provider with an existing external id is updated instead of added
+func (db *DataBase) ImportProviders(ctx context.Context, providers []Provider, options ImportOptions) ([]ImportResult, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	results := make([]ImportResult, len(providers))
+	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, parseError(err)
+		return nil, queryError(ctx, err)
 	}
-	id, err := result.LastInsertId()
-	return ID(id), parseError(err)
+	defer func() { _ = tx.Rollback() }()
+	for i, p := range providers {
+		// each provider is saved under a savepoint so a failure only reverts that provider
+		_, err = tx.ExecContext(ctx, "savepoint import_provider")
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		results[i] = db.importProvider(ctx, tx, p, options)
+		if results[i].Err != nil {
+			_, err = tx.ExecContext(ctx, "rollback to savepoint import_provider")
+		} else {
+			_, err = tx.ExecContext(ctx, "release savepoint import_provider")
+		}
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+	}
+	if options.DryRun {
+		return results, nil
+	}
+	err = tx.Commit()
+	if err != nil {
+		return results, queryError(ctx, err)
+	}
+	return results, nil
+}
+
+func (db *DataBase) importProvider(ctx context.Context, tx *sql.Tx, p Provider, options ImportOptions) ImportResult {
+	if err := p.Validate(); err != nil {
+		return ImportResult{Err: err}
+	}
+	if options.Upsert && p.ExternalID != "" {
+		var (
+			current  Provider
+			resumeAt sql.NullTime
+		)
+		err := tx.QueryRowContext(ctx, db.dialect.rebind("select Id, Status, ResumeAt from Provider where ExternalId = ?"), p.ExternalID).Scan(&p.ID, &current.Status, &resumeAt)
+		switch {
+		case err == nil:
+			current.ResumeAt = fromNullTime(resumeAt)
+			change, changed := p.ImportStatus(current, options.Now)
+			err = db.updateProvider(ctx, tx, p)
+			if err == nil && changed {
+				err = db.saveStatus(ctx, tx, p, change)
+			}
+			return ImportResult{ID: p.ID, Updated: err == nil, Err: queryError(ctx, err)}
+		case !errors.Is(err, sql.ErrNoRows):
+			return ImportResult{Err: queryError(ctx, err)}
+		}
+	}
+	change, _ := p.ImportStatus(Provider{}, options.Now)
+	id, err := db.insertProvider(ctx, tx, p)
+	if err == nil {
+		change.ProviderID = id
+		err = db.recordStatusChanges(ctx, tx, change)
+	}
+	if err != nil {
+		return ImportResult{Err: queryError(ctx, err)}
+	}
+	return ImportResult{ID: id}
+}
+
+// insertProvider adds provider with its branches, availability, working hours and materials with their details in tx
+func (db *DataBase) insertProvider(ctx context.Context, tx *sql.Tx, p Provider) (ID, error) {
+	point, pointArgs := db.dialect.pointExpr(p.Address)
+	status := p.Status
+	if status == "" {
+		status = StatusActive
+	}
+	query := `insert into Provider (ExternalId, Name, Address, Radius, RadiusUnit, Rating, MinimumCharge, TravelRate, MinArea, MaxArea, ServiceArea, TimeZone, Status, ResumeAt) values(?, ?, ` + point + `, ?, ?, ?, ?, ?, ?, ?, ` + db.dialect.areaExpr() + `, ?, ?, ?)`
+	args := append(append([]interface{}{externalID(p), p.Name}, pointArgs...), p.Radius, p.RadiusUnit, p.Rating, p.MinimumCharge, p.TravelRate, p.JobArea.Min, p.JobArea.Max, p.ServiceArea, p.TimeZone, status, nullTime(p.ResumeAt))
+	id, err := db.dialect.insert(ctx, tx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setMaterials(ctx, tx, ID(id), p.Materials)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setRates(ctx, tx, ID(id), p.Rates)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setJobAreas(ctx, tx, ID(id), p.MaterialJobAreas)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setMaterialRadii(ctx, tx, ID(id), p.MaterialRadii)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setMaterialRatings(ctx, tx, ID(id), p.MaterialRatings)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setBranches(ctx, tx, ID(id), p.Branches)
+	if err != nil {
+		return 0, err
+	}
+	err = db.setAvailability(ctx, tx, ID(id), p.Availability, p.Blackouts)
+	if err != nil {
+		return 0, err
+	}
+	return ID(id), db.setWorkingHours(ctx, tx, ID(id), p.WorkingHours)
+}
+
+// updateProvider replaces all fields of provider with its branches, availability, working hours and materials with their details in tx, status is kept
+func (db *DataBase) updateProvider(ctx context.Context, tx *sql.Tx, p Provider) error {
+	point, pointArgs := db.dialect.pointExpr(p.Address)
+	query := `update Provider set ExternalId = ?, Name = ?, Address = ` + point + `, Radius = ?, RadiusUnit = ?, Rating = ?, MinimumCharge = ?, TravelRate = ?, MinArea = ?, MaxArea = ?, ServiceArea = ` + db.dialect.areaExpr() + `, TimeZone = ? where Id = ?`
+	args := append(append([]interface{}{externalID(p), p.Name}, pointArgs...), p.Radius, p.RadiusUnit, p.Rating, p.MinimumCharge, p.TravelRate, p.JobArea.Min, p.JobArea.Max, p.ServiceArea, p.TimeZone, p.ID)
+	result, err := tx.ExecContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// mysql reports zero affected rows when nothing has changed, so make sure the row actually exists
+		var exists int
+		err = tx.QueryRowContext(ctx, db.dialect.rebind("select 1 from Provider where Id = ?"), p.ID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+	}
+	err = db.setMaterials(ctx, tx, p.ID, p.Materials)
+	if err != nil {
+		return err
+	}
+	err = db.setRates(ctx, tx, p.ID, p.Rates)
+	if err != nil {
+		return err
+	}
+	err = db.setJobAreas(ctx, tx, p.ID, p.MaterialJobAreas)
+	if err != nil {
+		return err
+	}
+	err = db.setMaterialRadii(ctx, tx, p.ID, p.MaterialRadii)
+	if err != nil {
+		return err
+	}
+	err = db.setMaterialRatings(ctx, tx, p.ID, p.MaterialRatings)
+	if err != nil {
+		return err
+	}
+	err = db.setBranches(ctx, tx, p.ID, p.Branches)
+	if err != nil {
+		return err
+	}
+	err = db.setAvailability(ctx, tx, p.ID, p.Availability, p.Blackouts)
+	if err != nil {
+		return err
+	}
+	err = db.setWorkingHours(ctx, tx, p.ID, p.WorkingHours)
+	if err != nil {
+		return err
+	}
+	return db.updateRating(ctx, tx, p.ID)
+}
+
+// externalID stores empty external ids as null, so they are not checked for uniqueness
+func externalID(p Provider) sql.NullString {
+	return sql.NullString{String: p.ExternalID, Valid: p.ExternalID != ""}
+}
+
+// setMaterials replaces materials of a provider, all materials should exist in catalogue
+func (db *DataBase) setMaterials(ctx context.Context, tx *sql.Tx, id ID, materials []FloorMaterial) error {
+	_, err := tx.ExecContext(ctx, db.dialect.rebind("delete from ProviderMaterial where ProviderId = ?"), id)
+	if err != nil {
+		return err
+	}
+	if len(materials) == 0 {
+		return nil
+	}
+	var args []interface{}
+	unique := map[FloorMaterial]bool{}
+	for _, material := range materials {
+		if !unique[material] {
+			unique[material] = true
+			args = append(args, material)
+		}
+	}
+	args = append(args, id)
+	query := "insert into ProviderMaterial (ProviderId, MaterialId) select p.Id, m.Id from Provider p join Material m on m.Name in (" + placeholders(len(unique)) + ") where p.Id = ?"
+	result, err := tx.ExecContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != int64(len(unique)) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// setRates sets rates of materials just set by setMaterials, a rate of a material provider does not work with is invalid
+func (db *DataBase) setRates(ctx context.Context, tx *sql.Tx, id ID, rates map[FloorMaterial]float64) error {
+	query := "update ProviderMaterial set PricePerSquareMetre = ? where ProviderId = ? and MaterialId = (select Id from Material where Name = ?)"
+	for material, rate := range rates {
+		result, err := tx.ExecContext(ctx, db.dialect.rebind(query), rate, id, material)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// setJobAreas sets job areas of materials just set by setMaterials, a job area of a material provider does not work with is invalid
+func (db *DataBase) setJobAreas(ctx context.Context, tx *sql.Tx, id ID, areas map[FloorMaterial]AreaRange) error {
+	query := "update ProviderMaterial set MinArea = ?, MaxArea = ? where ProviderId = ? and MaterialId = (select Id from Material where Name = ?)"
+	for material, area := range areas {
+		if !area.Valid() {
+			return ErrInvalid
+		}
+		result, err := tx.ExecContext(ctx, db.dialect.rebind(query), area.Min, area.Max, id, material)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// setMaterialRadii sets radii of materials just set by setMaterials, a radius of a material provider does not work with is invalid
+func (db *DataBase) setMaterialRadii(ctx context.Context, tx *sql.Tx, id ID, radii map[FloorMaterial]MaterialRadius) error {
+	query := "update ProviderMaterial set Radius = ?, RadiusUnit = ? where ProviderId = ? and MaterialId = (select Id from Material where Name = ?)"
+	for material, radius := range radii {
+		if !(radius.Radius > 0) || !radius.RadiusUnit.Valid() {
+			return ErrInvalid
+		}
+		result, err := tx.ExecContext(ctx, db.dialect.rebind(query), radius.Radius, radius.RadiusUnit, id, material)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// setMaterialRatings sets ratings of materials just set by setMaterials, a rating of a material provider does not work with is invalid
+func (db *DataBase) setMaterialRatings(ctx context.Context, tx *sql.Tx, id ID, ratings map[FloorMaterial]float64) error {
+	query := "update ProviderMaterial set Rating = ? where ProviderId = ? and MaterialId = (select Id from Material where Name = ?)"
+	for material, rating := range ratings {
+		if rating < 0 || rating > 5 {
+			return ErrInvalid
+		}
+		result, err := tx.ExecContext(ctx, db.dialect.rebind(query), rating, id, material)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// setBranches replaces branches of a provider
+func (db *DataBase) setBranches(ctx context.Context, tx *sql.Tx, id ID, branches []Branch) error {
+	_, err := tx.ExecContext(ctx, db.dialect.rebind("delete from ProviderLocation where ProviderId = ?"), id)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		if branch.Validate() != nil {
+			return ErrInvalid
+		}
+		point, pointArgs := db.dialect.pointExpr(branch.Address)
+		query := "insert into ProviderLocation (ProviderId, Name, Address, Radius, RadiusUnit) values(?, ?, " + point + ", ?, ?)"
+		args := append(append([]interface{}{id, branch.Name}, pointArgs...), branch.Radius, branch.RadiusUnit)
+		_, err = db.dialect.insert(ctx, tx, db.dialect.rebind(query), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTimeout limits ctx to configured query timeout
+func (db *DataBase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
+
+// withTx runs f inside a transaction, committing on success and rolling back on error
+func (db *DataBase) withTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = f(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
@@ -15,325 +15,103 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		panic(err)
 	}
+	err = db.MigrateUp()
+	if err != nil {
+		panic(err)
+	}
 	m.Run()
 }
 
-func PopulateDB(providers []Provider) {
-	err := db.Clear()
+func TestMigrations(t *testing.T) {
+	RegisterTestingT(t)
+	migrations, err := db.Migrations()
 	Expect(err).To(BeNil())
-	for i := range providers {
-		id, err := db.AddProvider(providers[i])
-		Expect(err).To(BeNil())
-		providers[i].ID = id
-	}
-}
+	Expect(migrations).NotTo(BeEmpty())
+	latest := len(migrations)
 
-func TestMatchingMaterial(t *testing.T) {
-	RegisterTestingT(t)
-	providers := []Provider{
-		{
-			Name: "p0",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: false,
-			Tile:   false,
-		},
-		{
-			Name: "p1",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
-		},
-		{
-			Name: "p2",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: true,
-			Tile:   false,
-		},
-		{
-			Name: "p3",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: false,
-			Tile:   true,
-		},
-		{
-			Name: "p4",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   false,
-		},
-		{
-			Name: "p5",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   true,
-		},
-		{
-			Name: "p6",
-			Address: Address{
-				Lat:  -26,
-				Long: 40,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: true,
-			Tile:   true,
-		},
+	version, err := db.SchemaVersion()
+	Expect(err).To(BeNil())
+	Expect(version).To(Equal(latest))
+
+	err = db.MigrateTo(0)
+	Expect(err).To(BeNil())
+	statuses, err := db.MigrationStatus()
+	Expect(err).To(BeNil())
+	Expect(statuses).To(HaveLen(latest))
+	for _, status := range statuses {
+		Expect(status.Applied).To(BeFalse())
 	}
-	PopulateDB(providers)
-	res, err := db.GetProviders(FloorWood, Address{Lat: -26, Long: 40})
+
+	err = db.MigrateUp()
 	Expect(err).To(BeNil())
-	Expect(res).To(ConsistOf([]Provider{providers[1], providers[4], providers[5]}))
-	res, err = db.GetProviders(FloorCarpet, Address{Lat: -26, Long: 40})
+	err = db.MigrateDown()
+	Expect(err).To(BeNil())
+	version, err = db.SchemaVersion()
+	Expect(err).To(BeNil())
+	Expect(version).To(Equal(latest - 1))
+
+	err = db.MigrateUp()
 	Expect(err).To(BeNil())
-	Expect(res).To(ConsistOf([]Provider{providers[2], providers[4], providers[6]}))
-	res, err = db.GetProviders(FloorTile, Address{Lat: -26, Long: 40})
+	statuses, err = db.MigrationStatus()
 	Expect(err).To(BeNil())
-	Expect(res).To(ConsistOf([]Provider{providers[3], providers[5], providers[6]}))
+	for _, status := range statuses {
+		Expect(status.Applied).To(BeTrue())
+	}
+	Expect(db.MigrateTo(latest + 1)).NotTo(BeNil())
 }
 
-func TestExcludeOutOfRadius(t *testing.T) {
+func TestSplitStatements(t *testing.T) {
 	RegisterTestingT(t)
-	providers := []Provider{
-		{
-			Name: "p0",
-			Address: Address{
-				Lat:  -26.66119,
-				Long: 40.95858,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p1",
-			Address: Address{
-				Lat:  -26.66129,
-				Long: 40.95858,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p2",
-			Address: Address{
-				Lat:  -26.66119,
-				Long: 40.95868,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p3",
-			Address: Address{
-				Lat:  -26.66129,
-				Long: 40.95868,
-			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-	}
-	PopulateDB(providers)
-	res, err := db.GetProviders(FloorWood, Address{Lat: -26.66119, Long: 40.95858})
-	Expect(err).To(BeNil())
-	Expect(res).To(ConsistOf([]Provider{providers[0], providers[1]}))
+	script := `-- comment
+CREATE TABLE t (
+    a INT NOT NULL);
+
+INSERT INTO t VALUES (1), (2);
+DROP TABLE t`
+	Expect(splitStatements(script)).To(Equal([]string{
+		"CREATE TABLE t (\n    a INT NOT NULL)",
+		"INSERT INTO t VALUES (1), (2)",
+		"DROP TABLE t",
+	}))
 }
 
-func TestOrder(t *testing.T) {
+func TestRebind(t *testing.T) {
 	RegisterTestingT(t)
-	providers := []Provider{
-		{
-			Name: "p0",
-			Address: Address{
-				Lat:  -26.66129,
-				Long: 40.95858,
-			},
-			Radius: 100,
-			Rating: 3.5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p1",
-			Address: Address{
-				Lat:  -26.66139,
-				Long: 40.95858,
-			},
-			Radius: 100,
-			Rating: 3.5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p2",
-			Address: Address{
-				Lat:  -26.66139,
-				Long: 40.95878,
-			},
-			Radius: 100,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p3",
-			Address: Address{
-				Lat:  -26.66119,
-				Long: 40.95858,
-			},
-			Radius: 10,
-			Rating: 3.0,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-	}
-	PopulateDB(providers)
-	res, err := db.GetProviders(FloorWood, Address{Lat: -26.66119, Long: 40.95858})
-	Expect(err).To(BeNil())
-	Expect(res).To(Equal([]Provider{providers[2], providers[0], providers[1], providers[3]}))
+	query := "select Id from Provider where Id = ? and Name in (?, ?)"
+	Expect(mysqlDialect{}.rebind(query)).To(Equal(query))
+	Expect(postgresDialect{}.rebind(query)).To(Equal("select Id from Provider where Id = $1 and Name in ($2, $3)"))
 }
 
-func TestMultipleChecks(t *testing.T) {
+func TestBoundingBoxes(t *testing.T) {
 	RegisterTestingT(t)
-	providers := []Provider{
-		{
-			Name: "p0",
-			Address: Address{
-				Lat:  -26.66119,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 3.5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p1",
-			Address: Address{
-				Lat:  -26.66120,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 4.5,
-			Wood:   false,
-			Carpet: true,
-			Tile:   true,
-		},
-		{
-			Name: "p2",
-			Address: Address{
-				Lat:  -26.66116,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 4.5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
-		},
-		{
-			Name: "p3",
-			Address: Address{
-				Lat:  -26.66117,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 4.7,
-			Wood:   true,
-			Carpet: true,
-			Tile:   false,
-		},
-		{
-			Name: "p4",
-			Address: Address{
-				Lat:  -26.66115,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 4.5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
-		},
-		{
-			Name: "p5",
-			Address: Address{
-				Lat:  -26.66118,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 4.1,
-			Wood:   true,
-			Carpet: false,
-			Tile:   true,
-		},
-		{
-			Name: "p6",
-			Address: Address{
-				Lat:  -26.66116,
-				Long: 40.95858,
-			},
-			Radius: 2,
-			Rating: 4.8,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
-		},
-	}
-	PopulateDB(providers)
-	res, err := db.GetProviders(FloorWood, Address{Lat: -26.66119, Long: 40.95858})
-	Expect(err).To(BeNil())
-	Expect(res).To(Equal([]Provider{providers[3], providers[5], providers[0]}))
+	// one degree of latitude is about 111195 meters
+	boxes := boundingBoxes(Address{Lat: 0, Long: 0}, 111195)
+	Expect(boxes).To(HaveLen(1))
+	Expect(boxes[0].MinLat).To(BeNumerically("~", -1, 0.01))
+	Expect(boxes[0].MaxLat).To(BeNumerically("~", 1, 0.01))
+	Expect(boxes[0].MinLong).To(BeNumerically("~", -1, 0.01))
+	Expect(boxes[0].MaxLong).To(BeNumerically("~", 1, 0.01))
+
+	// longitude difference grows with latitude
+	boxes = boundingBoxes(Address{Lat: 60, Long: 0}, 111195)
+	Expect(boxes).To(HaveLen(1))
+	Expect(boxes[0].MaxLong).To(BeNumerically(">", 2))
+
+	boxes = boundingBoxes(Address{Lat: 0, Long: 179.5}, 111195)
+	Expect(boxes).To(HaveLen(2))
+	Expect(boxes[0].MaxLong).To(Equal(180.0))
+	Expect(boxes[1].MinLong).To(Equal(-180.0))
+	Expect(boxes[1].MaxLong).To(BeNumerically("~", -179.5, 0.01))
+
+	Expect(boundingBoxes(Address{Lat: 89.5, Long: 0}, 111195)).To(BeNil())
+
+	Expect(boundingBox{MinLat: -1, MinLong: -2, MaxLat: 1, MaxLong: 2}.polygonWKT()).To(Equal("POLYGON((-2 -1, 2 -1, 2 1, -2 1, -2 -1))"))
+}
+
+func TestBackoff(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(backoff(0)).To(Equal(initialBackoff))
+	Expect(backoff(1)).To(Equal(2 * initialBackoff))
+	Expect(backoff(3)).To(Equal(8 * initialBackoff))
+	Expect(backoff(100)).To(Equal(maxBackoff))
 }
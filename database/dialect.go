@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// dialect hides differences between supported sql servers, queries are written with ? placeholders
+type dialect interface {
+	// driverName is name of the database/sql driver
+	driverName() string
+	// migrationsDir is directory of embedded migrations written for this server
+	migrationsDir() string
+	// transactionalDDL reports whether schema changes are rolled back with their transaction
+	transactionalDDL() bool
+	// rebind replaces ? placeholders with server specific ones
+	rebind(query string) string
+	// addressColumns selects latitude and longitude of an address column
+	addressColumns(column string) string
+	// pointExpr builds an address value from location
+	pointExpr(location Address) (string, []interface{})
+	// distanceExpr calculates distance between an address column and location in meters
+	distanceExpr(column string, location Address) (string, []interface{})
+	// withinExpr narrows rows to ones possibly closer than distance meters to location using spatial index,
+	// an empty expression means no narrowing is possible
+	withinExpr(column string, location Address, distance float64) (string, []interface{})
+	// areaExpr builds a multipolygon value from GeoJSON text placeholder, null text is an empty area
+	areaExpr() string
+	// emptyAreaExpr checks whether an area column holds no area
+	emptyAreaExpr(column string) string
+	// coversExpr checks whether location is inside an area column using spatial index
+	coversExpr(column string, location Address) (string, []interface{})
+	// insert runs an insert query and returns id of the new row
+	insert(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int64, error)
+	// lock acquires a session level named lock on conn
+	lock(ctx context.Context, conn *sql.Conn, name string) error
+	// unlock releases a lock acquired by lock
+	unlock(ctx context.Context, conn *sql.Conn, name string)
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// querier runs queries on a connection pool or in a transaction
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// AddLead saves a customer request with providers shown for it and offers made, CreatedAt is set by caller
+func (db *DataBase) AddLead(ctx context.Context, lead Lead) (ID, error) {
+	err := lead.Validate()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var id ID
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		var materialID ID
+		err := tx.QueryRowContext(ctx, db.dialect.rebind("select Id from Material where Name = ?"), lead.Material).Scan(&materialID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalid
+		}
+		if err != nil {
+			return err
+		}
+		point, pointArgs := db.dialect.pointExpr(lead.Address)
+		query := "insert into CustomerLead (MaterialId, Address, Area, PhoneNumber, CreatedAt, Status) values (?, " + point + ", ?, ?, ?, ?)"
+		args := append(append([]interface{}{materialID}, pointArgs...), lead.Area, lead.PhoneNumber, lead.CreatedAt.UTC(), lead.Status)
+		inserted, err := db.dialect.insert(ctx, tx, db.dialect.rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		id = ID(inserted)
+		err = db.addLeadProviders(ctx, tx, id, lead.ProviderIDs)
+		if err != nil {
+			return err
+		}
+		return db.addLeadOffers(ctx, tx, id, lead.Offers)
+	})
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// addLeadProviders saves providers shown for a lead in order
+func (db *DataBase) addLeadProviders(ctx context.Context, tx *sql.Tx, leadID ID, providerIDs []ID) error {
+	if len(providerIDs) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(providerIDs))
+	args := make([]interface{}, 0, 3*len(providerIDs))
+	for i, providerID := range providerIDs {
+		values = append(values, "(?, ?, ?)")
+		args = append(args, leadID, i, providerID)
+	}
+	query := "insert into LeadProvider (LeadId, Ordinal, ProviderId) values " + strings.Join(values, ", ")
+	_, err := tx.ExecContext(ctx, db.dialect.rebind(query), args...)
+	return err
+}
+
+// addLeadOffers saves offers of a lead in order
+func (db *DataBase) addLeadOffers(ctx context.Context, tx *sql.Tx, leadID ID, offers []LeadOffer) error {
+	if len(offers) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(offers))
+	args := make([]interface{}, 0, 5*len(offers))
+	for i, offer := range offers {
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, leadID, i, offer.ProviderID, offer.Status, offer.ExpiresAt.UTC())
+	}
+	query := "insert into LeadOffer (LeadId, Ordinal, ProviderId, Status, ExpiresAt) values " + strings.Join(values, ", ")
+	_, err := tx.ExecContext(ctx, db.dialect.rebind(query), args...)
+	return err
+}
+
+// GetLead returns a lead with providers shown for it and its offers as saved, see Lead.Expire
+func (db *DataBase) GetLead(ctx context.Context, id ID) (Lead, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	lead, err := db.getLead(ctx, db.db, id)
+	if err != nil {
+		return Lead{}, queryError(ctx, err)
+	}
+	return lead, nil
+}
+
+// GetOfferedLeads returns a page of leads offered to a provider, newest first, and total number of its offers.
+// leads are as saved, see Lead.Expire
+func (db *DataBase) GetOfferedLeads(ctx context.Context, providerID ID, page Page) ([]Lead, int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var total int
+	err := db.db.QueryRowContext(ctx, db.dialect.rebind("select count(*) from LeadOffer where ProviderId = ?"), providerID).Scan(&total)
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	query := "select l.Id from LeadOffer o join CustomerLead l on l.Id = o.LeadId where o.ProviderId = ? order by l.CreatedAt desc, l.Id desc limit ? offset ?"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), providerID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	ids := []ID{}
+	for rows.Next() {
+		var id ID
+		err := rows.Scan(&id)
+		if err != nil {
+			_ = rows.Close()
+			return nil, 0, queryError(ctx, err)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	res := make([]Lead, 0, len(ids))
+	for _, id := range ids {
+		lead, err := db.getLead(ctx, db.db, id)
+		if err != nil {
+			return nil, 0, queryError(ctx, err)
+		}
+		res = append(res, lead)
+	}
+	return res, total, nil
+}
+
+// ApplyLeadAction applies an action of a provider to a lead at time now and saves the result,
+// see Lead.Apply. Expired offers are saved as expired even when the action fails
+func (db *DataBase) ApplyLeadAction(ctx context.Context, id, providerID ID, action LeadAction, now time.Time) (Lead, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var lead Lead
+	var applyErr error
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		// lead row is locked, so two providers can not accept the same lead
+		var locked ID
+		err := tx.QueryRowContext(ctx, db.dialect.rebind("select Id from CustomerLead where Id = ? for update"), id).Scan(&locked)
+		if err != nil {
+			return err
+		}
+		lead, err = db.getLead(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		saved := lead.Status
+		offers := append([]LeadOffer{}, lead.Offers...)
+		applyErr = lead.Apply(providerID, action, now)
+		return db.saveLeadState(ctx, tx, lead, saved, offers)
+	})
+	if err != nil {
+		return Lead{}, queryError(ctx, err)
+	}
+	if applyErr != nil {
+		return Lead{}, applyErr
+	}
+	return lead, nil
+}
+
+// saveLeadState saves status of a lead and its offers which differ from saved ones
+func (db *DataBase) saveLeadState(ctx context.Context, tx *sql.Tx, lead Lead, saved LeadStatus, offers []LeadOffer) error {
+	if lead.Status != saved {
+		_, err := tx.ExecContext(ctx, db.dialect.rebind("update CustomerLead set Status = ? where Id = ?"), lead.Status, lead.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for i, offer := range lead.Offers {
+		if offer.Status == offers[i].Status {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, db.dialect.rebind("update LeadOffer set Status = ? where LeadId = ? and Ordinal = ?"), offer.Status, lead.ID, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DataBase) getLead(ctx context.Context, q querier, id ID) (Lead, error) {
+	query := "select l.Id, m.Name, " + db.dialect.addressColumns("l.Address") + ", l.Area, l.PhoneNumber, l.CreatedAt, l.Status from CustomerLead l join Material m on m.Id = l.MaterialId where l.Id = ?"
+	var lead Lead
+	err := q.QueryRowContext(ctx, db.dialect.rebind(query), id).Scan(&lead.ID, &lead.Material, &lead.Address.Lat, &lead.Address.Long, &lead.Area, &lead.PhoneNumber, &lead.CreatedAt, &lead.Status)
+	if err != nil {
+		return Lead{}, err
+	}
+	lead.CreatedAt = lead.CreatedAt.UTC()
+	lead.ProviderIDs, err = db.getLeadProviders(ctx, q, id)
+	if err != nil {
+		return Lead{}, err
+	}
+	lead.Offers, err = db.getLeadOffers(ctx, q, id)
+	if err != nil {
+		return Lead{}, err
+	}
+	return lead, nil
+}
+
+func (db *DataBase) getLeadProviders(ctx context.Context, q querier, id ID) ([]ID, error) {
+	rows, err := q.QueryContext(ctx, db.dialect.rebind("select ProviderId from LeadProvider where LeadId = ? order by Ordinal"), id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	res := []ID{}
+	for rows.Next() {
+		var providerID ID
+		err := rows.Scan(&providerID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, providerID)
+	}
+	return res, rows.Err()
+}
+
+func (db *DataBase) getLeadOffers(ctx context.Context, q querier, id ID) ([]LeadOffer, error) {
+	rows, err := q.QueryContext(ctx, db.dialect.rebind("select ProviderId, Status, ExpiresAt from LeadOffer where LeadId = ? order by Ordinal"), id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	res := []LeadOffer{}
+	for rows.Next() {
+		var offer LeadOffer
+		err := rows.Scan(&offer.ProviderID, &offer.Status, &offer.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		offer.ExpiresAt = offer.ExpiresAt.UTC()
+		res = append(res, offer)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/mysql/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+const migrationLockName = "floor_schema_migrations"
+
+// Migration is a single numbered schema change with its up and down scripts
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus shows whether a migration is applied to database
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations returns all embedded migrations for database server ordered by version
+func (db *DataBase) Migrations() ([]Migration, error) {
+	return loadMigrations(db.dialect.migrationsDir())
+}
+
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		// file names are in form of 0001_name.up.sql or 0001_name.down.sql
+		fileName := entry.Name()
+		base := strings.TrimSuffix(fileName, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %s", fileName)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", fileName)
+		}
+		content, err := migrationFiles.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("conflicting names for migration %d: %s, %s", version, m.Name, parts[1])
+		}
+		if direction == ".up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	for i := range res {
+		if res[i].Version != i+1 {
+			return nil, fmt.Errorf("missing migration %d", i+1)
+		}
+	}
+	return res, nil
+}
+
+// MigrateUp applies all pending migrations
+func (db *DataBase) MigrateUp() error {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+	return db.MigrateTo(len(migrations))
+}
+
+// MigrateDown reverts the last applied migration
+func (db *DataBase) MigrateDown() error {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	return db.MigrateTo(version - 1)
+}
+
+// MigrateTo applies or reverts migrations until schema is at the given version
+func (db *DataBase) MigrateTo(version int) error {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+	if version < 0 || version > len(migrations) {
+		return fmt.Errorf("unknown schema version %d, latest is %d", version, len(migrations))
+	}
+
+	ctx := context.Background()
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return parseError(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// prevent concurrent instances from migrating at the same time
+	err = db.dialect.lock(ctx, conn, migrationLockName)
+	if err != nil {
+		return err
+	}
+	defer db.dialect.unlock(ctx, conn, migrationLockName)
+
+	err = createMigrationsTable(ctx, conn)
+	if err != nil {
+		return err
+	}
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for current < version {
+		m := migrations[current]
+		err = db.runMigration(ctx, conn, m.Up, "insert into schema_migrations (version, name) values (?, ?)", m.Version, m.Name)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s up failed: %w", m.Version, m.Name, err)
+		}
+		current++
+	}
+	for current > version {
+		m := migrations[current-1]
+		if m.Down == "" {
+			return fmt.Errorf("migration %d_%s is irreversible", m.Version, m.Name)
+		}
+		err = db.runMigration(ctx, conn, m.Down, "delete from schema_migrations where version = ?", m.Version)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s down failed: %w", m.Version, m.Name, err)
+		}
+		current--
+	}
+	return nil
+}
+
+// SchemaVersion returns version of the last applied migration, 0 if nothing is applied
+func (db *DataBase) SchemaVersion() (int, error) {
+	ctx := context.Background()
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return 0, parseError(err)
+	}
+	defer func() { _ = conn.Close() }()
+	err = createMigrationsTable(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	return schemaVersion(ctx, conn)
+}
+
+// MigrationStatus returns status of all known migrations
+func (db *DataBase) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	defer func() { _ = conn.Close() }()
+	err = createMigrationsTable(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "select version, applied_at from schema_migrations")
+	if err != nil {
+		return nil, parseError(err)
+	}
+	defer func() { _ = rows.Close() }()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		res = append(res, MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return res, nil
+}
+
+func createMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	query := "create table if not exists schema_migrations (version int not null, name varchar(255) not null, applied_at timestamp not null default current_timestamp, primary key (version))"
+	_, err := conn.ExecContext(ctx, query)
+	return parseError(err)
+}
+
+func schemaVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	var version int
+	err := conn.QueryRowContext(ctx, "select coalesce(max(version), 0) from schema_migrations").Scan(&version)
+	return version, parseError(err)
+}
+
+// execer runs statements on a connection or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// runMigration runs script and then query recording it in schema_migrations. where ddl is transactional both run
+// in one transaction, so a failing script leaves neither a half applied schema nor a wrong version
+func (db *DataBase) runMigration(ctx context.Context, conn *sql.Conn, script string, query string, args ...interface{}) error {
+	if !db.dialect.transactionalDDL() {
+		return execMigration(ctx, conn, script, db.dialect.rebind(query), args...)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return parseError(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	err = execMigration(ctx, tx, script, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	return parseError(tx.Commit())
+}
+
+func execMigration(ctx context.Context, ex execer, script string, query string, args ...interface{}) error {
+	for _, statement := range splitStatements(script) {
+		_, err := ex.ExecContext(ctx, statement)
+		if err != nil {
+			return parseError(err)
+		}
+	}
+	_, err := ex.ExecContext(ctx, query, args...)
+	return parseError(err)
+}
+
+// splitStatements splits a sql script into statements, each statement should end with a semicolon at end of line
+func splitStatements(script string) []string {
+	var (
+		res     []string
+		current strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			res = append(res, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		res = append(res, rest)
+	}
+	return res
+}
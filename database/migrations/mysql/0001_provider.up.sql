@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `Provider` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `Name` VARCHAR(45) NOT NULL,
+    `Address` GEOMETRY NOT NULL,
+    `Radius` DOUBLE NOT NULL,
+    `Rating` DOUBLE NOT NULL,
+    `Wood` TINYINT NOT NULL,
+    `Carpet` TINYINT NOT NULL,
+    `Tile` TINYINT NOT NULL,
+    PRIMARY KEY (`Id`),
+    SPATIAL INDEX `Location` (`Address`) VISIBLE,
+    INDEX `Rating` (`Rating` ASC) VISIBLE)
+    ENGINE = InnoDB;
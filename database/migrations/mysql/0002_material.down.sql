@@ -0,0 +1,18 @@
+ALTER TABLE `Provider`
+    ADD COLUMN `Wood` TINYINT NOT NULL DEFAULT 0,
+    ADD COLUMN `Carpet` TINYINT NOT NULL DEFAULT 0,
+    ADD COLUMN `Tile` TINYINT NOT NULL DEFAULT 0;
+
+UPDATE `Provider` p SET
+    p.Wood = EXISTS (SELECT 1 FROM `ProviderMaterial` pm JOIN `Material` m ON m.Id = pm.MaterialId WHERE pm.ProviderId = p.Id AND m.Name = 'wood'),
+    p.Carpet = EXISTS (SELECT 1 FROM `ProviderMaterial` pm JOIN `Material` m ON m.Id = pm.MaterialId WHERE pm.ProviderId = p.Id AND m.Name = 'carpet'),
+    p.Tile = EXISTS (SELECT 1 FROM `ProviderMaterial` pm JOIN `Material` m ON m.Id = pm.MaterialId WHERE pm.ProviderId = p.Id AND m.Name = 'tile');
+
+ALTER TABLE `Provider`
+    ALTER COLUMN `Wood` DROP DEFAULT,
+    ALTER COLUMN `Carpet` DROP DEFAULT,
+    ALTER COLUMN `Tile` DROP DEFAULT;
+
+DROP TABLE IF EXISTS `ProviderMaterial`;
+
+DROP TABLE IF EXISTS `Material`;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS `Material` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `Name` VARCHAR(45) NOT NULL,
+    PRIMARY KEY (`Id`),
+    UNIQUE INDEX `Name` (`Name` ASC) VISIBLE)
+    ENGINE = InnoDB;
+
+INSERT IGNORE INTO `Material` (`Id`, `Name`) VALUES (1, 'wood'), (2, 'carpet'), (3, 'tile');
+
+CREATE TABLE IF NOT EXISTS `ProviderMaterial` (
+    `ProviderId` INT NOT NULL,
+    `MaterialId` INT NOT NULL,
+    PRIMARY KEY (`ProviderId`, `MaterialId`),
+    INDEX `Material` (`MaterialId` ASC) VISIBLE,
+    CONSTRAINT `fk_ProviderMaterial_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE,
+    CONSTRAINT `fk_ProviderMaterial_Material`
+        FOREIGN KEY (`MaterialId`) REFERENCES `Material` (`Id`))
+    ENGINE = InnoDB;
+
+INSERT IGNORE INTO `ProviderMaterial` (`ProviderId`, `MaterialId`)
+SELECT p.Id, m.Id FROM `Provider` p JOIN `Material` m ON m.Name = 'wood' WHERE p.Wood = 1;
+
+INSERT IGNORE INTO `ProviderMaterial` (`ProviderId`, `MaterialId`)
+SELECT p.Id, m.Id FROM `Provider` p JOIN `Material` m ON m.Name = 'carpet' WHERE p.Carpet = 1;
+
+INSERT IGNORE INTO `ProviderMaterial` (`ProviderId`, `MaterialId`)
+SELECT p.Id, m.Id FROM `Provider` p JOIN `Material` m ON m.Name = 'tile' WHERE p.Tile = 1;
+
+ALTER TABLE `Provider` DROP COLUMN `Wood`, DROP COLUMN `Carpet`, DROP COLUMN `Tile`;
@@ -0,0 +1,18 @@
+-- radiuses are compared in meters without unit
+UPDATE `Provider` SET `Radius` = `RadiusMeters`;
+
+ALTER TABLE `Provider` DROP COLUMN `RadiusMeters`;
+
+ALTER TABLE `Provider` DROP CHECK `chk_Provider_RadiusUnit`, DROP COLUMN `RadiusUnit`;
+
+ALTER TABLE `Provider` DROP INDEX `Location`;
+
+ALTER TABLE `Provider` ADD COLUMN `Location` GEOMETRY NULL AFTER `Address`;
+
+UPDATE `Provider` SET `Location` = ST_GeomFromText(CONCAT('POINT(', ST_Latitude(`Address`), ' ', ST_Longitude(`Address`), ')'));
+
+ALTER TABLE `Provider` DROP COLUMN `Address`;
+
+ALTER TABLE `Provider` CHANGE COLUMN `Location` `Address` GEOMETRY NOT NULL;
+
+ALTER TABLE `Provider` ADD SPATIAL INDEX `Location` (`Address`);
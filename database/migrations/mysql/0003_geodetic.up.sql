@@ -0,0 +1,20 @@
+-- addresses were stored as POINT(lat long) without SRID, rewrite them as SRID 4326 points
+ALTER TABLE `Provider` DROP INDEX `Location`;
+
+ALTER TABLE `Provider` ADD COLUMN `Location` POINT SRID 4326 NULL AFTER `Address`;
+
+UPDATE `Provider` SET `Location` = ST_GeomFromText(CONCAT('POINT(', ST_Y(`Address`), ' ', ST_X(`Address`), ')'), 4326, 'axis-order=long-lat');
+
+ALTER TABLE `Provider` DROP COLUMN `Address`;
+
+ALTER TABLE `Provider` CHANGE COLUMN `Location` `Address` POINT NOT NULL SRID 4326;
+
+ALTER TABLE `Provider` ADD SPATIAL INDEX `Location` (`Address`);
+
+-- existing radiuses were meant to be kilometers
+ALTER TABLE `Provider`
+    ADD COLUMN `RadiusUnit` VARCHAR(2) NOT NULL DEFAULT 'km' AFTER `Radius`,
+    ADD CONSTRAINT `chk_Provider_RadiusUnit` CHECK (`RadiusUnit` IN ('m', 'km', 'mi'));
+
+ALTER TABLE `Provider`
+    ADD COLUMN `RadiusMeters` DOUBLE AS (`Radius` * CASE `RadiusUnit` WHEN 'km' THEN 1000 WHEN 'mi' THEN 1609.344 ELSE 1 END) STORED AFTER `RadiusUnit`;
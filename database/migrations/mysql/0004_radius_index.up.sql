@@ -0,0 +1,2 @@
+-- largest radius bounds the area searched through spatial index
+ALTER TABLE `Provider` ADD INDEX `RadiusMeters` (`RadiusMeters` ASC) VISIBLE;
@@ -0,0 +1 @@
+ALTER TABLE `Provider` DROP INDEX `ExternalId`, DROP COLUMN `ExternalId`;
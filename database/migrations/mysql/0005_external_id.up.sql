@@ -0,0 +1,4 @@
+-- id of provider in an external system, used to update providers by bulk import
+ALTER TABLE `Provider`
+    ADD COLUMN `ExternalId` VARCHAR(64) NULL AFTER `Id`,
+    ADD UNIQUE INDEX `ExternalId` (`ExternalId` ASC) VISIBLE;
@@ -0,0 +1,15 @@
+-- reviews of customers, rating of a reviewed provider is average score of its reviews
+CREATE TABLE IF NOT EXISTS `Review` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `ProviderId` INT NOT NULL,
+    `Score` TINYINT NOT NULL,
+    `Text` VARCHAR(2000) NOT NULL DEFAULT '',
+    `LeadId` INT NULL,
+    `CreatedAt` DATETIME NOT NULL,
+    PRIMARY KEY (`Id`),
+    INDEX `ProviderCreatedAt` (`ProviderId` ASC, `CreatedAt` DESC) VISIBLE,
+    CONSTRAINT `chk_Review_Score` CHECK (`Score` BETWEEN 1 AND 5),
+    CONSTRAINT `fk_Review_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
@@ -0,0 +1,4 @@
+-- number of reviews rating is averaged from, used to rank providers
+ALTER TABLE `Provider` ADD COLUMN `ReviewCount` INT NOT NULL DEFAULT 0 AFTER `Rating`;
+
+UPDATE `Provider` p SET p.ReviewCount = (SELECT COUNT(*) FROM `Review` r WHERE r.ProviderId = p.Id);
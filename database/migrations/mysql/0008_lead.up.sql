@@ -0,0 +1,25 @@
+-- customer requests for providers, lead is a reserved word so table is named CustomerLead
+CREATE TABLE IF NOT EXISTS `CustomerLead` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `MaterialId` INT NOT NULL,
+    `Address` POINT NOT NULL SRID 4326,
+    `Area` DOUBLE NOT NULL,
+    `PhoneNumber` VARCHAR(32) NOT NULL,
+    `CreatedAt` DATETIME NOT NULL,
+    PRIMARY KEY (`Id`),
+    INDEX `CreatedAt` (`CreatedAt` ASC) VISIBLE,
+    CONSTRAINT `fk_CustomerLead_Material`
+        FOREIGN KEY (`MaterialId`) REFERENCES `Material` (`Id`))
+    ENGINE = InnoDB;
+
+-- providers shown for a lead, they are kept as shown even after a provider is deleted
+CREATE TABLE IF NOT EXISTS `LeadProvider` (
+    `LeadId` INT NOT NULL,
+    `Ordinal` INT NOT NULL,
+    `ProviderId` INT NOT NULL,
+    PRIMARY KEY (`LeadId`, `Ordinal`),
+    INDEX `Provider` (`ProviderId` ASC) VISIBLE,
+    CONSTRAINT `fk_LeadProvider_CustomerLead`
+        FOREIGN KEY (`LeadId`) REFERENCES `CustomerLead` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
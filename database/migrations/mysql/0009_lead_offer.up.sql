@@ -0,0 +1,19 @@
+-- leads saved before offers existed were never offered to anyone
+ALTER TABLE `CustomerLead` ADD COLUMN `Status` VARCHAR(16) NOT NULL DEFAULT 'offered';
+
+UPDATE `CustomerLead` SET `Status` = 'expired';
+
+-- offers of a lead to top matched providers, pending offers past ExpiresAt are expired
+CREATE TABLE IF NOT EXISTS `LeadOffer` (
+    `LeadId` INT NOT NULL,
+    `Ordinal` INT NOT NULL,
+    `ProviderId` INT NOT NULL,
+    `Status` VARCHAR(16) NOT NULL,
+    `ExpiresAt` DATETIME NOT NULL,
+    PRIMARY KEY (`LeadId`, `Ordinal`),
+    UNIQUE INDEX `LeadProvider` (`LeadId` ASC, `ProviderId` ASC) VISIBLE,
+    INDEX `Provider` (`ProviderId` ASC) VISIBLE,
+    CONSTRAINT `fk_LeadOffer_CustomerLead`
+        FOREIGN KEY (`LeadId`) REFERENCES `CustomerLead` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
@@ -0,0 +1,3 @@
+ALTER TABLE `Provider` DROP COLUMN `MinimumCharge`, DROP COLUMN `TravelRate`;
+
+ALTER TABLE `ProviderMaterial` DROP COLUMN `PricePerSquareMetre`;
@@ -0,0 +1,7 @@
+-- price per square metre of a material, materials without a price are not estimated
+ALTER TABLE `ProviderMaterial` ADD COLUMN `PricePerSquareMetre` DOUBLE NULL;
+
+-- least price of a job and surcharge per kilometre of distance to customer
+ALTER TABLE `Provider`
+    ADD COLUMN `MinimumCharge` DOUBLE NOT NULL DEFAULT 0,
+    ADD COLUMN `TravelRate` DOUBLE NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE `Provider` DROP COLUMN `ServiceArea`;
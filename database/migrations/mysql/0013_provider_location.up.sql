@@ -0,0 +1,18 @@
+-- branches of providers, a provider is matched when its address or any of its branches covers the location
+CREATE TABLE IF NOT EXISTS `ProviderLocation` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `ProviderId` INT NOT NULL,
+    `Name` VARCHAR(45) NOT NULL,
+    `Address` POINT NOT NULL SRID 4326,
+    `Radius` DOUBLE NOT NULL,
+    `RadiusUnit` VARCHAR(2) NOT NULL DEFAULT 'km',
+    `RadiusMeters` DOUBLE AS (`Radius` * CASE `RadiusUnit` WHEN 'km' THEN 1000 WHEN 'mi' THEN 1609.344 ELSE 1 END) STORED,
+    PRIMARY KEY (`Id`),
+    INDEX `Provider` (`ProviderId` ASC) VISIBLE,
+    INDEX `RadiusMeters` (`RadiusMeters` ASC) VISIBLE,
+    SPATIAL INDEX `Location` (`Address`) VISIBLE,
+    CONSTRAINT `chk_ProviderLocation_RadiusUnit` CHECK (`RadiusUnit` IN ('m', 'km', 'mi')),
+    CONSTRAINT `fk_ProviderLocation_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
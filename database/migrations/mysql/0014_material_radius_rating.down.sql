@@ -0,0 +1,8 @@
+ALTER TABLE `ProviderMaterial` DROP INDEX `RadiusMeters`;
+
+ALTER TABLE `ProviderMaterial`
+    DROP CHECK `chk_ProviderMaterial_RadiusUnit`,
+    DROP COLUMN `RadiusMeters`,
+    DROP COLUMN `Radius`,
+    DROP COLUMN `RadiusUnit`,
+    DROP COLUMN `Rating`;
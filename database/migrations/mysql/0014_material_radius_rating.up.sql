@@ -0,0 +1,10 @@
+-- radius and rating for jobs with a single material, null when provider ones apply
+ALTER TABLE `ProviderMaterial`
+    ADD COLUMN `Radius` DOUBLE NULL,
+    ADD COLUMN `RadiusUnit` VARCHAR(2) NULL,
+    ADD COLUMN `RadiusMeters` DOUBLE AS (`Radius` * CASE `RadiusUnit` WHEN 'km' THEN 1000 WHEN 'mi' THEN 1609.344 ELSE 1 END) STORED,
+    ADD COLUMN `Rating` DOUBLE NULL,
+    ADD CONSTRAINT `chk_ProviderMaterial_RadiusUnit` CHECK (`RadiusUnit` IN ('m', 'km', 'mi'));
+
+-- largest radius bounds the area searched through spatial index
+ALTER TABLE `ProviderMaterial` ADD INDEX `RadiusMeters` (`RadiusMeters` ASC) VISIBLE;
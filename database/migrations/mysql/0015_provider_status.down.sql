@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `ProviderStatusChange`;
+
+ALTER TABLE `Provider`
+    DROP CHECK `chk_Provider_Status`,
+    DROP COLUMN `Status`,
+    DROP COLUMN `ResumeAt`;
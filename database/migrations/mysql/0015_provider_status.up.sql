@@ -0,0 +1,22 @@
+-- lifecycle status of providers, only active ones are matched. providers added before statuses existed stay active
+ALTER TABLE `Provider`
+    ADD COLUMN `Status` VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN `ResumeAt` DATETIME NULL,
+    ADD CONSTRAINT `chk_Provider_Status` CHECK (`Status` IN ('pending', 'active', 'paused', 'suspended'));
+
+-- audit of status changes, a paused provider resumed at its ResumeAt is recorded with its next change
+CREATE TABLE IF NOT EXISTS `ProviderStatusChange` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `ProviderId` INT NOT NULL,
+    `FromStatus` VARCHAR(16) NOT NULL,
+    `ToStatus` VARCHAR(16) NOT NULL,
+    `ResumeAt` DATETIME NULL,
+    `Actor` VARCHAR(16) NOT NULL,
+    `Reason` VARCHAR(255) NOT NULL DEFAULT '',
+    `ChangedAt` DATETIME NOT NULL,
+    PRIMARY KEY (`Id`),
+    INDEX `ProviderChangedAt` (`ProviderId` ASC, `ChangedAt` DESC) VISIBLE,
+    CONSTRAINT `fk_ProviderStatusChange_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
@@ -0,0 +1,14 @@
+-- date ranges providers can start jobs in, blackouts are ranges they can not start jobs in
+CREATE TABLE IF NOT EXISTS `ProviderAvailability` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `ProviderId` INT NOT NULL,
+    `Blackout` BOOLEAN NOT NULL DEFAULT FALSE,
+    `FromDate` DATE NOT NULL,
+    `ToDate` DATE NOT NULL,
+    PRIMARY KEY (`Id`),
+    INDEX `Provider` (`ProviderId` ASC) VISIBLE,
+    CONSTRAINT `chk_ProviderAvailability_Dates` CHECK (`ToDate` >= `FromDate`),
+    CONSTRAINT `fk_ProviderAvailability_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
@@ -0,0 +1,43 @@
+-- time zone and weekly working hours of providers, appointments are booked within them
+ALTER TABLE `Provider`
+    ADD COLUMN `TimeZone` VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS `ProviderWorkingHours` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `ProviderId` INT NOT NULL,
+    `Weekday` TINYINT NOT NULL,
+    `StartMinute` SMALLINT NOT NULL,
+    `EndMinute` SMALLINT NOT NULL,
+    PRIMARY KEY (`Id`),
+    INDEX `Provider` (`ProviderId` ASC) VISIBLE,
+    CONSTRAINT `chk_ProviderWorkingHours_Weekday` CHECK (`Weekday` BETWEEN 0 AND 6),
+    CONSTRAINT `chk_ProviderWorkingHours_Minutes` CHECK (`StartMinute` >= 0 AND `EndMinute` > `StartMinute` AND `EndMinute` <= 1440),
+    CONSTRAINT `fk_ProviderWorkingHours_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
+
+-- on-site measurement appointments of customers with providers, booked ones of a provider do not overlap
+CREATE TABLE IF NOT EXISTS `Appointment` (
+    `Id` INT NOT NULL AUTO_INCREMENT,
+    `ProviderId` INT NOT NULL,
+    `LeadId` INT NOT NULL,
+    `CustomerName` VARCHAR(45) NOT NULL DEFAULT '',
+    `StartsAt` DATETIME NOT NULL,
+    `EndsAt` DATETIME NOT NULL,
+    `Status` VARCHAR(16) NOT NULL,
+    `Sequence` INT NOT NULL DEFAULT 0,
+    `CreatedAt` DATETIME NOT NULL,
+    `UpdatedAt` DATETIME NOT NULL,
+    PRIMARY KEY (`Id`),
+    INDEX `ProviderStartsAt` (`ProviderId` ASC, `StartsAt` ASC) VISIBLE,
+    INDEX `Lead` (`LeadId` ASC) VISIBLE,
+    CONSTRAINT `chk_Appointment_Times` CHECK (`EndsAt` > `StartsAt`),
+    CONSTRAINT `chk_Appointment_Status` CHECK (`Status` IN ('booked', 'canceled')),
+    CONSTRAINT `fk_Appointment_Provider`
+        FOREIGN KEY (`ProviderId`) REFERENCES `Provider` (`Id`)
+            ON DELETE CASCADE,
+    CONSTRAINT `fk_Appointment_CustomerLead`
+        FOREIGN KEY (`LeadId`) REFERENCES `CustomerLead` (`Id`)
+            ON DELETE CASCADE)
+    ENGINE = InnoDB;
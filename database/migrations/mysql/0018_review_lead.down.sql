@@ -0,0 +1 @@
+ALTER TABLE `Review` DROP INDEX `LeadProvider`;
@@ -0,0 +1,8 @@
+-- a customer reviews a provider once per lead, later duplicates of older reviews keep their score without the lead
+UPDATE `Review` r JOIN (
+    SELECT `LeadId`, `ProviderId`, MIN(`Id`) AS `FirstId` FROM `Review`
+    WHERE `LeadId` IS NOT NULL GROUP BY `LeadId`, `ProviderId` HAVING COUNT(*) > 1) d
+    ON d.`LeadId` = r.`LeadId` AND d.`ProviderId` = r.`ProviderId` AND r.`Id` <> d.`FirstId`
+SET r.`LeadId` = NULL;
+
+ALTER TABLE `Review` ADD UNIQUE INDEX `LeadProvider` (`LeadId` ASC, `ProviderId` ASC) VISIBLE;
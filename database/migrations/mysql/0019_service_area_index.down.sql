@@ -0,0 +1,5 @@
+ALTER TABLE `Provider` DROP INDEX `Area`, MODIFY COLUMN `ServiceArea` GEOMETRY SRID 4326 NULL;
+
+UPDATE `Provider` SET `ServiceArea` = NULL WHERE ST_IsEmpty(`ServiceArea`);
+
+ALTER TABLE `Provider` MODIFY COLUMN `ServiceArea` MULTIPOLYGON SRID 4326 NULL;
@@ -0,0 +1,9 @@
+-- providers without a service area keep an empty geometry, a spatial index needs a not null column.
+-- mysql has no empty multipolygon, so the column holds any geometry and the empty one is a collection
+ALTER TABLE `Provider` MODIFY COLUMN `ServiceArea` GEOMETRY SRID 4326 NULL;
+
+UPDATE `Provider` SET `ServiceArea` = ST_GeomFromText('GEOMETRYCOLLECTION EMPTY', 4326) WHERE `ServiceArea` IS NULL;
+
+ALTER TABLE `Provider`
+    MODIFY COLUMN `ServiceArea` GEOMETRY NOT NULL SRID 4326 DEFAULT (ST_GeomFromText('GEOMETRYCOLLECTION EMPTY', 4326)),
+    ADD SPATIAL INDEX `Area` (`ServiceArea`) VISIBLE;
@@ -0,0 +1,16 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS Provider (
+    Id SERIAL NOT NULL,
+    Name VARCHAR(45) NOT NULL,
+    Address geography(Point, 4326) NOT NULL,
+    Radius DOUBLE PRECISION NOT NULL,
+    Rating DOUBLE PRECISION NOT NULL,
+    Wood BOOLEAN NOT NULL,
+    Carpet BOOLEAN NOT NULL,
+    Tile BOOLEAN NOT NULL,
+    PRIMARY KEY (Id));
+
+CREATE INDEX IF NOT EXISTS provider_location_idx ON Provider USING GIST (Address);
+
+CREATE INDEX IF NOT EXISTS provider_rating_idx ON Provider (Rating);
@@ -0,0 +1,18 @@
+ALTER TABLE Provider
+    ADD COLUMN Wood BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN Carpet BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN Tile BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE Provider p SET
+    Wood = EXISTS (SELECT 1 FROM ProviderMaterial pm JOIN Material m ON m.Id = pm.MaterialId WHERE pm.ProviderId = p.Id AND m.Name = 'wood'),
+    Carpet = EXISTS (SELECT 1 FROM ProviderMaterial pm JOIN Material m ON m.Id = pm.MaterialId WHERE pm.ProviderId = p.Id AND m.Name = 'carpet'),
+    Tile = EXISTS (SELECT 1 FROM ProviderMaterial pm JOIN Material m ON m.Id = pm.MaterialId WHERE pm.ProviderId = p.Id AND m.Name = 'tile');
+
+ALTER TABLE Provider
+    ALTER COLUMN Wood DROP DEFAULT,
+    ALTER COLUMN Carpet DROP DEFAULT,
+    ALTER COLUMN Tile DROP DEFAULT;
+
+DROP TABLE IF EXISTS ProviderMaterial;
+
+DROP TABLE IF EXISTS Material;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS Material (
+    Id SERIAL NOT NULL,
+    Name VARCHAR(45) NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT material_name_key UNIQUE (Name));
+
+INSERT INTO Material (Id, Name) VALUES (1, 'wood'), (2, 'carpet'), (3, 'tile') ON CONFLICT DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('material', 'id'), (SELECT MAX(Id) FROM Material));
+
+CREATE TABLE IF NOT EXISTS ProviderMaterial (
+    ProviderId INT NOT NULL,
+    MaterialId INT NOT NULL,
+    PRIMARY KEY (ProviderId, MaterialId),
+    CONSTRAINT fk_providermaterial_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_providermaterial_material
+        FOREIGN KEY (MaterialId) REFERENCES Material (Id));
+
+CREATE INDEX IF NOT EXISTS providermaterial_material_idx ON ProviderMaterial (MaterialId);
+
+INSERT INTO ProviderMaterial (ProviderId, MaterialId)
+SELECT p.Id, m.Id FROM Provider p JOIN Material m ON m.Name = 'wood' WHERE p.Wood
+ON CONFLICT DO NOTHING;
+
+INSERT INTO ProviderMaterial (ProviderId, MaterialId)
+SELECT p.Id, m.Id FROM Provider p JOIN Material m ON m.Name = 'carpet' WHERE p.Carpet
+ON CONFLICT DO NOTHING;
+
+INSERT INTO ProviderMaterial (ProviderId, MaterialId)
+SELECT p.Id, m.Id FROM Provider p JOIN Material m ON m.Name = 'tile' WHERE p.Tile
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE Provider DROP COLUMN Wood, DROP COLUMN Carpet, DROP COLUMN Tile;
@@ -0,0 +1,6 @@
+-- radiuses are compared in meters without unit
+UPDATE Provider SET Radius = RadiusMeters;
+
+ALTER TABLE Provider DROP COLUMN RadiusMeters;
+
+ALTER TABLE Provider DROP CONSTRAINT chk_provider_radiusunit, DROP COLUMN RadiusUnit;
@@ -0,0 +1,8 @@
+-- addresses are already geography(Point, 4326), only radius unit is added
+-- existing radiuses were meant to be kilometers
+ALTER TABLE Provider
+    ADD COLUMN RadiusUnit VARCHAR(2) NOT NULL DEFAULT 'km',
+    ADD CONSTRAINT chk_provider_radiusunit CHECK (RadiusUnit IN ('m', 'km', 'mi'));
+
+ALTER TABLE Provider
+    ADD COLUMN RadiusMeters DOUBLE PRECISION GENERATED ALWAYS AS (Radius * CASE RadiusUnit WHEN 'km' THEN 1000 WHEN 'mi' THEN 1609.344 ELSE 1 END) STORED;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS provider_radiusmeters_idx;
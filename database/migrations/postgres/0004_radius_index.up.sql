@@ -0,0 +1,2 @@
+-- largest radius bounds the area searched through spatial index
+CREATE INDEX IF NOT EXISTS provider_radiusmeters_idx ON Provider (RadiusMeters);
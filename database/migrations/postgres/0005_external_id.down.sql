@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS provider_externalid_idx;
+
+ALTER TABLE Provider DROP COLUMN ExternalId;
@@ -0,0 +1,15 @@
+-- reviews of customers, rating of a reviewed provider is average score of its reviews
+CREATE TABLE IF NOT EXISTS Review (
+    Id SERIAL NOT NULL,
+    ProviderId INT NOT NULL,
+    Score SMALLINT NOT NULL,
+    Text VARCHAR(2000) NOT NULL DEFAULT '',
+    LeadId INT NULL,
+    CreatedAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT chk_review_score CHECK (Score BETWEEN 1 AND 5),
+    CONSTRAINT fk_review_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS review_provider_createdat_idx ON Review (ProviderId, CreatedAt DESC);
@@ -0,0 +1,4 @@
+-- number of reviews rating is averaged from, used to rank providers
+ALTER TABLE Provider ADD COLUMN ReviewCount INT NOT NULL DEFAULT 0;
+
+UPDATE Provider p SET ReviewCount = (SELECT COUNT(*) FROM Review r WHERE r.ProviderId = p.Id);
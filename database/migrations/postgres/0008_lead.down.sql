@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS LeadProvider;
+
+DROP TABLE IF EXISTS CustomerLead;
@@ -0,0 +1,25 @@
+-- customer requests for providers, named the same as in mysql where lead is a reserved word
+CREATE TABLE IF NOT EXISTS CustomerLead (
+    Id SERIAL NOT NULL,
+    MaterialId INT NOT NULL,
+    Address geography(Point, 4326) NOT NULL,
+    Area DOUBLE PRECISION NOT NULL,
+    PhoneNumber VARCHAR(32) NOT NULL,
+    CreatedAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT fk_customerlead_material
+        FOREIGN KEY (MaterialId) REFERENCES Material (Id));
+
+CREATE INDEX IF NOT EXISTS customerlead_createdat_idx ON CustomerLead (CreatedAt);
+
+-- providers shown for a lead, they are kept as shown even after a provider is deleted
+CREATE TABLE IF NOT EXISTS LeadProvider (
+    LeadId INT NOT NULL,
+    Ordinal INT NOT NULL,
+    ProviderId INT NOT NULL,
+    PRIMARY KEY (LeadId, Ordinal),
+    CONSTRAINT fk_leadprovider_customerlead
+        FOREIGN KEY (LeadId) REFERENCES CustomerLead (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS leadprovider_provider_idx ON LeadProvider (ProviderId);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS LeadOffer;
+
+ALTER TABLE CustomerLead DROP COLUMN Status;
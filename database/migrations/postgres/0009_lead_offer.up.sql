@@ -0,0 +1,19 @@
+-- leads saved before offers existed were never offered to anyone
+ALTER TABLE CustomerLead ADD COLUMN Status VARCHAR(16) NOT NULL DEFAULT 'offered';
+
+UPDATE CustomerLead SET Status = 'expired';
+
+-- offers of a lead to top matched providers, pending offers past ExpiresAt are expired
+CREATE TABLE IF NOT EXISTS LeadOffer (
+    LeadId INT NOT NULL,
+    Ordinal INT NOT NULL,
+    ProviderId INT NOT NULL,
+    Status VARCHAR(16) NOT NULL,
+    ExpiresAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (LeadId, Ordinal),
+    CONSTRAINT leadoffer_lead_provider_key UNIQUE (LeadId, ProviderId),
+    CONSTRAINT fk_leadoffer_customerlead
+        FOREIGN KEY (LeadId) REFERENCES CustomerLead (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS leadoffer_provider_idx ON LeadOffer (ProviderId);
@@ -0,0 +1,3 @@
+ALTER TABLE ProviderMaterial DROP COLUMN MinArea, DROP COLUMN MaxArea;
+
+ALTER TABLE Provider DROP COLUMN MinArea, DROP COLUMN MaxArea;
@@ -0,0 +1,9 @@
+-- limits of area of jobs a provider takes, zero MaxArea means no upper limit
+ALTER TABLE Provider
+    ADD COLUMN MinArea DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN MaxArea DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- limits for jobs with a single material, both are null when provider limits apply
+ALTER TABLE ProviderMaterial
+    ADD COLUMN MinArea DOUBLE PRECISION NULL,
+    ADD COLUMN MaxArea DOUBLE PRECISION NULL;
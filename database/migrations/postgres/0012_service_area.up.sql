@@ -0,0 +1,2 @@
+-- optional area a provider works in instead of its operating radius
+ALTER TABLE Provider ADD COLUMN ServiceArea geography(MultiPolygon, 4326) NULL;
@@ -0,0 +1,20 @@
+-- branches of providers, a provider is matched when its address or any of its branches covers the location
+CREATE TABLE IF NOT EXISTS ProviderLocation (
+    Id SERIAL NOT NULL,
+    ProviderId INT NOT NULL,
+    Name VARCHAR(45) NOT NULL,
+    Address geography(Point, 4326) NOT NULL,
+    Radius DOUBLE PRECISION NOT NULL,
+    RadiusUnit VARCHAR(2) NOT NULL DEFAULT 'km',
+    RadiusMeters DOUBLE PRECISION GENERATED ALWAYS AS (Radius * CASE RadiusUnit WHEN 'km' THEN 1000 WHEN 'mi' THEN 1609.344 ELSE 1 END) STORED,
+    PRIMARY KEY (Id),
+    CONSTRAINT chk_providerlocation_radiusunit CHECK (RadiusUnit IN ('m', 'km', 'mi')),
+    CONSTRAINT fk_providerlocation_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS providerlocation_provider_idx ON ProviderLocation (ProviderId);
+
+CREATE INDEX IF NOT EXISTS providerlocation_radiusmeters_idx ON ProviderLocation (RadiusMeters);
+
+CREATE INDEX IF NOT EXISTS providerlocation_location_idx ON ProviderLocation USING GIST (Address);
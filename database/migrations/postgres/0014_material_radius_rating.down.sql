@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS providermaterial_radiusmeters_idx;
+
+ALTER TABLE ProviderMaterial
+    DROP COLUMN RadiusMeters,
+    DROP CONSTRAINT chk_providermaterial_radiusunit,
+    DROP COLUMN Radius,
+    DROP COLUMN RadiusUnit,
+    DROP COLUMN Rating;
@@ -0,0 +1,12 @@
+-- radius and rating for jobs with a single material, null when provider ones apply
+ALTER TABLE ProviderMaterial
+    ADD COLUMN Radius DOUBLE PRECISION NULL,
+    ADD COLUMN RadiusUnit VARCHAR(2) NULL,
+    ADD COLUMN Rating DOUBLE PRECISION NULL,
+    ADD CONSTRAINT chk_providermaterial_radiusunit CHECK (RadiusUnit IN ('m', 'km', 'mi'));
+
+ALTER TABLE ProviderMaterial
+    ADD COLUMN RadiusMeters DOUBLE PRECISION GENERATED ALWAYS AS (Radius * CASE RadiusUnit WHEN 'km' THEN 1000 WHEN 'mi' THEN 1609.344 ELSE 1 END) STORED;
+
+-- largest radius bounds the area searched through spatial index
+CREATE INDEX IF NOT EXISTS providermaterial_radiusmeters_idx ON ProviderMaterial (RadiusMeters);
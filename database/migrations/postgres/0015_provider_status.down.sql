@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS ProviderStatusChange;
+
+ALTER TABLE Provider
+    DROP CONSTRAINT chk_provider_status,
+    DROP COLUMN Status,
+    DROP COLUMN ResumeAt;
@@ -0,0 +1,22 @@
+-- lifecycle status of providers, only active ones are matched. providers added before statuses existed stay active
+ALTER TABLE Provider
+    ADD COLUMN Status VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN ResumeAt TIMESTAMP WITH TIME ZONE NULL,
+    ADD CONSTRAINT chk_provider_status CHECK (Status IN ('pending', 'active', 'paused', 'suspended'));
+
+-- audit of status changes, a paused provider resumed at its ResumeAt is recorded with its next change
+CREATE TABLE IF NOT EXISTS ProviderStatusChange (
+    Id SERIAL NOT NULL,
+    ProviderId INT NOT NULL,
+    FromStatus VARCHAR(16) NOT NULL,
+    ToStatus VARCHAR(16) NOT NULL,
+    ResumeAt TIMESTAMP WITH TIME ZONE NULL,
+    Actor VARCHAR(16) NOT NULL,
+    Reason VARCHAR(255) NOT NULL DEFAULT '',
+    ChangedAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT fk_providerstatuschange_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS providerstatuschange_provider_changedat_idx ON ProviderStatusChange (ProviderId, ChangedAt DESC);
@@ -0,0 +1,14 @@
+-- date ranges providers can start jobs in, blackouts are ranges they can not start jobs in
+CREATE TABLE IF NOT EXISTS ProviderAvailability (
+    Id SERIAL NOT NULL,
+    ProviderId INT NOT NULL,
+    Blackout BOOLEAN NOT NULL DEFAULT FALSE,
+    FromDate DATE NOT NULL,
+    ToDate DATE NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT chk_provideravailability_dates CHECK (ToDate >= FromDate),
+    CONSTRAINT fk_provideravailability_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS provideravailability_provider_idx ON ProviderAvailability (ProviderId);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS Appointment;
+DROP TABLE IF EXISTS ProviderWorkingHours;
+
+ALTER TABLE Provider
+    DROP COLUMN TimeZone;
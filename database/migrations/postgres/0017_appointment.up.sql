@@ -0,0 +1,43 @@
+-- time zone and weekly working hours of providers, appointments are booked within them
+ALTER TABLE Provider
+    ADD COLUMN TimeZone VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS ProviderWorkingHours (
+    Id SERIAL NOT NULL,
+    ProviderId INT NOT NULL,
+    Weekday SMALLINT NOT NULL,
+    StartMinute SMALLINT NOT NULL,
+    EndMinute SMALLINT NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT chk_providerworkinghours_weekday CHECK (Weekday BETWEEN 0 AND 6),
+    CONSTRAINT chk_providerworkinghours_minutes CHECK (StartMinute >= 0 AND EndMinute > StartMinute AND EndMinute <= 1440),
+    CONSTRAINT fk_providerworkinghours_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS providerworkinghours_provider_idx ON ProviderWorkingHours (ProviderId);
+
+-- on-site measurement appointments of customers with providers, booked ones of a provider do not overlap
+CREATE TABLE IF NOT EXISTS Appointment (
+    Id SERIAL NOT NULL,
+    ProviderId INT NOT NULL,
+    LeadId INT NOT NULL,
+    CustomerName VARCHAR(45) NOT NULL DEFAULT '',
+    StartsAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    EndsAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    Status VARCHAR(16) NOT NULL,
+    Sequence INT NOT NULL DEFAULT 0,
+    CreatedAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    UpdatedAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (Id),
+    CONSTRAINT chk_appointment_times CHECK (EndsAt > StartsAt),
+    CONSTRAINT chk_appointment_status CHECK (Status IN ('booked', 'canceled')),
+    CONSTRAINT fk_appointment_provider
+        FOREIGN KEY (ProviderId) REFERENCES Provider (Id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_appointment_customerlead
+        FOREIGN KEY (LeadId) REFERENCES CustomerLead (Id)
+            ON DELETE CASCADE);
+
+CREATE INDEX IF NOT EXISTS appointment_provider_startsat_idx ON Appointment (ProviderId, StartsAt);
+CREATE INDEX IF NOT EXISTS appointment_lead_idx ON Appointment (LeadId);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS review_lead_provider_idx;
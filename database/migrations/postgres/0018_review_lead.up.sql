@@ -0,0 +1,6 @@
+-- a customer reviews a provider once per lead, later duplicates of older reviews keep their score without the lead
+UPDATE Review r SET LeadId = NULL
+FROM Review f
+WHERE f.LeadId = r.LeadId AND f.ProviderId = r.ProviderId AND f.Id < r.Id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS review_lead_provider_idx ON Review (LeadId, ProviderId);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS provider_servicearea_idx;
+
+ALTER TABLE Provider ALTER COLUMN ServiceArea DROP NOT NULL;
+ALTER TABLE Provider ALTER COLUMN ServiceArea DROP DEFAULT;
+
+UPDATE Provider SET ServiceArea = NULL WHERE ST_IsEmpty(ServiceArea::geometry);
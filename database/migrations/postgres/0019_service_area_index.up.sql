@@ -0,0 +1,7 @@
+-- providers without a service area keep an empty geometry, so the column is not null like in mysql and is indexed
+UPDATE Provider SET ServiceArea = 'SRID=4326;MULTIPOLYGON EMPTY'::geography WHERE ServiceArea IS NULL;
+
+ALTER TABLE Provider ALTER COLUMN ServiceArea SET DEFAULT 'SRID=4326;MULTIPOLYGON EMPTY'::geography;
+ALTER TABLE Provider ALTER COLUMN ServiceArea SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS provider_servicearea_idx ON Provider USING GIST (ServiceArea);
@@ -1,8 +1,15 @@
 package database
 
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
 // FloorMaterial material for the floor
 type FloorMaterial string
 
+// materials in the default catalogue, more can be added to the Material table without code changes
 const (
 	// FloorWood wood material
 	FloorWood FloorMaterial = "wood"
@@ -12,6 +19,79 @@ const (
 	FloorTile FloorMaterial = "tile"
 )
 
+// Material is an entry of material catalogue
+type Material struct {
+	ID   ID
+	Name FloorMaterial
+}
+
+// DistanceUnit unit of a distance value
+type DistanceUnit string
+
+const (
+	// Metre distance in meters
+	Metre DistanceUnit = "m"
+	// Kilometre distance in kilometers
+	Kilometre DistanceUnit = "km"
+	// Mile distance in international miles
+	Mile DistanceUnit = "mi"
+)
+
+var metersPerUnit = map[DistanceUnit]float64{
+	Metre:     1,
+	Kilometre: 1000,
+	Mile:      1609.344,
+}
+
+// Valid reports whether unit is a supported distance unit
+func (u DistanceUnit) Valid() bool {
+	_, ok := metersPerUnit[u]
+	return ok
+}
+
+// ToMeters converts a value in this unit to meters
+func (u DistanceUnit) ToMeters(value float64) float64 {
+	return value * metersPerUnit[u]
+}
+
+// FromMeters converts a value in meters to this unit
+func (u DistanceUnit) FromMeters(value float64) float64 {
+	return value / metersPerUnit[u]
+}
+
+// AreaRange limits area of a job in square metres, zero Max means there is no upper limit
+type AreaRange struct {
+	Min float64
+	Max float64
+}
+
+// Valid reports whether limits are non-negative and max is not below min
+func (r AreaRange) Valid() bool {
+	return r.Min >= 0 && r.Max >= 0 && (r.Max == 0 || r.Max >= r.Min)
+}
+
+// Covers reports whether area is within limits
+func (r AreaRange) Covers(area float64) bool {
+	return area >= r.Min && (r.Max == 0 || area <= r.Max)
+}
+
+// MaterialRadius is operating radius of a provider for jobs with a material
+type MaterialRadius struct {
+	Radius     float64
+	RadiusUnit DistanceUnit
+}
+
+// Criteria selects providers for a customer request
+type Criteria struct {
+	Material FloorMaterial
+	Location Address
+	// Area is area of the job in square metres, providers whose job area does not cover it are left out.
+	// zero area matches regardless of job area
+	Area float64
+	// Now is time status of providers is checked at, paused providers due to resume by then are matched
+	Now time.Time
+}
+
 // Address is a location on map
 type Address struct {
 	Lat  float64
@@ -23,12 +103,278 @@ type ID int64
 
 // Provider holds information aboud a provider in db
 type Provider struct {
-	ID      ID
-	Name    string
-	Address Address
-	Radius  float64
-	Rating  float64
-	Wood    bool
-	Carpet  bool
-	Tile    bool
+	ID ID
+	// ExternalID is optional unique id of provider in an external system
+	ExternalID string
+	Name       string
+	Address    Address
+	Radius     float64
+	// RadiusUnit is unit of Radius
+	RadiusUnit DistanceUnit
+	Rating     float64
+	// ReviewCount is number of reviews rating is averaged from, maintained by storage
+	ReviewCount int
+	Materials   []FloorMaterial
+	// Rates are prices per square metre of materials provider works with, materials without a rate are not priced
+	Rates map[FloorMaterial]float64
+	// MinimumCharge is the least price of a job
+	MinimumCharge float64
+	// TravelRate is surcharge per kilometre of distance to customer
+	TravelRate float64
+	// JobArea limits area of jobs provider takes
+	JobArea AreaRange
+	// MaterialJobAreas replace JobArea for jobs with some of provider materials
+	MaterialJobAreas map[FloorMaterial]AreaRange
+	// MaterialRadii replace Radius and RadiusUnit for jobs with some of provider materials
+	MaterialRadii map[FloorMaterial]MaterialRadius
+	// MaterialRatings replace Rating for jobs with some of provider materials
+	MaterialRatings map[FloorMaterial]float64
+	// ServiceArea replaces circle of Radius around Address in matching when not empty
+	ServiceArea ServiceArea
+	// Branches are depots of provider, it is matched when its address or any of branches covers the location
+	Branches []Branch
+	// Availability are date ranges provider can start jobs in, it can start on any day when there are none
+	Availability []DateRange
+	// Blackouts are date ranges provider can not start jobs in, they win over Availability
+	Blackouts []DateRange
+	// TimeZone is IANA name of time zone WorkingHours are in, empty is UTC
+	TimeZone string
+	// WorkingHours are weekly hours provider takes appointments in, it takes none when there are none
+	WorkingHours []WorkingHours
+	// Status is only changed by ChangeProviderStatus and imports, a new provider without status is active
+	// unless it is imported, see ImportStatus
+	Status ProviderStatus
+	// ResumeAt is when a paused provider becomes active again, zero when it is not paused or has no resume time
+	ResumeAt time.Time
+	// Distance is distance to requested location in meters, only set for providers matched by GetProviders.
+	// radius and rating of matched providers are ones of the requested material, see ForMaterial
+	Distance float64
+	// ClosestBranch is the closest of branches covering requested location, nil when address is closer.
+	// only set for providers matched by GetProviders, Distance is measured to it
+	ClosestBranch *Branch
+}
+
+// Validate checks fields of provider are in range, materials are checked against catalogue by storage
+func (p Provider) Validate() error {
+	switch {
+	case p.Name == "" || len(p.Name) > 45:
+		return fmt.Errorf("%w: name should have 1 to 45 characters", ErrInvalid)
+	case len(p.ExternalID) > 64:
+		return fmt.Errorf("%w: external id should have at most 64 characters", ErrInvalid)
+	case p.Status != "" && !p.Status.Valid():
+		return fmt.Errorf("%w: unknown status %q", ErrInvalid, p.Status)
+	case !p.ResumeAt.IsZero() && p.Status != StatusPaused:
+		return fmt.Errorf("%w: only paused providers have a resume time", ErrInvalid)
+	case p.Address.Lat < -90 || p.Address.Lat > 90 || p.Address.Long < -180 || p.Address.Long > 180:
+		return fmt.Errorf("%w: address is out of range", ErrInvalid)
+	case !(p.Radius > 0):
+		return fmt.Errorf("%w: radius should be positive", ErrInvalid)
+	case !p.RadiusUnit.Valid():
+		return fmt.Errorf("%w: unknown radius unit %q", ErrInvalid, p.RadiusUnit)
+	case p.Rating < 0 || p.Rating > 5:
+		return fmt.Errorf("%w: rating should be between 0 and 5", ErrInvalid)
+	case p.MinimumCharge < 0 || p.TravelRate < 0:
+		return fmt.Errorf("%w: minimum charge and travel rate should not be negative", ErrInvalid)
+	case !p.JobArea.Valid():
+		return fmt.Errorf("%w: job area should have non-negative limits with max not below min", ErrInvalid)
+	}
+	if err := p.validateRates(); err != nil {
+		return err
+	}
+	if err := p.ServiceArea.Validate(); err != nil {
+		return err
+	}
+	for _, branch := range p.Branches {
+		if err := branch.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := p.validateMaterialRadii(); err != nil {
+		return err
+	}
+	if err := p.validateMaterialRatings(); err != nil {
+		return err
+	}
+	if err := p.validateAvailability(); err != nil {
+		return err
+	}
+	if err := p.validateSchedule(); err != nil {
+		return err
+	}
+	return p.validateJobAreas()
+}
+
+// validateMaterialRadii checks radii of materials are positive and only given for materials of provider
+func (p Provider) validateMaterialRadii() error {
+	for material, radius := range p.MaterialRadii {
+		if !(radius.Radius > 0) || !radius.RadiusUnit.Valid() {
+			return fmt.Errorf("%w: radius of %s should be positive with a known unit", ErrInvalid, material)
+		}
+		if !p.HasMaterial(material) {
+			return fmt.Errorf("%w: radius of %s is given without experience in it", ErrInvalid, material)
+		}
+	}
+	return nil
+}
+
+// validateMaterialRatings checks ratings of materials are in range and only given for materials of provider
+func (p Provider) validateMaterialRatings() error {
+	for material, rating := range p.MaterialRatings {
+		if rating < 0 || rating > 5 {
+			return fmt.Errorf("%w: rating of %s should be between 0 and 5", ErrInvalid, material)
+		}
+		if !p.HasMaterial(material) {
+			return fmt.Errorf("%w: rating of %s is given without experience in it", ErrInvalid, material)
+		}
+	}
+	return nil
+}
+
+// ForMaterial returns provider with radius and rating replaced by ones of material when it has them
+func (p Provider) ForMaterial(material FloorMaterial) Provider {
+	if radius, ok := p.MaterialRadii[material]; ok {
+		p.Radius, p.RadiusUnit = radius.Radius, radius.RadiusUnit
+	}
+	if rating, ok := p.MaterialRatings[material]; ok {
+		p.Rating = rating
+	}
+	return p
+}
+
+// ReviewsFor returns number of reviews backing rating of provider for jobs with material, a material rating is set
+// rather than earned through reviews so it is backed by none and ranks like a rating of a provider without reviews
+func (p Provider) ReviewsFor(material FloorMaterial) int {
+	if _, ok := p.MaterialRatings[material]; ok {
+		return 0
+	}
+	return p.ReviewCount
+}
+
+// validateJobAreas checks job areas of materials are valid and only given for materials of provider
+func (p Provider) validateJobAreas() error {
+	for material, area := range p.MaterialJobAreas {
+		if !area.Valid() {
+			return fmt.Errorf("%w: job area of %s should have non-negative limits with max not below min", ErrInvalid, material)
+		}
+		if !p.HasMaterial(material) {
+			return fmt.Errorf("%w: job area of %s is given without experience in it", ErrInvalid, material)
+		}
+	}
+	return nil
+}
+
+// JobAreaFor returns limits of area of jobs with material provider takes
+func (p Provider) JobAreaFor(material FloorMaterial) AreaRange {
+	if area, ok := p.MaterialJobAreas[material]; ok {
+		return area
+	}
+	return p.JobArea
+}
+
+// validateRates checks rates are positive and only given for materials of provider
+func (p Provider) validateRates() error {
+	for material, rate := range p.Rates {
+		if !(rate > 0) {
+			return fmt.Errorf("%w: rate of %s should be positive", ErrInvalid, material)
+		}
+		if !p.HasMaterial(material) {
+			return fmt.Errorf("%w: rate of %s is given without experience in it", ErrInvalid, material)
+		}
+	}
+	return nil
+}
+
+// HasMaterial reports whether provider works with material
+func (p Provider) HasMaterial(material FloorMaterial) bool {
+	for _, m := range p.Materials {
+		if m == material {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportOptions controls how ImportProviders saves providers
+type ImportOptions struct {
+	// Upsert updates providers having the same external id instead of reporting a duplicate
+	Upsert bool
+	// DryRun checks providers against storage without saving them
+	DryRun bool
+	// Now is time status changes made by import are recorded at, set by caller
+	Now time.Time
+}
+
+// ImportResult is outcome of importing a single provider
+type ImportResult struct {
+	ID ID
+	// Updated is set when an existing provider was updated
+	Updated bool
+	Err     error
+}
+
+// Review is a customer review of a provider
+type Review struct {
+	ID         ID
+	ProviderID ID
+	// Score is number of stars from 1 to 5
+	Score int
+	Text  string
+	// LeadID is id of the lead review is written for, zero when review is not linked to a lead
+	LeadID    ID
+	CreatedAt time.Time
+}
+
+// Validate checks fields of review are in range
+func (r Review) Validate() error {
+	switch {
+	case r.Score < 1 || r.Score > 5:
+		return fmt.Errorf("%w: score should be between 1 and 5", ErrInvalid)
+	case len(r.Text) > 2000:
+		return fmt.Errorf("%w: text should have at most 2000 characters", ErrInvalid)
+	case r.LeadID < 0:
+		return fmt.Errorf("%w: invalid lead id", ErrInvalid)
+	}
+	return nil
+}
+
+// AverageScore is rating of a provider with count reviews scoring sum stars in total, rounded to two decimals
+func AverageScore(sum, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(float64(sum)/float64(count)*100) / 100
+}
+
+// Page selects a part of a list
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Lead is a customer request for providers, kept as a record of demand
+type Lead struct {
+	ID          ID
+	Material    FloorMaterial
+	Address     Address
+	Area        float64
+	PhoneNumber string
+	CreatedAt   time.Time
+	// ProviderIDs are ids of matched providers in order they were shown to customer
+	ProviderIDs []ID
+	Status      LeadStatus
+	// Offers are offers of the lead to top matched providers, in order of ProviderIDs
+	Offers []LeadOffer
+}
+
+// Validate checks fields of lead are in range, material is checked against catalogue by storage
+func (l Lead) Validate() error {
+	switch {
+	case l.Address.Lat < -90 || l.Address.Lat > 90 || l.Address.Long < -180 || l.Address.Long > 180:
+		return fmt.Errorf("%w: address is out of range", ErrInvalid)
+	case !(l.Area > 0):
+		return fmt.Errorf("%w: area should be positive", ErrInvalid)
+	case l.PhoneNumber == "" || len(l.PhoneNumber) > 32:
+		return fmt.Errorf("%w: phone number should have 1 to 32 characters", ErrInvalid)
+	}
+	return nil
 }
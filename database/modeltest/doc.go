@@ -0,0 +1,2 @@
+// Package modeltest tests ranking, availability and scheduling of providers, which need no database server
+package modeltest
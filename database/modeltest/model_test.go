@@ -0,0 +1,146 @@
+package modeltest
+
+import (
+	"ah/database"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestRanking(t *testing.T) {
+	RegisterTestingT(t)
+	newcomer := database.Provider{ID: 1, Rating: 5, ReviewCount: 1}
+	veteran := database.Provider{ID: 2, Rating: 4.8, ReviewCount: 400}
+	unreviewed := database.Provider{ID: 3, Rating: 4.9}
+	for _, ranking := range []database.Ranking{
+		{Method: database.RankBayesian, PriorMean: 4, PriorWeight: 10},
+		{Method: database.RankWilson},
+	} {
+		Expect(ranking.Validate()).To(BeNil())
+		providers := []database.Provider{unreviewed, newcomer, veteran}
+		ranking.Sort(providers, database.FloorWood)
+		Expect(providers).To(Equal([]database.Provider{veteran, newcomer, unreviewed}), string(ranking.Method))
+		Expect(ranking.Score(veteran, database.FloorWood)).To(BeNumerically("<", veteran.Rating))
+	}
+
+	bayesian := database.Ranking{Method: database.RankBayesian, PriorMean: 4, PriorWeight: 10}
+	Expect(bayesian.Score(unreviewed, database.FloorWood)).To(Equal(4.0))
+	Expect(bayesian.Score(database.Provider{Rating: 3, ReviewCount: 10}, database.FloorWood)).To(Equal(3.5))
+	Expect(database.Ranking{Method: database.RankWilson}.Score(database.Provider{Rating: 5, ReviewCount: 1000000}, database.FloorWood)).To(BeNumerically("~", 5, 0.001))
+
+	// raw rating keeps order of equally rated providers
+	providers := []database.Provider{veteran, newcomer, unreviewed, {ID: 4, Rating: 5}}
+	database.Ranking{Method: database.RankByRating}.Sort(providers, database.FloorWood)
+	Expect(providers).To(Equal([]database.Provider{newcomer, {ID: 4, Rating: 5}, unreviewed, veteran}))
+
+	// reviews are of provider work as a whole, so a material rating is ranked as if nobody reviewed it
+	overridden := veteran
+	overridden.MaterialRatings = map[database.FloorMaterial]float64{database.FloorTile: 5}
+	overridden = overridden.ForMaterial(database.FloorTile)
+	Expect(overridden.ReviewsFor(database.FloorTile)).To(Equal(0))
+	Expect(overridden.ReviewsFor(database.FloorWood)).To(Equal(400))
+	Expect(bayesian.Score(overridden, database.FloorTile)).To(Equal(bayesian.Score(database.Provider{Rating: 5}, database.FloorTile)))
+	Expect(database.Ranking{Method: database.RankWilson}.Score(overridden, database.FloorTile)).To(Equal(0.0))
+	Expect(database.Ranking{Method: database.RankByRating}.Score(overridden, database.FloorTile)).To(Equal(5.0))
+	providers = []database.Provider{overridden, veteran}
+	bayesian.Sort(providers, database.FloorTile)
+	Expect(providers).To(Equal([]database.Provider{veteran, overridden}))
+
+	Expect(database.Ranking{Method: "stars"}.Validate()).NotTo(BeNil())
+	Expect(database.Ranking{Method: database.RankBayesian, PriorMean: 4}.Validate()).NotTo(BeNil())
+}
+
+func TestNextAvailableDay(t *testing.T) {
+	RegisterTestingT(t)
+	day := func(d int) time.Time {
+		return time.Date(2024, 7, d, 0, 0, 0, 0, time.UTC)
+	}
+	next := func(p database.Provider, from time.Time) time.Time {
+		res, ok := p.NextAvailableDay(from)
+		Expect(ok).To(BeTrue())
+		return res
+	}
+	// without windows any day out of blackouts is available, adjacent blackouts are skipped together
+	open := database.Provider{Blackouts: []database.DateRange{{From: day(11), To: day(15)}, {From: day(5), To: day(10)}}}
+	Expect(next(open, day(1).Add(13*time.Hour))).To(Equal(day(1)))
+	Expect(next(open, day(7))).To(Equal(day(16)))
+
+	windows := database.Provider{
+		Availability: []database.DateRange{{From: day(20), To: day(25)}, {From: day(3), To: day(6)}},
+		Blackouts:    []database.DateRange{{From: day(1), To: day(4)}, {From: day(6), To: day(21)}},
+	}
+	Expect(next(windows, day(1))).To(Equal(day(5)))
+	Expect(next(windows, day(6))).To(Equal(day(22)))
+	Expect(next(windows, day(25))).To(Equal(day(25)))
+	_, ok := windows.NextAvailableDay(day(26))
+	Expect(ok).To(BeFalse())
+	_, ok = database.Provider{Availability: []database.DateRange{{From: day(1), To: day(2)}}, Blackouts: []database.DateRange{{From: day(1), To: day(2)}}}.NextAvailableDay(day(1))
+	Expect(ok).To(BeFalse())
+}
+
+func TestSlots(t *testing.T) {
+	RegisterTestingT(t)
+	scheduling := database.Scheduling{SlotDuration: time.Hour, HorizonDays: 30}
+	// Maputo is two hours ahead of UTC, 2024-07-01 is a monday
+	p := database.Provider{Status: database.StatusActive, TimeZone: "Africa/Maputo",
+		WorkingHours: []database.WorkingHours{{Weekday: time.Monday, Start: 8 * 60, End: 10*60 + 30}, {Weekday: time.Tuesday, Start: 13 * 60, End: 14 * 60}},
+		Blackouts:    []database.DateRange{{From: time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC)}},
+	}
+	loc, err := p.Location()
+	Expect(err).To(BeNil())
+	utc := func(d, hour int) time.Time {
+		return time.Date(2024, 7, d, hour, 0, 0, 0, time.UTC)
+	}
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, loc)
+	now := utc(1, 0)
+	slots, err := scheduling.Slots(p, nil, from, 8, now)
+	Expect(err).To(BeNil())
+	// slots which do not fit before end of working hours and blackout days are left out
+	Expect(slots).To(Equal([]time.Time{utc(1, 6), utc(1, 7), utc(2, 11)}))
+
+	// booked appointments take slots they overlap, canceled ones do not
+	booked := []database.Appointment{
+		{ID: 1, StartsAt: utc(1, 7).Add(30 * time.Minute), EndsAt: utc(1, 8).Add(30 * time.Minute), Status: database.AppointmentBooked},
+		{ID: 2, StartsAt: utc(2, 11), EndsAt: utc(2, 12), Status: database.AppointmentCanceled},
+	}
+	slots, err = scheduling.Slots(p, booked, from, 8, now)
+	Expect(err).To(BeNil())
+	Expect(slots).To(Equal([]time.Time{utc(1, 6), utc(2, 11)}))
+
+	// past slots and slots beyond horizon are left out
+	slots, err = scheduling.Slots(p, nil, from, 8, utc(1, 6))
+	Expect(err).To(BeNil())
+	Expect(slots).To(Equal([]time.Time{utc(1, 7), utc(2, 11)}))
+	slots, err = database.Scheduling{SlotDuration: time.Hour, HorizonDays: 1}.Slots(p, nil, from, 8, now)
+	Expect(err).To(BeNil())
+	Expect(slots).To(Equal([]time.Time{utc(1, 6), utc(1, 7)}))
+
+	// providers which are not active have no slots
+	p.Status = database.StatusPaused
+	slots, err = scheduling.Slots(p, nil, from, 8, now)
+	Expect(err).To(BeNil())
+	Expect(slots).To(BeEmpty())
+}
+
+func TestScheduleCheck(t *testing.T) {
+	RegisterTestingT(t)
+	scheduling := database.Scheduling{SlotDuration: time.Hour, HorizonDays: 30}
+	p := database.Provider{Status: database.StatusActive, WorkingHours: []database.WorkingHours{{Weekday: time.Monday, Start: 8 * 60, End: 12 * 60}}}
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	at := func(start time.Time) database.Appointment {
+		return database.Appointment{StartsAt: start, EndsAt: start.Add(scheduling.SlotDuration)}
+	}
+	monday := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	Expect(scheduling.Check(p, at(monday), now)).To(BeNil())
+	// appointments may start at any time which fits in working hours
+	Expect(scheduling.Check(p, at(monday.Add(150*time.Minute)), now)).To(BeNil())
+	Expect(scheduling.Check(p, at(monday.Add(181*time.Minute)), now)).To(MatchError(database.ErrInvalid))
+	Expect(scheduling.Check(p, at(monday.Add(-time.Minute)), now)).To(MatchError(database.ErrInvalid))
+	Expect(scheduling.Check(p, at(monday.AddDate(0, 0, 1)), now)).To(MatchError(database.ErrInvalid))
+	Expect(scheduling.Check(p, database.Appointment{StartsAt: monday, EndsAt: monday.Add(2 * time.Hour)}, now)).To(MatchError(database.ErrInvalid))
+	// past appointments and ones beyond horizon are invalid
+	Expect(scheduling.Check(p, at(monday), monday)).To(MatchError(database.ErrInvalid))
+	Expect(scheduling.Check(p, at(monday.AddDate(0, 0, 35)), now)).To(MatchError(database.ErrInvalid))
+	p.Status = database.StatusSuspended
+	Expect(scheduling.Check(p, at(monday), now)).To(MatchError(database.ErrConflict))
+}
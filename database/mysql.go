@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"strconv"
+	"strings"
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) driverName() string {
+	return "mysql"
+}
+
+func (mysqlDialect) migrationsDir() string {
+	return "migrations/mysql"
+}
+
+// ddl statements commit implicitly in mysql
+func (mysqlDialect) transactionalDDL() bool {
+	return false
+}
+
+func (mysqlDialect) rebind(query string) string {
+	return query
+}
+
+// addresses are stored as SRID 4326 points
+func (mysqlDialect) addressColumns(column string) string {
+	return fmt.Sprintf("ST_Latitude(%s) AS Latitude, ST_Longitude(%s) AS Longitude", column, column)
+}
+
+func (mysqlDialect) pointExpr(location Address) (string, []interface{}) {
+	return "ST_GeomFromText(?, 4326, 'axis-order=long-lat')", []interface{}{pointWKT(location)}
+}
+
+func (mysqlDialect) distanceExpr(column string, location Address) (string, []interface{}) {
+	return fmt.Sprintf("ST_Distance_Sphere(%s, ST_GeomFromText(?, 4326, 'axis-order=long-lat'))", column), []interface{}{pointWKT(location)}
+}
+
+// spatial index is only used by mbr functions, so candidates are searched in bounding boxes of the circle
+func (mysqlDialect) withinExpr(column string, location Address, distance float64) (string, []interface{}) {
+	boxes := boundingBoxes(location, distance)
+	if len(boxes) == 0 {
+		return "", nil
+	}
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for _, box := range boxes {
+		conditions = append(conditions, fmt.Sprintf("MBRIntersects(ST_GeomFromText(?, 4326, 'axis-order=long-lat'), %s)", column))
+		args = append(args, box.polygonWKT())
+	}
+	return "(" + strings.Join(conditions, " or ") + ")", args
+}
+
+// geojson coordinates are always longitude first, st_contains works on the ellipsoid of srid 4326.
+// mysql has no empty multipolygon, an empty geometry collection stands for no area
+func (mysqlDialect) areaExpr() string {
+	return "coalesce(ST_GeomFromGeoJSON(?), ST_GeomFromText('GEOMETRYCOLLECTION EMPTY', 4326))"
+}
+
+func (mysqlDialect) emptyAreaExpr(column string) string {
+	return "ST_IsEmpty(" + column + ")"
+}
+
+func (mysqlDialect) coversExpr(column string, location Address) (string, []interface{}) {
+	return fmt.Sprintf("ST_Contains(%s, ST_GeomFromText(?, 4326, 'axis-order=long-lat'))", column), []interface{}{pointWKT(location)}
+}
+
+// pointWKT formats location as a well-known text point with longitude first
+func pointWKT(location Address) string {
+	return "POINT(" + strconv.FormatFloat(location.Long, 'f', -1, 64) + " " + strconv.FormatFloat(location.Lat, 'f', -1, 64) + ")"
+}
+
+func (mysqlDialect) insert(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (mysqlDialect) lock(ctx context.Context, conn *sql.Conn, name string) error {
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, "select get_lock(?, 60)", name).Scan(&locked)
+	if err != nil {
+		return parseError(err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("could not acquire lock %s", name)
+	}
+	return nil
+}
+
+func (mysqlDialect) unlock(ctx context.Context, conn *sql.Conn, name string) {
+	_, _ = conn.ExecContext(ctx, "select release_lock(?)", name)
+}
+
+func openMySQL(connectionString string) (*sql.DB, error) {
+	// timestamps are scanned into time.Time values
+	dsn, err := mysql.ParseDSN(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	dsn.ParseTime = true
+	return sql.Open("mysql", dsn.FormatDSN())
+}
+
+func parseMySQLError(err error) (error, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err, false
+	}
+	switch mysqlErr.Number {
+	case 1062:
+		return ErrDuplicateEntry, true
+	case 1452, 3819:
+		return ErrInvalid, true
+	default:
+		return err, true
+	}
+}
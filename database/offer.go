@@ -0,0 +1,187 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// LeadStatus is a state of a lead, a lead moves offered -> accepted -> contacted -> won or lost,
+// an offered lead none of providers accepted in time is expired
+type LeadStatus string
+
+const (
+	// LeadOffered lead is offered to providers and waits for one of them to accept it
+	LeadOffered LeadStatus = "offered"
+	// LeadAccepted lead is accepted by a provider, customer phone number is revealed to it
+	LeadAccepted LeadStatus = "accepted"
+	// LeadContacted provider contacted the customer
+	LeadContacted LeadStatus = "contacted"
+	// LeadWon provider got the job
+	LeadWon LeadStatus = "won"
+	// LeadLost provider did not get the job
+	LeadLost LeadStatus = "lost"
+	// LeadExpired every offer of the lead was declined or expired
+	LeadExpired LeadStatus = "expired"
+)
+
+// OfferStatus is a state of an offer of a lead to a single provider
+type OfferStatus string
+
+const (
+	// OfferPending offer waits for provider to answer
+	OfferPending OfferStatus = "pending"
+	// OfferAccepted provider accepted the lead
+	OfferAccepted OfferStatus = "accepted"
+	// OfferDeclined provider declined the lead
+	OfferDeclined OfferStatus = "declined"
+	// OfferExpired provider did not answer in time
+	OfferExpired OfferStatus = "expired"
+	// OfferWithdrawn lead was accepted by another provider before this one answered
+	OfferWithdrawn OfferStatus = "withdrawn"
+)
+
+// LeadOffer is an offer of a lead to a provider
+type LeadOffer struct {
+	ProviderID ID
+	Status     OfferStatus
+	// ExpiresAt is when a pending offer expires
+	ExpiresAt time.Time
+}
+
+// LeadAction is what a provider does with a lead offered to it
+type LeadAction string
+
+const (
+	// ActionAccept accepts a pending offer
+	ActionAccept LeadAction = "accept"
+	// ActionDecline declines a pending offer
+	ActionDecline LeadAction = "decline"
+	// ActionContact marks an accepted lead as contacted
+	ActionContact LeadAction = "contact"
+	// ActionWin marks a contacted lead as won
+	ActionWin LeadAction = "win"
+	// ActionLose marks a contacted lead as lost
+	ActionLose LeadAction = "lose"
+)
+
+// Routing configures how leads are offered to matched providers
+type Routing struct {
+	// OfferCount is number of top matched providers a lead is offered to
+	OfferCount int
+	// OfferTTL is how long providers have to answer an offer
+	OfferTTL time.Duration
+}
+
+// Validate checks routing parameters
+func (r Routing) Validate() error {
+	if r.OfferCount < 0 || r.OfferTTL <= 0 {
+		return fmt.Errorf("%w: offer count should not be negative and offer ttl should be positive", ErrInvalid)
+	}
+	return nil
+}
+
+// Offer offers a new lead to the first providers shown for it
+func (r Routing) Offer(lead *Lead, now time.Time) {
+	lead.Offers = []LeadOffer{}
+	for i, providerID := range lead.ProviderIDs {
+		if i == r.OfferCount {
+			break
+		}
+		lead.Offers = append(lead.Offers, LeadOffer{ProviderID: providerID, Status: OfferPending, ExpiresAt: now.Add(r.OfferTTL)})
+	}
+	lead.Status = LeadOffered
+	if len(lead.Offers) == 0 {
+		lead.Status = LeadExpired
+	}
+}
+
+// Expire marks pending offers past their expiry as expired, and the lead as expired when no offer is left pending
+func (l *Lead) Expire(now time.Time) {
+	for i := range l.Offers {
+		if l.Offers[i].Status == OfferPending && !now.Before(l.Offers[i].ExpiresAt) {
+			l.Offers[i].Status = OfferExpired
+		}
+	}
+	if l.Status == LeadOffered && !l.hasPending() {
+		l.Status = LeadExpired
+	}
+}
+
+// Offer returns the offer of the lead to a provider
+func (l *Lead) Offer(providerID ID) (*LeadOffer, bool) {
+	for i := range l.Offers {
+		if l.Offers[i].ProviderID == providerID {
+			return &l.Offers[i], true
+		}
+	}
+	return nil, false
+}
+
+// AcceptedBy returns id of provider who accepted the lead, zero when nobody did
+func (l *Lead) AcceptedBy() ID {
+	for _, offer := range l.Offers {
+		if offer.Status == OfferAccepted {
+			return offer.ProviderID
+		}
+	}
+	return 0
+}
+
+// Apply moves the lead by an action of a provider at time now. ErrNotFound is returned when the lead
+// was not offered to the provider and ErrConflict when the action is not allowed in current state
+func (l *Lead) Apply(providerID ID, action LeadAction, now time.Time) error {
+	l.Expire(now)
+	offer, ok := l.Offer(providerID)
+	if !ok {
+		return fmt.Errorf("%w: lead was not offered to provider %d", ErrNotFound, providerID)
+	}
+	switch action {
+	case ActionAccept, ActionDecline:
+		if offer.Status != OfferPending {
+			return fmt.Errorf("%w: offer is %s", ErrConflict, offer.Status)
+		}
+	case ActionContact, ActionWin, ActionLose:
+		if offer.Status != OfferAccepted {
+			return fmt.Errorf("%w: lead is not accepted by provider %d", ErrConflict, providerID)
+		}
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalid, action)
+	}
+	switch action {
+	case ActionAccept:
+		offer.Status = OfferAccepted
+		for i := range l.Offers {
+			if l.Offers[i].Status == OfferPending {
+				l.Offers[i].Status = OfferWithdrawn
+			}
+		}
+		l.Status = LeadAccepted
+	case ActionDecline:
+		offer.Status = OfferDeclined
+		l.Expire(now)
+	case ActionContact:
+		return l.move(LeadAccepted, LeadContacted)
+	case ActionWin:
+		return l.move(LeadContacted, LeadWon)
+	case ActionLose:
+		return l.move(LeadContacted, LeadLost)
+	}
+	return nil
+}
+
+func (l *Lead) move(from, to LeadStatus) error {
+	if l.Status != from {
+		return fmt.Errorf("%w: lead is %s", ErrConflict, l.Status)
+	}
+	l.Status = to
+	return nil
+}
+
+func (l *Lead) hasPending() bool {
+	for _, offer := range l.Offers {
+		if offer.Status == OfferPending {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	// initialBackoff is delay after the first failed connection attempt, doubled after each failure
+	initialBackoff = 500 * time.Millisecond
+	// maxBackoff caps delay between two connection attempts
+	maxBackoff = 10 * time.Second
+)
+
+// PoolStats is a snapshot of connection pool usage
+type PoolStats struct {
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+}
+
+// PoolStats returns current usage of connection pool
+func (db *DataBase) PoolStats() PoolStats {
+	stats := db.db.Stats()
+	return PoolStats{
+		MaxOpen:      stats.MaxOpenConnections,
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration,
+	}
+}
+
+// Ping checks database is reachable
+func (db *DataBase) Ping(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	return queryError(ctx, db.db.PingContext(ctx))
+}
+
+// WaitUntilAvailable pings database with exponential backoff until a connection is available,
+// an error is returned if database is still not available after configured wait timeout
+func (db *DataBase) WaitUntilAvailable(ctx context.Context) error {
+	if db.waitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, db.waitTimeout)
+		defer cancel()
+	}
+	for attempt := 0; ; attempt++ {
+		err := db.db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		delay := backoff(attempt)
+		zap.L().Error("db connection failed", zap.Error(err), zap.Int("attempt", attempt+1), zap.Duration("retry_in", delay))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database is not available after %d attempts: %w", attempt+1, err)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns delay after a number of failed attempts
+func backoff(attempt int) time.Duration {
+	delay := initialBackoff
+	for i := 0; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) driverName() string {
+	return "postgres"
+}
+
+func (postgresDialect) migrationsDir() string {
+	return "migrations/postgres"
+}
+
+func (postgresDialect) transactionalDDL() bool {
+	return true
+}
+
+func (postgresDialect) rebind(query string) string {
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// addresses are stored as geography points with longitude as x
+func (postgresDialect) addressColumns(column string) string {
+	return fmt.Sprintf("ST_Y(%s::geometry) AS Latitude, ST_X(%s::geometry) AS Longitude", column, column)
+}
+
+func (postgresDialect) pointExpr(location Address) (string, []interface{}) {
+	return "ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography", []interface{}{location.Long, location.Lat}
+}
+
+// distance is calculated on a sphere, same as mysql st_distance_sphere
+func (postgresDialect) distanceExpr(column string, location Address) (string, []interface{}) {
+	return fmt.Sprintf("ST_Distance(%s, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, false)", column), []interface{}{location.Long, location.Lat}
+}
+
+// st_dwithin uses gist index on geography and handles poles and antimeridian itself
+func (postgresDialect) withinExpr(column string, location Address, distance float64) (string, []interface{}) {
+	return fmt.Sprintf("ST_DWithin(%s, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?, false)", column), []interface{}{location.Long, location.Lat, distance}
+}
+
+func (postgresDialect) areaExpr() string {
+	return "coalesce(ST_GeomFromGeoJSON(?)::geography, 'SRID=4326;MULTIPOLYGON EMPTY'::geography)"
+}
+
+// st_isempty has no geography variant
+func (postgresDialect) emptyAreaExpr(column string) string {
+	return "ST_IsEmpty(" + column + "::geometry)"
+}
+
+// st_covers also matches locations on the boundary, st_contains has no geography variant
+func (postgresDialect) coversExpr(column string, location Address) (string, []interface{}) {
+	return fmt.Sprintf("ST_Covers(%s, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography)", column), []interface{}{location.Long, location.Lat}
+}
+
+func (postgresDialect) insert(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, query+" returning Id", args...).Scan(&id)
+	return id, err
+}
+
+func (postgresDialect) lock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "select pg_advisory_lock($1)", lockKey(name))
+	return parseError(err)
+}
+
+func (postgresDialect) unlock(ctx context.Context, conn *sql.Conn, name string) {
+	_, _ = conn.ExecContext(ctx, "select pg_advisory_unlock($1)", lockKey(name))
+}
+
+// lockKey maps a lock name to a postgres advisory lock key
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func parsePostgresError(err error) (error, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err, false
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return ErrDuplicateEntry, true
+	case "23503", "23514": // foreign_key_violation, check_violation
+		return ErrInvalid, true
+	default:
+		return err, true
+	}
+}
@@ -0,0 +1,15 @@
+package database
+
+import "math"
+
+// EstimatePrice estimates price of covering area square metres with material by a matched provider: area times rate of
+// the material but at least minimum charge, plus travel surcharge for distance to customer. rounded to two decimals,
+// false is returned when provider has no rate for the material
+func (p Provider) EstimatePrice(material FloorMaterial, area float64) (float64, bool) {
+	rate, ok := p.Rates[material]
+	if !ok {
+		return 0, false
+	}
+	price := math.Max(area*rate, p.MinimumCharge) + p.TravelRate*Kilometre.FromMeters(p.Distance)
+	return math.Round(price*100) / 100, true
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+)
+
+// searchRadii are largest radii in meters of provider addresses and of branches, no provider covers a location
+// farther than them. zero radii do not limit the search
+type searchRadii struct {
+	address float64
+	branch  float64
+}
+
+// searchRadii reads largest radii of all providers, radius columns are indexed so each max is a single index lookup.
+// they are read for every search rather than cached, so providers saved through any server are matched right away
+func (db *DataBase) searchRadii(ctx context.Context) (searchRadii, error) {
+	var radii searchRadii
+	query := "select greatest((select coalesce(max(RadiusMeters), 0) from Provider), (select coalesce(max(RadiusMeters), 0) from ProviderMaterial)), (select coalesce(max(RadiusMeters), 0) from ProviderLocation)"
+	err := db.db.QueryRowContext(ctx, query).Scan(&radii.address, &radii.branch)
+	return radii, err
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RankingMethod is how matched providers are ranked
+type RankingMethod string
+
+const (
+	// RankByRating ranks providers by their average rating, regardless of number of reviews
+	RankByRating RankingMethod = "rating"
+	// RankBayesian ranks providers by bayesian average, rating is pulled towards a prior mean
+	// less and less as reviews are added
+	RankBayesian RankingMethod = "bayesian"
+	// RankWilson ranks providers by lower bound of wilson score interval of their rating
+	RankWilson RankingMethod = "wilson"
+)
+
+// wilsonZ is z-score of 95% confidence used for wilson lower bound
+const wilsonZ = 1.96
+
+// Ranking orders matched providers by a score accounting for number of reviews
+type Ranking struct {
+	Method RankingMethod
+	// PriorMean is rating a provider is assumed to have before any review, used by bayesian ranking
+	PriorMean float64
+	// PriorWeight is number of reviews prior mean counts as, used by bayesian ranking
+	PriorWeight float64
+}
+
+// Validate checks ranking parameters
+func (r Ranking) Validate() error {
+	switch r.Method {
+	case RankByRating, RankWilson:
+		return nil
+	case RankBayesian:
+		if r.PriorMean < 0 || r.PriorMean > 5 || r.PriorWeight <= 0 {
+			return fmt.Errorf("bayesian ranking needs a prior mean between 0 and 5 and a positive prior weight")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown ranking method %q, supported methods: rating, bayesian, wilson", r.Method)
+	}
+}
+
+// Score returns ranking score of a provider matched for jobs with material between 0 and 5, providers with higher
+// scores rank first
+func (r Ranking) Score(p Provider, material FloorMaterial) float64 {
+	n := float64(p.ReviewsFor(material))
+	switch r.Method {
+	case RankBayesian:
+		return (r.PriorWeight*r.PriorMean + n*p.Rating) / (r.PriorWeight + n)
+	case RankWilson:
+		if n == 0 {
+			return 0
+		}
+		// rating is taken as share of positive feedback, 1 star being none and 5 stars all of it
+		share := (p.Rating - 1) / 4
+		z2 := wilsonZ * wilsonZ
+		bound := (share + z2/(2*n) - wilsonZ*math.Sqrt((share*(1-share)+z2/(4*n))/n)) / (1 + z2/n)
+		return 1 + 4*math.Max(bound, 0)
+	default:
+		return p.Rating
+	}
+}
+
+// Sort orders providers matched for jobs with material by score, providers with equal scores keep their order
+func (r Ranking) Sort(providers []Provider, material FloorMaterial) {
+	ranked := rankedProviders{providers: providers, scores: make([]float64, len(providers))}
+	for i, p := range providers {
+		ranked.scores[i] = r.Score(p, material)
+	}
+	sort.Stable(ranked)
+}
+
+// rankedProviders sorts providers together with their scores
+type rankedProviders struct {
+	providers []Provider
+	scores    []float64
+}
+
+func (r rankedProviders) Len() int {
+	return len(r.providers)
+}
+
+func (r rankedProviders) Less(i, j int) bool {
+	return r.scores[i] > r.scores[j]
+}
+
+func (r rankedProviders) Swap(i, j int) {
+	r.providers[i], r.providers[j] = r.providers[j], r.providers[i]
+	r.scores[i], r.scores[j] = r.scores[j], r.scores[i]
+}
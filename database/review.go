@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AddReview adds a review and recomputes rating of its provider in the same transaction,
+// CreatedAt is set by caller. ErrNotFound is returned for an unknown provider and ErrDuplicateEntry
+// when the lead already has a review of the provider
+func (db *DataBase) AddReview(ctx context.Context, r Review) (ID, error) {
+	err := r.Validate()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var id ID
+	err = db.withTx(ctx, func(tx *sql.Tx) error {
+		// provider row is locked, so ratings of concurrent reviews are recomputed one after another
+		var providerID ID
+		err := tx.QueryRowContext(ctx, db.dialect.rebind("select Id from Provider where Id = ? for update"), r.ProviderID).Scan(&providerID)
+		if err != nil {
+			return err
+		}
+		query := "insert into Review (ProviderId, Score, Text, LeadId, CreatedAt) values (?, ?, ?, ?, ?)"
+		inserted, err := db.dialect.insert(ctx, tx, db.dialect.rebind(query), r.ProviderID, r.Score, r.Text, leadID(r.LeadID), r.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		id = ID(inserted)
+		return db.updateRating(ctx, tx, r.ProviderID)
+	})
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// GetReviews returns a page of reviews of a provider, newest first, and total number of its reviews
+func (db *DataBase) GetReviews(ctx context.Context, providerID ID, page Page) ([]Review, int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	// provider is joined so an unknown provider is not mistaken for one without reviews
+	var total int
+	query := "select count(r.Id) from Provider p left join Review r on r.ProviderId = p.Id where p.Id = ? group by p.Id"
+	err := db.db.QueryRowContext(ctx, db.dialect.rebind(query), providerID).Scan(&total)
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	query = "select Id, ProviderId, Score, Text, coalesce(LeadId, 0), CreatedAt from Review where ProviderId = ? order by CreatedAt desc, Id desc limit ? offset ?"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), providerID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+	res := []Review{}
+	for rows.Next() {
+		var item Review
+		err := rows.Scan(&item.ID, &item.ProviderID, &item.Score, &item.Text, &item.LeadID, &item.CreatedAt)
+		if err != nil {
+			return nil, 0, queryError(ctx, err)
+		}
+		item.CreatedAt = item.CreatedAt.UTC()
+		res = append(res, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	return res, total, nil
+}
+
+// updateRating sets rating and review count of a reviewed provider from its reviews in tx,
+// providers without reviews keep the rating they were given
+func (db *DataBase) updateRating(ctx context.Context, tx *sql.Tx, providerID ID) error {
+	var sum, count int
+	query := "select coalesce(sum(Score), 0), count(*) from Review where ProviderId = ?"
+	err := tx.QueryRowContext(ctx, db.dialect.rebind(query), providerID).Scan(&sum, &count)
+	if err != nil || count == 0 {
+		return err
+	}
+	query = "update Provider set Rating = ?, ReviewCount = ? where Id = ?"
+	_, err = tx.ExecContext(ctx, db.dialect.rebind(query), AverageScore(sum, count), count, providerID)
+	return err
+}
+
+// leadID stores reviews without a lead with null lead id
+func leadID(id ID) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
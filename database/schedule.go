@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ClockLayout is format of times of day in api and files
+const ClockLayout = "15:04"
+
+// WorkingHours are hours a provider works on a weekday, in its time zone
+type WorkingHours struct {
+	Weekday time.Weekday
+	// Start and End are minutes from midnight
+	Start int
+	End   int
+}
+
+// Valid reports whether weekday is known and hours are within a day with end after start
+func (h WorkingHours) Valid() bool {
+	return h.Weekday >= time.Sunday && h.Weekday <= time.Saturday && h.Start >= 0 && h.End > h.Start && h.End <= 24*60
+}
+
+// ParseWeekday returns weekday with the given english name
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// ParseClock returns minutes from midnight of a time of day in ClockLayout, 24:00 is end of day
+func ParseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse(ClockLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid time of day %q", ErrInvalid, value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// FormatClock formats minutes from midnight as a time of day in ClockLayout
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Location returns time zone of provider, UTC when it has none
+func (p Provider) Location() (*time.Location, error) {
+	loc, err := time.LoadLocation(p.TimeZone)
+	// local time zone of server is not a time zone of provider
+	if err != nil || p.TimeZone == "Local" {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalid, p.TimeZone)
+	}
+	return loc, nil
+}
+
+// validateSchedule checks time zone and working hours of provider
+func (p Provider) validateSchedule() error {
+	if len(p.TimeZone) > 64 {
+		return fmt.Errorf("%w: time zone should have at most 64 characters", ErrInvalid)
+	}
+	if _, err := p.Location(); err != nil {
+		return err
+	}
+	for _, h := range p.WorkingHours {
+		if !h.Valid() {
+			return fmt.Errorf("%w: working hours should end after they start on a known weekday", ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// workingPeriods returns periods provider works on the day of t in its time zone, none on blackout days
+func (p Provider) workingPeriods(t time.Time, loc *time.Location) [][2]time.Time {
+	local := t.In(loc)
+	year, month, day := local.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	for _, b := range p.Blackouts {
+		if b.Contains(date) {
+			return nil
+		}
+	}
+	var periods [][2]time.Time
+	for _, h := range p.WorkingHours {
+		if h.Weekday != local.Weekday() {
+			continue
+		}
+		start := time.Date(year, month, day, h.Start/60, h.Start%60, 0, 0, loc)
+		end := time.Date(year, month, day, h.End/60, h.End%60, 0, 0, loc)
+		periods = append(periods, [2]time.Time{start, end})
+	}
+	return periods
+}
+
+// Scheduling configures appointments with providers
+type Scheduling struct {
+	// SlotDuration is length of an appointment
+	SlotDuration time.Duration
+	// HorizonDays is how many days ahead appointments can be booked
+	HorizonDays int
+}
+
+// Validate checks scheduling parameters
+func (s Scheduling) Validate() error {
+	if s.SlotDuration < time.Minute || s.HorizonDays <= 0 {
+		return fmt.Errorf("%w: slot duration should be at least a minute and horizon should be positive", ErrInvalid)
+	}
+	return nil
+}
+
+// Slots returns start times of free slots of provider from time from through days days, ordered by time.
+// slots are laid back to back from start of working hours and only booked appointments take them,
+// a provider which is not active has none
+func (s Scheduling) Slots(p Provider, booked []Appointment, from time.Time, days int, now time.Time) ([]time.Time, error) {
+	loc, err := p.Location()
+	if err != nil || p.StatusAt(now) != StatusActive {
+		return nil, err
+	}
+	var slots []time.Time
+	local := from.In(loc)
+	for i := 0; i < days; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 12, 0, 0, 0, loc)
+		for _, period := range p.workingPeriods(day, loc) {
+			for start := period[0]; !start.Add(s.SlotDuration).After(period[1]); start = start.Add(s.SlotDuration) {
+				if start.Before(from) || !start.After(now) || !s.bookable(start, now) || overlapsAny(booked, start, start.Add(s.SlotDuration), 0) {
+					continue
+				}
+				slots = append(slots, start.UTC())
+			}
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Before(slots[j])
+	})
+	return slots, nil
+}
+
+// Check checks an appointment may be booked with provider at time now, ErrConflict is returned when provider
+// is not active and ErrInvalid when appointment is in past, beyond horizon or out of working hours.
+// other appointments are checked by storage
+func (s Scheduling) Check(p Provider, a Appointment, now time.Time) error {
+	if p.StatusAt(now) != StatusActive {
+		return fmt.Errorf("%w: provider is %s", ErrConflict, p.StatusAt(now))
+	}
+	if !a.StartsAt.After(now) || !s.bookable(a.StartsAt, now) {
+		return fmt.Errorf("%w: appointment should start in the next %d days", ErrInvalid, s.HorizonDays)
+	}
+	if a.EndsAt.Sub(a.StartsAt) != s.SlotDuration {
+		return fmt.Errorf("%w: appointment should last %s", ErrInvalid, s.SlotDuration)
+	}
+	loc, err := p.Location()
+	if err != nil {
+		return err
+	}
+	for _, period := range p.workingPeriods(a.StartsAt, loc) {
+		if !a.StartsAt.Before(period[0]) && !a.EndsAt.After(period[1]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: appointment is out of working hours of provider", ErrInvalid)
+}
+
+// bookable reports whether start is within horizon from now
+func (s Scheduling) bookable(start, now time.Time) bool {
+	return start.Before(now.AddDate(0, 0, s.HorizonDays))
+}
+
+// setWorkingHours replaces working hours of a provider
+func (db *DataBase) setWorkingHours(ctx context.Context, tx *sql.Tx, id ID, hours []WorkingHours) error {
+	_, err := tx.ExecContext(ctx, db.dialect.rebind("delete from ProviderWorkingHours where ProviderId = ?"), id)
+	if err != nil {
+		return err
+	}
+	query := db.dialect.rebind("insert into ProviderWorkingHours (ProviderId, Weekday, StartMinute, EndMinute) values (?, ?, ?, ?)")
+	for _, h := range hours {
+		if !h.Valid() {
+			return ErrInvalid
+		}
+		_, err := tx.ExecContext(ctx, query, id, int(h.Weekday), h.Start, h.End)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadWorkingHours fills working hours of given providers in order they were added
+func (db *DataBase) loadWorkingHours(ctx context.Context, providers []Provider) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	index := make(map[ID]int, len(providers))
+	args := make([]interface{}, 0, len(providers))
+	for i := range providers {
+		index[providers[i].ID] = i
+		args = append(args, providers[i].ID)
+	}
+	query := "select ProviderId, Weekday, StartMinute, EndMinute from ProviderWorkingHours where ProviderId in (" + placeholders(len(args)) + ") order by Id"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			providerID ID
+			h          WorkingHours
+		)
+		err := rows.Scan(&providerID, &h.Weekday, &h.Start, &h.End)
+		if err != nil {
+			return err
+		}
+		i := index[providerID]
+		providers[i].WorkingHours = append(providers[i].WorkingHours, h)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ProviderStatus is a lifecycle state of a provider, only active providers are matched
+type ProviderStatus string
+
+const (
+	// StatusPending provider signed up and waits to be verified
+	StatusPending ProviderStatus = "pending"
+	// StatusActive provider is matched with customers
+	StatusActive ProviderStatus = "active"
+	// StatusPaused provider paused itself, e.g. for holidays, until it resumes or its resume time
+	StatusPaused ProviderStatus = "paused"
+	// StatusSuspended provider is suspended by ops
+	StatusSuspended ProviderStatus = "suspended"
+)
+
+// Valid reports whether status is a known one
+func (s ProviderStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusActive, StatusPaused, StatusSuspended:
+		return true
+	}
+	return false
+}
+
+// Actor is who changes status of a provider
+type Actor string
+
+const (
+	// ActorProvider provider itself
+	ActorProvider Actor = "provider"
+	// ActorAdmin ops with admin access
+	ActorAdmin Actor = "admin"
+	// ActorSystem resumes paused providers at their resume time
+	ActorSystem Actor = "system"
+)
+
+// transitions are statuses each actor may move a provider to from its current status.
+// paused providers may be paused again to change their resume time
+var transitions = map[Actor]map[ProviderStatus][]ProviderStatus{
+	ActorProvider: {
+		StatusActive: {StatusPaused},
+		StatusPaused: {StatusActive, StatusPaused},
+	},
+	ActorAdmin: {
+		StatusPending:   {StatusActive, StatusSuspended},
+		StatusActive:    {StatusPaused, StatusSuspended},
+		StatusPaused:    {StatusActive, StatusPaused, StatusSuspended},
+		StatusSuspended: {StatusActive},
+	},
+}
+
+// StatusChange is an audit entry of a status transition of a provider
+type StatusChange struct {
+	ID         ID
+	ProviderID ID
+	From       ProviderStatus
+	To         ProviderStatus
+	// ResumeAt is when a paused provider becomes active again, zero when it stays paused until resumed
+	ResumeAt time.Time
+	Actor    Actor
+	Reason   string
+	// ChangedAt is set by caller
+	ChangedAt time.Time
+}
+
+// Validate checks fields of a requested change, From is filled by storage
+func (c StatusChange) Validate() error {
+	switch {
+	case !c.To.Valid():
+		return fmt.Errorf("%w: unknown status %q", ErrInvalid, c.To)
+	case c.Actor != ActorProvider && c.Actor != ActorAdmin:
+		return fmt.Errorf("%w: unknown actor %q", ErrInvalid, c.Actor)
+	case len(c.Reason) > 255:
+		return fmt.Errorf("%w: reason should have at most 255 characters", ErrInvalid)
+	case !c.ResumeAt.IsZero() && c.To != StatusPaused:
+		return fmt.Errorf("%w: only paused providers have a resume time", ErrInvalid)
+	case !c.ResumeAt.IsZero() && !c.ResumeAt.After(c.ChangedAt):
+		return fmt.Errorf("%w: resume time should be in future", ErrInvalid)
+	}
+	return nil
+}
+
+// StatusAt returns status of provider at time now, a paused provider is active from its resume time
+func (p Provider) StatusAt(now time.Time) ProviderStatus {
+	if p.Status == StatusPaused && !p.ResumeAt.IsZero() && !now.Before(p.ResumeAt) {
+		return StatusActive
+	}
+	return p.Status
+}
+
+// Resume makes a paused provider past its resume time active, the change made is returned
+func (p *Provider) Resume(now time.Time) (StatusChange, bool) {
+	if p.StatusAt(now) == p.Status {
+		return StatusChange{}, false
+	}
+	change := StatusChange{ProviderID: p.ID, From: p.Status, To: StatusActive, Actor: ActorSystem, ChangedAt: p.ResumeAt}
+	p.Status, p.ResumeAt = StatusActive, time.Time{}
+	return change, true
+}
+
+// ChangeStatus moves provider to status of change, a paused provider past its resume time is resumed first.
+// changes made are returned to be audited, ErrConflict is returned when the actor may not make the transition
+func (p *Provider) ChangeStatus(change StatusChange) ([]StatusChange, error) {
+	if err := change.Validate(); err != nil {
+		return nil, err
+	}
+	var changes []StatusChange
+	if resumed, ok := p.Resume(change.ChangedAt); ok {
+		changes = append(changes, resumed)
+	}
+	allowed := false
+	for _, to := range transitions[change.Actor][p.Status] {
+		allowed = allowed || to == change.To
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s can not move %s provider to %s", ErrConflict, change.Actor, p.Status, change.To)
+	}
+	change.ProviderID, change.From = p.ID, p.Status
+	p.Status, p.ResumeAt = change.To, change.ResumeAt
+	return append(changes, change), nil
+}
+
+// ImportStatus sets status of an imported provider saved with status of current, which is empty for a new provider,
+// and returns the change to audit, false when status is kept. new providers without a status are pending verification
+// and existing ones keep theirs, any given status is set regardless of transitions since imports restore data
+func (p *Provider) ImportStatus(current Provider, now time.Time) (StatusChange, bool) {
+	if p.Status == "" && current.Status != "" {
+		p.Status, p.ResumeAt = current.Status, current.ResumeAt
+		return StatusChange{}, false
+	}
+	if p.Status == "" {
+		p.Status = StatusPending
+	}
+	if p.Status == current.Status && p.ResumeAt.Equal(current.ResumeAt) {
+		return StatusChange{}, false
+	}
+	return StatusChange{ProviderID: p.ID, From: current.Status, To: p.Status, ResumeAt: p.ResumeAt, Actor: ActorAdmin, Reason: "import", ChangedAt: now}, true
+}
+
+// ChangeProviderStatus moves a provider to status of change and records the change, see Provider.ChangeStatus.
+// ChangedAt is set by caller, nothing is saved when the change fails
+func (db *DataBase) ChangeProviderStatus(ctx context.Context, change StatusChange) (Provider, error) {
+	if err := change.Validate(); err != nil {
+		return Provider{}, err
+	}
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	err := db.withTx(ctx, func(tx *sql.Tx) error {
+		// provider row is locked, so concurrent changes are made one after another
+		p := Provider{ID: change.ProviderID}
+		var resumeAt sql.NullTime
+		err := tx.QueryRowContext(ctx, db.dialect.rebind("select Status, ResumeAt from Provider where Id = ? for update"), p.ID).Scan(&p.Status, &resumeAt)
+		if err != nil {
+			return err
+		}
+		p.ResumeAt = fromNullTime(resumeAt)
+		changes, err := p.ChangeStatus(change)
+		if err != nil {
+			return err
+		}
+		return db.saveStatus(ctx, tx, p, changes...)
+	})
+	if err != nil {
+		return Provider{}, queryError(ctx, err)
+	}
+	return db.GetProvider(ctx, change.ProviderID)
+}
+
+// saveStatus sets status of provider in tx and records changes made to it
+func (db *DataBase) saveStatus(ctx context.Context, tx *sql.Tx, p Provider, changes ...StatusChange) error {
+	_, err := tx.ExecContext(ctx, db.dialect.rebind("update Provider set Status = ?, ResumeAt = ? where Id = ?"), p.Status, nullTime(p.ResumeAt), p.ID)
+	if err != nil {
+		return err
+	}
+	return db.recordStatusChanges(ctx, tx, changes...)
+}
+
+// recordStatusChanges adds changes to status history in tx
+func (db *DataBase) recordStatusChanges(ctx context.Context, tx *sql.Tx, changes ...StatusChange) error {
+	query := "insert into ProviderStatusChange (ProviderId, FromStatus, ToStatus, ResumeAt, Actor, Reason, ChangedAt) values (?, ?, ?, ?, ?, ?, ?)"
+	for _, c := range changes {
+		_, err := tx.ExecContext(ctx, db.dialect.rebind(query), c.ProviderID, c.From, c.To, nullTime(c.ResumeAt), c.Actor, c.Reason, c.ChangedAt.UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatusHistory returns a page of status changes of a provider, newest first, and total number of its changes
+func (db *DataBase) GetStatusHistory(ctx context.Context, providerID ID, page Page) ([]StatusChange, int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	// provider is joined so an unknown provider is not mistaken for one without changes
+	var total int
+	query := "select count(c.Id) from Provider p left join ProviderStatusChange c on c.ProviderId = p.Id where p.Id = ? group by p.Id"
+	err := db.db.QueryRowContext(ctx, db.dialect.rebind(query), providerID).Scan(&total)
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	query = "select Id, ProviderId, FromStatus, ToStatus, ResumeAt, Actor, Reason, ChangedAt from ProviderStatusChange where ProviderId = ? order by ChangedAt desc, Id desc limit ? offset ?"
+	rows, err := db.db.QueryContext(ctx, db.dialect.rebind(query), providerID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+	res := []StatusChange{}
+	for rows.Next() {
+		var (
+			item     StatusChange
+			resumeAt sql.NullTime
+		)
+		err := rows.Scan(&item.ID, &item.ProviderID, &item.From, &item.To, &resumeAt, &item.Actor, &item.Reason, &item.ChangedAt)
+		if err != nil {
+			return nil, 0, queryError(ctx, err)
+		}
+		item.ResumeAt = fromNullTime(resumeAt)
+		item.ChangedAt = item.ChangedAt.UTC()
+		res = append(res, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, queryError(ctx, err)
+	}
+	return res, total, nil
+}
+
+// nullTime stores zero time as null
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// fromNullTime reads null as zero time
+func fromNullTime(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time.UTC()
+}
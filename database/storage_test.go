@@ -0,0 +1,56 @@
+package database_test
+
+import (
+	"ah/database"
+	"ah/database/storagetest"
+	"ah/server/handlers"
+	"context"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+var _ handlers.Storage = (*database.DataBase)(nil)
+
+func TestStorage(t *testing.T) {
+	RegisterTestingT(t)
+	db, err := database.Connect()
+	Expect(err).To(BeNil())
+	storagetest.Run(t, func(t *testing.T) handlers.Storage {
+		Expect(db.Clear()).To(BeNil())
+		return db
+	})
+}
+
+func TestSearchAcrossServers(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := context.Background()
+	searching, err := database.Connect()
+	Expect(err).To(BeNil())
+	saving, err := database.Connect()
+	Expect(err).To(BeNil())
+	Expect(saving.Clear()).To(BeNil())
+
+	// a search of an empty database does not hide providers saved through another server afterwards
+	location := database.Address{Lat: -26, Long: 40}
+	criteria := database.Criteria{Material: database.FloorWood, Location: location, Now: time.Now()}
+	res, err := searching.GetProviders(ctx, criteria)
+	Expect(err).To(BeNil())
+	Expect(res).To(BeEmpty())
+	p := database.Provider{Name: "p0", Address: database.Address{Lat: -26.1, Long: 40}, Radius: 20, RadiusUnit: database.Kilometre, Rating: 5,
+		Status: database.StatusActive, Materials: []database.FloorMaterial{database.FloorWood}}
+	p.ID, err = saving.AddProvider(ctx, p)
+	Expect(err).To(BeNil())
+	res, err = searching.GetProviders(ctx, criteria)
+	Expect(err).To(BeNil())
+	Expect(res).To(HaveLen(1))
+
+	// nor a radius widened through it
+	p.Address = database.Address{Lat: -26.5, Long: 40}
+	p.Radius = 80
+	Expect(saving.UpdateProvider(ctx, p)).To(BeNil())
+	res, err = searching.GetProviders(ctx, criteria)
+	Expect(err).To(BeNil())
+	Expect(res).To(HaveLen(1))
+	Expect(res[0].ID).To(Equal(p.ID))
+}
@@ -0,0 +1,1350 @@
+// Package storagetest contains conformance tests every storage backend should pass
+package storagetest
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/onsi/gomega"
+	"math"
+	"testing"
+	"time"
+)
+
+// earthRadius is mean radius of earth in meters used to build test fixtures
+const earthRadius = 6370986
+
+// Factory returns an empty storage, it is called once for each test
+type Factory func(t *testing.T) handlers.Storage
+
+// Run runs all conformance tests against storages created by factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(ctx context.Context, storage handlers.Storage)
+	}{
+		{"MatchingMaterial", testMatchingMaterial},
+		{"ExcludeOutOfRadius", testExcludeOutOfRadius},
+		{"Order", testOrder},
+		{"MultipleChecks", testMultipleChecks},
+		{"Antimeridian", testAntimeridian},
+		{"Poles", testPoles},
+		{"RadiusBoundary", testRadiusBoundary},
+		{"RadiusUnit", testRadiusUnit},
+		{"Distance", testDistance},
+		{"ProviderCRUD", testProviderCRUD},
+		{"Materials", testMaterials},
+		{"Rates", testRates},
+		{"JobArea", testJobArea},
+		{"ServiceArea", testServiceArea},
+		{"Branches", testBranches},
+		{"MaterialRadiusRating", testMaterialRadiusRating},
+		{"Availability", testAvailability},
+		{"WorkingHours", testWorkingHours},
+		{"ProviderStatus", testProviderStatus},
+		{"ExternalID", testExternalID},
+		{"Import", testImport},
+		{"ImportStatus", testImportStatus},
+		{"Export", testExport},
+		{"Reviews", testReviews},
+		{"Leads", testLeads},
+		{"LeadOffers", testLeadOffers},
+		{"OfferedLeads", testOfferedLeads},
+		{"Appointments", testAppointments},
+		{"Canceled", testCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTestingT(t)
+			tt.test(context.Background(), factory(t))
+		})
+	}
+}
+
+func populate(ctx context.Context, storage handlers.Storage, providers []database.Provider) {
+	for i := range providers {
+		id, err := storage.AddProvider(ctx, providers[i])
+		Expect(err).To(BeNil())
+		providers[i].ID = id
+		// providers are added active unless fixture says otherwise
+		if providers[i].Status == "" {
+			providers[i].Status = database.StatusActive
+		}
+	}
+}
+
+// withoutDistance clears distances of matched providers so they can be compared with fixtures
+func withoutDistance(providers []database.Provider) []database.Provider {
+	res := make([]database.Provider, 0, len(providers))
+	for _, p := range providers {
+		p.Distance = 0
+		res = append(res, p)
+	}
+	return res
+}
+
+func materials(m ...database.FloorMaterial) []database.FloorMaterial {
+	return m
+}
+
+func testMatchingMaterial(ctx context.Context, storage handlers.Storage) {
+	location := database.Address{Lat: -26, Long: 40}
+	providers := []database.Provider{
+		{Name: "p0", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5},
+		{Name: "p1", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorWood)},
+		{Name: "p2", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorCarpet)},
+		{Name: "p3", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorTile)},
+		{Name: "p4", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorWood, database.FloorCarpet)},
+		{Name: "p5", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorWood, database.FloorTile)},
+		{Name: "p6", Address: location, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorCarpet, database.FloorTile)},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: location})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(ConsistOf(providers[1], providers[4], providers[5]))
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: database.FloorCarpet, Location: location})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(ConsistOf(providers[2], providers[4], providers[6]))
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: database.FloorTile, Location: location})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(ConsistOf(providers[3], providers[5], providers[6]))
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: "laminate", Location: location})
+	Expect(err).To(BeNil())
+	Expect(res).To(BeEmpty())
+}
+
+func testExcludeOutOfRadius(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	providers := []database.Provider{
+		// same location
+		{Name: "p0", Address: database.Address{Lat: -26.66119, Long: 40.95858}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// ~11.1m to the south
+		{Name: "p1", Address: database.Address{Lat: -26.66129, Long: 40.95858}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// ~9.9m to the east
+		{Name: "p2", Address: database.Address{Lat: -26.66119, Long: 40.95868}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// ~14.9m to the south east
+		{Name: "p3", Address: database.Address{Lat: -26.66129, Long: 40.95868}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: -26.66119, Long: 40.95858}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(ConsistOf(providers[0], providers[2]))
+}
+
+func testOrder(ctx context.Context, storage handlers.Storage) {
+	all := materials(database.FloorWood, database.FloorCarpet, database.FloorTile)
+	providers := []database.Provider{
+		{Name: "p0", Address: database.Address{Lat: -26.66129, Long: 40.95858}, Radius: 100, RadiusUnit: database.Metre, Rating: 3.5, Materials: all},
+		{Name: "p1", Address: database.Address{Lat: -26.66139, Long: 40.95858}, Radius: 100, RadiusUnit: database.Metre, Rating: 3.5, Materials: all},
+		{Name: "p2", Address: database.Address{Lat: -26.66139, Long: 40.95878}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: all},
+		{Name: "p3", Address: database.Address{Lat: -26.66119, Long: 40.95858}, Radius: 10, RadiusUnit: database.Metre, Rating: 3.0, Materials: all},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: -26.66119, Long: 40.95858}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[2], providers[0], providers[1], providers[3]}))
+}
+
+func testMultipleChecks(ctx context.Context, storage handlers.Storage) {
+	providers := []database.Provider{
+		{Name: "p0", Address: database.Address{Lat: -26.66119, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 3.5, Materials: materials(database.FloorWood, database.FloorCarpet, database.FloorTile)},
+		{Name: "p1", Address: database.Address{Lat: -26.66120, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 4.5, Materials: materials(database.FloorCarpet, database.FloorTile)},
+		{Name: "p2", Address: database.Address{Lat: -26.66116, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 4.5, Materials: materials(database.FloorWood)},
+		{Name: "p3", Address: database.Address{Lat: -26.66117, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 4.7, Materials: materials(database.FloorWood, database.FloorCarpet)},
+		{Name: "p4", Address: database.Address{Lat: -26.66115, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 4.5, Materials: materials(database.FloorWood)},
+		{Name: "p5", Address: database.Address{Lat: -26.66118, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 4.1, Materials: materials(database.FloorWood, database.FloorTile)},
+		{Name: "p6", Address: database.Address{Lat: -26.66116, Long: 40.95858}, Radius: 2, RadiusUnit: database.Metre, Rating: 4.8, Materials: materials(database.FloorWood)},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: -26.66119, Long: 40.95858}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[5], providers[0]}))
+}
+
+func testAntimeridian(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	providers := []database.Provider{
+		// ~22m away on the other side of antimeridian
+		{Name: "p0", Address: database.Address{Lat: 0, Long: -179.9999}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// ~1.1km away on the same side
+		{Name: "p1", Address: database.Address{Lat: 0, Long: 179.9899}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// ~1.1km away on the other side
+		{Name: "p2", Address: database.Address{Lat: 0, Long: -179.9899}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 0, Long: 179.9999}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[0]}))
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 0, Long: -180}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[0]}))
+}
+
+func testPoles(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	providers := []database.Provider{
+		// ~22m away across the north pole
+		{Name: "p0", Address: database.Address{Lat: 89.9999, Long: 180}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// exactly on north pole, longitude is meaningless there
+		{Name: "p1", Address: database.Address{Lat: 90, Long: 123}, Radius: 100, RadiusUnit: database.Metre, Rating: 4, Materials: wood},
+		// ~22km away from north pole
+		{Name: "p2", Address: database.Address{Lat: 89.8, Long: 0}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		// exactly on south pole
+		{Name: "p3", Address: database.Address{Lat: -90, Long: 0}, Radius: 100, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 89.9999, Long: 0}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[0], providers[1]}))
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 90, Long: -45}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[0], providers[1]}))
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: -89.9999, Long: 90}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[3]}))
+}
+
+func testRadiusBoundary(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	// along the equator great-circle distance is proportional to longitude difference
+	const longDiff = 0.01
+	distance := earthRadius * longDiff * math.Pi / 180
+	providers := []database.Provider{
+		{Name: "p0", Address: database.Address{Lat: 0, Long: longDiff}, Radius: distance * 1.001, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		{Name: "p1", Address: database.Address{Lat: 0, Long: longDiff}, Radius: distance * 0.999, RadiusUnit: database.Metre, Rating: 5, Materials: wood},
+		{Name: "p2", Address: database.Address{Lat: 0, Long: -longDiff}, Radius: distance * 1.001, RadiusUnit: database.Metre, Rating: 4, Materials: wood},
+		{Name: "p3", Address: database.Address{Lat: 0, Long: -longDiff}, Radius: distance * 0.999, RadiusUnit: database.Metre, Rating: 4, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 0, Long: 0}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[0], providers[2]}))
+
+	// radius is exclusive, a provider covers nothing at distance equal to its radius
+	Expect(storage.DeleteProvider(ctx, providers[0].ID)).To(BeNil())
+	Expect(storage.DeleteProvider(ctx, providers[2].ID)).To(BeNil())
+	onBoundary := database.Provider{Name: "p4", Address: database.Address{Lat: 10, Long: 10}, Radius: 0, RadiusUnit: database.Metre, Rating: 5, Materials: wood}
+	populate(ctx, storage, []database.Provider{onBoundary})
+	res, err = storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 10, Long: 10}})
+	Expect(err).To(BeNil())
+	Expect(res).To(BeEmpty())
+}
+
+func testRadiusUnit(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	// one degree of latitude is ~111.2km
+	providers := []database.Provider{
+		{Name: "p0", Address: database.Address{Lat: 1, Long: 0}, Radius: 112, RadiusUnit: database.Kilometre, Rating: 5, Materials: wood},
+		{Name: "p1", Address: database.Address{Lat: 1, Long: 0}, Radius: 111, RadiusUnit: database.Kilometre, Rating: 5, Materials: wood},
+		{Name: "p2", Address: database.Address{Lat: 1, Long: 0}, Radius: 70, RadiusUnit: database.Mile, Rating: 4, Materials: wood},
+		{Name: "p3", Address: database.Address{Lat: 1, Long: 0}, Radius: 69, RadiusUnit: database.Mile, Rating: 4, Materials: wood},
+		{Name: "p4", Address: database.Address{Lat: 1, Long: 0}, Radius: 112000, RadiusUnit: database.Metre, Rating: 3, Materials: wood},
+		{Name: "p5", Address: database.Address{Lat: 1, Long: 0}, Radius: 111000, RadiusUnit: database.Metre, Rating: 3, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 0, Long: 0}})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[0], providers[2], providers[4]}))
+}
+
+func testDistance(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	// along a meridian great-circle distance is proportional to latitude difference
+	meters := func(latDiff float64) float64 {
+		return earthRadius * latDiff * math.Pi / 180
+	}
+	providers := []database.Provider{
+		{Name: "p0", Address: database.Address{Lat: 0.02, Long: 0}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: wood},
+		{Name: "p1", Address: database.Address{Lat: 0.01, Long: 0}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: wood},
+		{Name: "p2", Address: database.Address{Lat: -0.01, Long: 0}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: wood},
+		{Name: "p3", Address: database.Address{Lat: 0.03, Long: 0}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 0, Long: 0}})
+	Expect(err).To(BeNil())
+	// higher rating wins over distance, equal distances are ordered by id
+	Expect(withoutDistance(res)).To(Equal([]database.Provider{providers[3], providers[1], providers[2], providers[0]}))
+	Expect(res[0].Distance).To(BeNumerically("~", meters(0.03), 1))
+	Expect(res[1].Distance).To(BeNumerically("~", meters(0.01), 1))
+	Expect(res[2].Distance).To(BeNumerically("~", meters(0.01), 1))
+	Expect(res[3].Distance).To(BeNumerically("~", meters(0.02), 1))
+}
+
+func testProviderCRUD(ctx context.Context, storage handlers.Storage) {
+	provider := database.Provider{
+		Name:       "p0",
+		Address:    database.Address{Lat: -26.66119, Long: 40.95858},
+		Radius:     10,
+		RadiusUnit: database.Metre,
+		Rating:     4,
+		Materials:  materials(database.FloorWood, database.FloorTile),
+		Status:     database.StatusActive,
+	}
+	id, err := storage.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	provider.ID = id
+
+	res, err := storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+
+	provider.Name = "p1"
+	provider.Radius = 2
+	provider.RadiusUnit = database.Mile
+	provider.Rating = 4.5
+	provider.Materials = materials(database.FloorCarpet)
+	err = storage.UpdateProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+
+	// updating with identical values is not an error
+	err = storage.UpdateProvider(ctx, provider)
+	Expect(err).To(BeNil())
+
+	// unknown materials are rejected and leave provider untouched
+	invalid := provider
+	invalid.Materials = materials(database.FloorWood, "marble")
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	invalid = provider
+	invalid.RadiusUnit = "ft"
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+
+	err = storage.DeleteProvider(ctx, id)
+	Expect(err).To(BeNil())
+	_, err = storage.GetProvider(ctx, id)
+	Expect(err).To(Equal(database.ErrNotFound))
+	Expect(storage.UpdateProvider(ctx, provider)).To(Equal(database.ErrNotFound))
+	Expect(storage.DeleteProvider(ctx, id)).To(Equal(database.ErrNotFound))
+}
+
+func testMaterials(ctx context.Context, storage handlers.Storage) {
+	res, err := storage.GetMaterials(ctx)
+	Expect(err).To(BeNil())
+	Expect(res).To(ContainElements(
+		database.Material{ID: 1, Name: database.FloorWood},
+		database.Material{ID: 2, Name: database.FloorCarpet},
+		database.Material{ID: 3, Name: database.FloorTile},
+	))
+}
+
+func testExternalID(ctx context.Context, storage handlers.Storage) {
+	provider := database.Provider{ExternalID: "crm-1", Name: "p0", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorWood), Status: database.StatusActive}
+	id, err := storage.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	provider.ID = id
+	res, err := storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+
+	// external ids are unique, empty ones are not
+	duplicate := provider
+	_, err = storage.AddProvider(ctx, duplicate)
+	Expect(err).To(Equal(database.ErrDuplicateEntry))
+	duplicate.ExternalID = ""
+	other, err := storage.AddProvider(ctx, duplicate)
+	Expect(err).To(BeNil())
+	_, err = storage.AddProvider(ctx, duplicate)
+	Expect(err).To(BeNil())
+	duplicate.ID, duplicate.ExternalID = other, provider.ExternalID
+	Expect(storage.UpdateProvider(ctx, duplicate)).To(Equal(database.ErrDuplicateEntry))
+	res, err = storage.GetProvider(ctx, other)
+	Expect(err).To(BeNil())
+	Expect(res.ExternalID).To(BeEmpty())
+}
+
+func testImport(ctx context.Context, storage handlers.Storage) {
+	existing := database.Provider{ExternalID: "crm-1", Name: "p0", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorWood), Status: database.StatusActive}
+	id, err := storage.AddProvider(ctx, existing)
+	Expect(err).To(BeNil())
+	existing.ID = id
+
+	update := existing
+	update.ID, update.Name, update.Materials = 0, "p1", materials(database.FloorTile)
+	created := database.Provider{ExternalID: "crm-2", Name: "p2", Address: database.Address{Lat: 20, Long: 20}, Radius: 1, RadiusUnit: database.Kilometre, Materials: materials(database.FloorCarpet)}
+	invalid := created
+	invalid.ExternalID, invalid.Name = "crm-3", ""
+	unknownMaterial := created
+	unknownMaterial.ExternalID, unknownMaterial.Materials = "crm-4", materials("marble")
+	providers := []database.Provider{update, created, invalid, unknownMaterial}
+
+	// nothing is saved in dry run but results are the same
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, options := range []database.ImportOptions{{Upsert: true, DryRun: true, Now: now}, {Upsert: true, Now: now}} {
+		results, err := storage.ImportProviders(ctx, providers, options)
+		Expect(err).To(BeNil())
+		Expect(results).To(HaveLen(4))
+		Expect(results[0]).To(Equal(database.ImportResult{ID: id, Updated: true}))
+		Expect(results[1].Err).To(BeNil())
+		Expect(results[1].Updated).To(BeFalse())
+		Expect(errors.Is(results[2].Err, database.ErrInvalid)).To(BeTrue())
+		Expect(errors.Is(results[3].Err, database.ErrInvalid)).To(BeTrue())
+
+		res, err := storage.GetProvider(ctx, id)
+		Expect(err).To(BeNil())
+		_, createdErr := storage.GetProvider(ctx, results[1].ID)
+		if options.DryRun {
+			Expect(res).To(Equal(existing))
+			Expect(createdErr).To(Equal(database.ErrNotFound))
+			continue
+		}
+		update.ID = id
+		Expect(res).To(Equal(update))
+		Expect(createdErr).To(BeNil())
+	}
+
+	// without upsert known external ids are duplicates
+	results, err := storage.ImportProviders(ctx, providers[:2], database.ImportOptions{Now: now})
+	Expect(err).To(BeNil())
+	Expect(errors.Is(results[0].Err, database.ErrDuplicateEntry)).To(BeTrue())
+	Expect(errors.Is(results[1].Err, database.ErrDuplicateEntry)).To(BeTrue())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = storage.ImportProviders(canceled, providers, database.ImportOptions{})
+	Expect(err).To(MatchError(context.Canceled))
+}
+
+func testImportStatus(ctx context.Context, storage handlers.Storage) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	resumeAt := now.Add(24 * time.Hour)
+	history := func(id database.ID) []database.StatusChange {
+		changes, _, err := storage.GetStatusHistory(ctx, id, database.Page{Limit: 10})
+		Expect(err).To(BeNil())
+		for i := range changes {
+			Expect(changes[i].ID).NotTo(BeZero())
+			changes[i].ID = 0
+		}
+		return changes
+	}
+	verified := database.Provider{ExternalID: "crm-1", Name: "p1", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Metre, Materials: materials(database.FloorWood)}
+	unverified := verified
+	unverified.ExternalID, unverified.Name = "crm-2", "p2"
+	verified.Status = database.StatusActive
+
+	// new providers wait for verification unless their status is given, both are audited
+	results, err := storage.ImportProviders(ctx, []database.Provider{verified, unverified}, database.ImportOptions{Now: now})
+	Expect(err).To(BeNil())
+	Expect(results[0].Err).To(BeNil())
+	Expect(results[1].Err).To(BeNil())
+	saved, err := storage.GetProvider(ctx, results[1].ID)
+	Expect(err).To(BeNil())
+	Expect(saved.Status).To(Equal(database.StatusPending))
+	Expect(history(results[0].ID)).To(Equal([]database.StatusChange{
+		{ProviderID: results[0].ID, To: database.StatusActive, Actor: database.ActorAdmin, Reason: "import", ChangedAt: now},
+	}))
+	Expect(history(results[1].ID)).To(Equal([]database.StatusChange{
+		{ProviderID: results[1].ID, To: database.StatusPending, Actor: database.ActorAdmin, Reason: "import", ChangedAt: now},
+	}))
+
+	// existing providers keep their status unless one is given, nothing is audited in dry run
+	verified.Status, unverified.Status, unverified.ResumeAt = "", database.StatusPaused, resumeAt
+	for _, options := range []database.ImportOptions{{Upsert: true, DryRun: true, Now: now.Add(time.Hour)}, {Upsert: true, Now: now.Add(time.Hour)}} {
+		results, err = storage.ImportProviders(ctx, []database.Provider{verified, unverified}, options)
+		Expect(err).To(BeNil())
+		Expect(results[0].Updated).To(BeTrue())
+		Expect(results[1].Updated).To(BeTrue())
+	}
+	saved, err = storage.GetProvider(ctx, results[0].ID)
+	Expect(err).To(BeNil())
+	Expect(saved.Status).To(Equal(database.StatusActive))
+	Expect(history(results[0].ID)).To(HaveLen(1))
+	saved, err = storage.GetProvider(ctx, results[1].ID)
+	Expect(err).To(BeNil())
+	Expect(saved.Status).To(Equal(database.StatusPaused))
+	Expect(saved.ResumeAt).To(Equal(resumeAt))
+	Expect(history(results[1].ID)).To(Equal([]database.StatusChange{
+		{ProviderID: results[1].ID, From: database.StatusPending, To: database.StatusPaused, ResumeAt: resumeAt, Actor: database.ActorAdmin, Reason: "import", ChangedAt: now.Add(time.Hour)},
+		{ProviderID: results[1].ID, To: database.StatusPending, Actor: database.ActorAdmin, Reason: "import", ChangedAt: now},
+	}))
+
+	// the same status is not audited again
+	_, err = storage.ImportProviders(ctx, []database.Provider{unverified}, database.ImportOptions{Upsert: true, Now: now.Add(2 * time.Hour)})
+	Expect(err).To(BeNil())
+	Expect(history(results[1].ID)).To(HaveLen(2))
+
+	// resume time is only given to paused providers
+	verified.Status, verified.ResumeAt = database.StatusActive, resumeAt
+	results, err = storage.ImportProviders(ctx, []database.Provider{verified}, database.ImportOptions{Upsert: true, Now: now})
+	Expect(err).To(BeNil())
+	Expect(errors.Is(results[0].Err, database.ErrInvalid)).To(BeTrue())
+}
+
+func testExport(ctx context.Context, storage handlers.Storage) {
+	// more providers than fit in a single page of database export
+	providers := make([]database.Provider, 1001)
+	for i := range providers {
+		providers[i] = database.Provider{Name: "p", Address: database.Address{Lat: float64(i%180) - 89.5, Long: 10}, Radius: float64(i + 1), RadiusUnit: database.Metre, Rating: 5, Status: database.StatusActive}
+		if i%2 == 0 {
+			providers[i].ExternalID = fmt.Sprintf("crm-%d", i)
+			providers[i].Materials = materials(database.FloorWood, database.FloorTile)
+		}
+		if i%3 == 0 {
+			day := time.Date(2024, 7, 1+i%28, 0, 0, 0, 0, time.UTC)
+			providers[i].Blackouts = []database.DateRange{{From: day, To: day.AddDate(0, 0, 7)}}
+		}
+	}
+	results, err := storage.ImportProviders(ctx, providers, database.ImportOptions{Now: time.Now().UTC().Truncate(time.Second)})
+	Expect(err).To(BeNil())
+	for i, result := range results {
+		Expect(result.Err).To(BeNil())
+		providers[i].ID = result.ID
+	}
+
+	var res []database.Provider
+	err = storage.ExportProviders(ctx, func(p database.Provider) error {
+		res = append(res, p)
+		return nil
+	})
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers))
+
+	// an error returned by callback stops the export
+	stop := errors.New("stop")
+	count := 0
+	err = storage.ExportProviders(ctx, func(database.Provider) error {
+		count++
+		return stop
+	})
+	Expect(err).To(Equal(stop))
+	Expect(count).To(Equal(1))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = storage.ExportProviders(canceled, func(database.Provider) error {
+		return nil
+	})
+	Expect(err).To(MatchError(context.Canceled))
+}
+
+func testRates(ctx context.Context, storage handlers.Storage) {
+	provider := database.Provider{
+		Name:          "p0",
+		Address:       database.Address{Lat: 10, Long: 10},
+		Radius:        10,
+		RadiusUnit:    database.Kilometre,
+		Rating:        4,
+		Materials:     materials(database.FloorWood, database.FloorTile),
+		Rates:         map[database.FloorMaterial]float64{database.FloorWood: 25.5},
+		MinimumCharge: 300,
+		TravelRate:    1.5,
+		Status:        database.StatusActive,
+	}
+	id, err := storage.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	provider.ID = id
+	res, err := storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+
+	// matched providers carry rates, so price can be estimated
+	matched, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: 10.01, Long: 10}})
+	Expect(err).To(BeNil())
+	Expect(matched).To(HaveLen(1))
+	Expect(matched[0].Rates).To(Equal(provider.Rates))
+	Expect(matched[0].MinimumCharge).To(Equal(provider.MinimumCharge))
+	Expect(matched[0].TravelRate).To(Equal(provider.TravelRate))
+	price, ok := matched[0].EstimatePrice(database.FloorWood, 10)
+	Expect(ok).To(BeTrue())
+	Expect(price).To(BeNumerically("~", 300+1.5*matched[0].Distance/1000, 0.01))
+
+	provider.Rates = map[database.FloorMaterial]float64{database.FloorTile: 40, database.FloorWood: 20}
+	provider.MinimumCharge = 0
+	Expect(storage.UpdateProvider(ctx, provider)).To(BeNil())
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+
+	provider.Rates = nil
+	Expect(storage.UpdateProvider(ctx, provider)).To(BeNil())
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res.Rates).To(BeEmpty())
+
+	// a rate is only given for a material provider works with
+	invalid := provider
+	invalid.Rates = map[database.FloorMaterial]float64{database.FloorCarpet: 10}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res.Rates).To(BeEmpty())
+}
+
+func testJobArea(ctx context.Context, storage handlers.Storage) {
+	location := database.Address{Lat: 10, Long: 10}
+	providers := []database.Provider{
+		{Name: "any", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood, database.FloorTile)},
+		{Name: "large", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: materials(database.FloorWood, database.FloorTile),
+			JobArea: database.AreaRange{Min: 50}},
+		// tile jobs are limited differently from wood jobs
+		{Name: "small", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 3, Materials: materials(database.FloorWood, database.FloorTile),
+			JobArea: database.AreaRange{Max: 100}, MaterialJobAreas: map[database.FloorMaterial]database.AreaRange{database.FloorTile: {Min: 10, Max: 1000}}},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProvider(ctx, providers[2].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[2]))
+
+	names := func(material database.FloorMaterial, area float64) []string {
+		res, err := storage.GetProviders(ctx, database.Criteria{Material: material, Location: location, Area: area})
+		Expect(err).To(BeNil())
+		names := []string{}
+		for _, p := range res {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	Expect(names(database.FloorWood, 0)).To(Equal([]string{"any", "large", "small"}))
+	Expect(names(database.FloorWood, 5)).To(Equal([]string{"any", "small"}))
+	// limits are inclusive
+	Expect(names(database.FloorWood, 50)).To(Equal([]string{"any", "large", "small"}))
+	Expect(names(database.FloorWood, 100)).To(Equal([]string{"any", "large", "small"}))
+	Expect(names(database.FloorWood, 500)).To(Equal([]string{"any", "large"}))
+	Expect(names(database.FloorTile, 5)).To(Equal([]string{"any"}))
+	Expect(names(database.FloorTile, 500)).To(Equal([]string{"any", "large", "small"}))
+	Expect(names(database.FloorTile, 5000)).To(Equal([]string{"any", "large"}))
+
+	// job areas are invalid with max below min or for a material provider does not work with
+	invalid := providers[2]
+	invalid.JobArea = database.AreaRange{Min: 100, Max: 50}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	invalid = providers[2]
+	invalid.MaterialJobAreas = map[database.FloorMaterial]database.AreaRange{database.FloorCarpet: {Min: 10}}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	res, err = storage.GetProvider(ctx, providers[2].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[2]))
+}
+
+// square returns a closed counterclockwise ring of a square with the given south west corner and side in degrees
+func square(lat, long, side float64) []database.Address {
+	return []database.Address{{Lat: lat, Long: long}, {Lat: lat, Long: long + side}, {Lat: lat + side, Long: long + side}, {Lat: lat + side, Long: long}, {Lat: lat, Long: long}}
+}
+
+func reversed(ring []database.Address) []database.Address {
+	res := make([]database.Address, 0, len(ring))
+	for i := len(ring) - 1; i >= 0; i-- {
+		res = append(res, ring[i])
+	}
+	return res
+}
+
+func testServiceArea(ctx context.Context, storage handlers.Storage) {
+	providers := []database.Provider{
+		// the first polygon has a hole in the middle
+		{Name: "area", Address: database.Address{}, Radius: 1, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood),
+			ServiceArea: database.ServiceArea{{square(10, 10, 1), reversed(square(10.4, 10.4, 0.2))}, {square(20, 20, 1)}}},
+		{Name: "radius", Address: database.Address{Lat: 10.5, Long: 10.5}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: materials(database.FloorWood)},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+
+	names := func(location database.Address) []string {
+		res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: location})
+		Expect(err).To(BeNil())
+		names := []string{}
+		for _, p := range res {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	Expect(names(database.Address{Lat: 10.2, Long: 10.2})).To(Equal([]string{"area"}))
+	Expect(names(database.Address{Lat: 20.5, Long: 20.7})).To(Equal([]string{"area"}))
+	Expect(names(database.Address{Lat: 10.5, Long: 10.5})).To(Equal([]string{"radius"}))
+	// radius is not used when there is a service area
+	Expect(names(database.Address{Lat: 0.001, Long: 0.001})).To(BeEmpty())
+
+	invalid := providers[0]
+	invalid.ServiceArea = database.ServiceArea{{square(10, 10, 1)[:4]}}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+
+	// without a service area radius applies again
+	providers[0].ServiceArea = nil
+	Expect(storage.UpdateProvider(ctx, providers[0])).To(BeNil())
+	res, err = storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+	Expect(names(database.Address{Lat: 0.001, Long: 0.001})).To(Equal([]string{"area"}))
+	Expect(names(database.Address{Lat: 10.2, Long: 10.2})).To(BeEmpty())
+}
+
+func testBranches(ctx context.Context, storage handlers.Storage) {
+	wood := materials(database.FloorWood)
+	meters := func(latDiff float64) float64 {
+		return earthRadius * latDiff * math.Pi / 180
+	}
+	north := database.Branch{Name: "north", Address: database.Address{Lat: 1, Long: 0}, Radius: 20, RadiusUnit: database.Kilometre}
+	farNorth := database.Branch{Name: "far north", Address: database.Address{Lat: 1.1, Long: 0}, Radius: 50, RadiusUnit: database.Kilometre}
+	providers := []database.Provider{
+		{Name: "hq", Address: database.Address{}, Radius: 5, RadiusUnit: database.Kilometre, Rating: 4, Materials: wood, Branches: []database.Branch{north, farNorth}},
+		{Name: "other", Address: database.Address{Lat: 1.01, Long: 0}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: wood},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+
+	match := func(lat float64) []database.Provider {
+		res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: database.Address{Lat: lat, Long: 0}})
+		Expect(err).To(BeNil())
+		return res
+	}
+	// both branches cover the location, provider is listed once with the closest one
+	matched := match(1.02)
+	Expect(matched).To(HaveLen(2))
+	Expect(matched[0].ID).To(Equal(providers[1].ID))
+	Expect(matched[1].ID).To(Equal(providers[0].ID))
+	Expect(matched[1].Branches).To(Equal(providers[0].Branches))
+	Expect(matched[1].ClosestBranch).To(Equal(&north))
+	Expect(matched[1].Distance).To(BeNumerically("~", meters(0.02), 1))
+
+	matched = match(1.3)
+	Expect(matched).To(HaveLen(1))
+	Expect(matched[0].ClosestBranch).To(Equal(&farNorth))
+	Expect(matched[0].Distance).To(BeNumerically("~", meters(0.2), 1))
+
+	// address is reported without a branch
+	matched = match(0.01)
+	Expect(matched).To(HaveLen(1))
+	Expect(matched[0].ClosestBranch).To(BeNil())
+	Expect(matched[0].Distance).To(BeNumerically("~", meters(0.01), 1))
+	Expect(match(0.5)).To(BeEmpty())
+
+	invalid := providers[0]
+	invalid.Branches = []database.Branch{{Name: "", Address: database.Address{Lat: 1, Long: 0}, Radius: 20, RadiusUnit: database.Kilometre}}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	invalid.Branches = []database.Branch{{Name: "b", Address: database.Address{Lat: 1, Long: 0}, Radius: 20, RadiusUnit: "ft"}}
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+
+	providers[0].Branches = []database.Branch{farNorth}
+	Expect(storage.UpdateProvider(ctx, providers[0])).To(BeNil())
+	res, err = storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+	matched = match(1.02)
+	Expect(matched).To(HaveLen(2))
+	Expect(matched[1].ClosestBranch).To(Equal(&farNorth))
+}
+
+func testMaterialRadiusRating(ctx context.Context, storage handlers.Storage) {
+	both := materials(database.FloorWood, database.FloorCarpet)
+	providers := []database.Provider{
+		{Name: "a", Address: database.Address{}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 3, Materials: both,
+			MaterialRadii:   map[database.FloorMaterial]database.MaterialRadius{database.FloorWood: {Radius: 50, RadiusUnit: database.Kilometre}},
+			MaterialRatings: map[database.FloorMaterial]float64{database.FloorCarpet: 5}},
+		{Name: "b", Address: database.Address{Lat: 0.05, Long: 0}, Radius: 30, RadiusUnit: database.Kilometre, Rating: 4, Materials: both},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+
+	match := func(material database.FloorMaterial, lat float64) []database.Provider {
+		res, err := storage.GetProviders(ctx, database.Criteria{Material: material, Location: database.Address{Lat: lat, Long: 0}})
+		Expect(err).To(BeNil())
+		return withoutDistance(res)
+	}
+	// matched providers have radius and rating of the requested material
+	Expect(match(database.FloorWood, 0.3)).To(Equal([]database.Provider{providers[1], providers[0].ForMaterial(database.FloorWood)}))
+	Expect(match(database.FloorCarpet, 0.3)).To(Equal([]database.Provider{providers[1]}))
+	Expect(match(database.FloorCarpet, 0.01)).To(Equal([]database.Provider{providers[0].ForMaterial(database.FloorCarpet), providers[1]}))
+	Expect(match(database.FloorWood, 0.01)).To(Equal([]database.Provider{providers[1], providers[0].ForMaterial(database.FloorWood)}))
+
+	// radius and rating are invalid out of range or for a material provider does not work with
+	invalid := providers[0]
+	invalid.MaterialRadii = map[database.FloorMaterial]database.MaterialRadius{database.FloorTile: {Radius: 5, RadiusUnit: database.Kilometre}}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	invalid.MaterialRadii = map[database.FloorMaterial]database.MaterialRadius{database.FloorWood: {Radius: 0, RadiusUnit: database.Kilometre}}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	invalid = providers[0]
+	invalid.MaterialRatings = map[database.FloorMaterial]float64{database.FloorWood: 6}
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	res, err = storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+}
+
+func testAvailability(ctx context.Context, storage handlers.Storage) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 7, d, 0, 0, 0, 0, time.UTC)
+	}
+	location := database.Address{Lat: 10, Long: 10}
+	providers := []database.Provider{
+		{Name: "windows", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood),
+			Availability: []database.DateRange{{From: day(20), To: day(31)}, {From: day(1), To: day(10)}},
+			Blackouts:    []database.DateRange{{From: day(5), To: day(5)}}},
+		{Name: "any", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: materials(database.FloorWood)},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+
+	// matched providers come with their availability
+	matched, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: location})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(matched)).To(Equal(providers))
+
+	// availability is replaced on update
+	providers[0].Availability = []database.DateRange{{From: day(15), To: day(15)}}
+	providers[0].Blackouts = nil
+	Expect(storage.UpdateProvider(ctx, providers[0])).To(BeNil())
+	res, err = storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+
+	// ranges ending before they start or not on whole days are invalid
+	invalid := providers[1]
+	invalid.Blackouts = []database.DateRange{{From: day(10), To: day(9)}}
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	invalid.Blackouts = nil
+	invalid.Availability = []database.DateRange{{From: day(10).Add(time.Hour), To: day(11)}}
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	res, err = storage.GetProvider(ctx, providers[1].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[1]))
+}
+
+func testWorkingHours(ctx context.Context, storage handlers.Storage) {
+	location := database.Address{Lat: 10, Long: 10}
+	providers := []database.Provider{
+		{Name: "hours", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood), TimeZone: "Africa/Maputo",
+			WorkingHours: []database.WorkingHours{{Weekday: time.Monday, Start: 8 * 60, End: 12 * 60}, {Weekday: time.Monday, Start: 13 * 60, End: 17 * 60}, {Weekday: time.Saturday, Start: 9 * 60, End: 24 * 60}}},
+		{Name: "none", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: materials(database.FloorWood)},
+	}
+	populate(ctx, storage, providers)
+	res, err := storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+	matched, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: location})
+	Expect(err).To(BeNil())
+	Expect(withoutDistance(matched)).To(Equal(providers))
+
+	// working hours are replaced on update
+	providers[0].TimeZone = ""
+	providers[0].WorkingHours = []database.WorkingHours{{Weekday: time.Sunday, Start: 0, End: 60}}
+	Expect(storage.UpdateProvider(ctx, providers[0])).To(BeNil())
+	res, err = storage.GetProvider(ctx, providers[0].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[0]))
+
+	// unknown time zones and hours ending before they start are invalid
+	invalid := providers[1]
+	invalid.TimeZone = "Mars/Olympus_Mons"
+	Expect(storage.UpdateProvider(ctx, invalid)).To(Equal(database.ErrInvalid))
+	invalid.TimeZone = ""
+	invalid.WorkingHours = []database.WorkingHours{{Weekday: time.Friday, Start: 17 * 60, End: 8 * 60}}
+	_, err = storage.AddProvider(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	res, err = storage.GetProvider(ctx, providers[1].ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(providers[1]))
+}
+
+func testProviderStatus(ctx context.Context, storage handlers.Storage) {
+	location := database.Address{Lat: 10, Long: 10}
+	providers := []database.Provider{
+		{Name: "pending", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood), Status: database.StatusPending},
+		{Name: "active", Address: location, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: materials(database.FloorWood)},
+	}
+	populate(ctx, storage, providers)
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	match := func(now time.Time) []database.ID {
+		res, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: location, Now: now})
+		Expect(err).To(BeNil())
+		ids := []database.ID{}
+		for _, p := range res {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	change := func(id database.ID, to database.ProviderStatus, actor database.Actor, resumeAt, at time.Time) (database.Provider, error) {
+		return storage.ChangeProviderStatus(ctx, database.StatusChange{ProviderID: id, To: to, Actor: actor, Reason: "test", ResumeAt: resumeAt, ChangedAt: at})
+	}
+	pending, active := providers[0].ID, providers[1].ID
+	Expect(match(now)).To(Equal([]database.ID{active}))
+
+	// providers can not verify themselves
+	_, err := change(pending, database.StatusActive, database.ActorProvider, time.Time{}, now)
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	p, err := change(pending, database.StatusActive, database.ActorAdmin, time.Time{}, now)
+	Expect(err).To(BeNil())
+	Expect(p.Status).To(Equal(database.StatusActive))
+	Expect(match(now)).To(Equal([]database.ID{pending, active}))
+
+	// a paused provider is matched again from its resume time, the resume is recorded with the next change
+	resumeAt := now.Add(48 * time.Hour)
+	p, err = change(active, database.StatusPaused, database.ActorProvider, resumeAt, now)
+	Expect(err).To(BeNil())
+	Expect(p.Status).To(Equal(database.StatusPaused))
+	Expect(p.ResumeAt).To(Equal(resumeAt))
+	Expect(match(now)).To(Equal([]database.ID{pending}))
+	Expect(match(resumeAt)).To(Equal([]database.ID{pending, active}))
+	_, err = change(active, database.StatusActive, database.ActorProvider, time.Time{}, resumeAt.Add(time.Hour))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	_, err = change(active, database.StatusSuspended, database.ActorProvider, time.Time{}, resumeAt.Add(time.Hour))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	p, err = change(active, database.StatusSuspended, database.ActorAdmin, time.Time{}, resumeAt.Add(time.Hour))
+	Expect(err).To(BeNil())
+	Expect(p.Status).To(Equal(database.StatusSuspended))
+	Expect(p.ResumeAt.IsZero()).To(BeTrue())
+	Expect(match(resumeAt.Add(time.Hour))).To(Equal([]database.ID{pending}))
+
+	// status is kept by updates
+	p.Name = "renamed"
+	Expect(storage.UpdateProvider(ctx, p)).To(BeNil())
+	saved, err := storage.GetProvider(ctx, active)
+	Expect(err).To(BeNil())
+	Expect(saved.Status).To(Equal(database.StatusSuspended))
+	p.Status = database.StatusActive
+	Expect(storage.UpdateProvider(ctx, p)).To(BeNil())
+	saved, err = storage.GetProvider(ctx, active)
+	Expect(err).To(BeNil())
+	Expect(saved.Status).To(Equal(database.StatusSuspended))
+
+	history, total, err := storage.GetStatusHistory(ctx, active, database.Page{Limit: 10})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	for i := range history {
+		Expect(history[i].ID).NotTo(BeZero())
+		history[i].ID = 0
+	}
+	Expect(history).To(Equal([]database.StatusChange{
+		{ProviderID: active, From: database.StatusActive, To: database.StatusSuspended, Actor: database.ActorAdmin, Reason: "test", ChangedAt: resumeAt.Add(time.Hour)},
+		{ProviderID: active, From: database.StatusPaused, To: database.StatusActive, Actor: database.ActorSystem, ChangedAt: resumeAt},
+		{ProviderID: active, From: database.StatusActive, To: database.StatusPaused, ResumeAt: resumeAt, Actor: database.ActorProvider, Reason: "test", ChangedAt: now},
+	}))
+	history, total, err = storage.GetStatusHistory(ctx, active, database.Page{Limit: 1, Offset: 2})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	Expect(history).To(HaveLen(1))
+	Expect(history[0].To).To(Equal(database.StatusPaused))
+
+	_, err = change(active, "deleted", database.ActorAdmin, time.Time{}, now)
+	Expect(errors.Is(err, database.ErrInvalid)).To(BeTrue())
+	_, err = change(active+pending, database.StatusActive, database.ActorAdmin, time.Time{}, now)
+	Expect(err).To(Equal(database.ErrNotFound))
+	_, _, err = storage.GetStatusHistory(ctx, active+pending, database.Page{Limit: 10})
+	Expect(err).To(Equal(database.ErrNotFound))
+}
+
+func testReviews(ctx context.Context, storage handlers.Storage) {
+	provider := database.Provider{Name: "p0", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 1, Materials: materials(database.FloorWood)}
+	id, err := storage.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	provider.ID = id
+	other := provider
+	other.Rating = 4.4
+	other.ID, err = storage.AddProvider(ctx, other)
+	Expect(err).To(BeNil())
+
+	reviews, total, err := storage.GetReviews(ctx, id, database.Page{Limit: 10})
+	Expect(err).To(BeNil())
+	Expect(total).To(BeZero())
+	Expect(reviews).To(BeEmpty())
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	added := []database.Review{
+		{ProviderID: id, Score: 5, Text: "great", CreatedAt: created},
+		{ProviderID: id, Score: 4, LeadID: 7, CreatedAt: created.Add(time.Hour)},
+		{ProviderID: id, Score: 4, Text: "good", CreatedAt: created.Add(time.Hour)},
+	}
+	for i := range added {
+		added[i].ID, err = storage.AddReview(ctx, added[i])
+		Expect(err).To(BeNil())
+	}
+
+	// rating is average score rounded to two decimals
+	res, err := storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res.Rating).To(Equal(4.33))
+	Expect(res.ReviewCount).To(Equal(3))
+	matched, err := storage.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: provider.Address})
+	Expect(err).To(BeNil())
+	Expect(matched).To(HaveLen(2))
+	Expect(matched[0].ID).To(Equal(other.ID))
+	Expect(matched[1].ID).To(Equal(id))
+	Expect(matched[1].ReviewCount).To(Equal(3))
+	Expect(matched[0].ReviewCount).To(BeZero())
+
+	// newest first, ties are broken by id
+	reviews, total, err = storage.GetReviews(ctx, id, database.Page{Limit: 2})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	Expect(reviews).To(Equal([]database.Review{added[2], added[1]}))
+	reviews, total, err = storage.GetReviews(ctx, id, database.Page{Limit: 2, Offset: 2})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	Expect(reviews).To(Equal([]database.Review{added[0]}))
+	reviews, _, err = storage.GetReviews(ctx, id, database.Page{Limit: 2, Offset: 5})
+	Expect(err).To(BeNil())
+	Expect(reviews).To(BeEmpty())
+
+	// rating and review count of a reviewed provider can not be overwritten
+	provider.Rating, provider.ReviewCount = 5, 0
+	Expect(storage.UpdateProvider(ctx, provider)).To(BeNil())
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res.Rating).To(Equal(4.33))
+	Expect(res.ReviewCount).To(Equal(3))
+
+	// a lead reviews a provider once, reviews without a lead are not limited
+	_, err = storage.AddReview(ctx, database.Review{ProviderID: id, Score: 1, LeadID: 7, CreatedAt: created.Add(2 * time.Hour)})
+	Expect(err).To(Equal(database.ErrDuplicateEntry))
+	_, err = storage.AddReview(ctx, database.Review{ProviderID: other.ID, Score: 3, LeadID: 7, CreatedAt: created})
+	Expect(err).To(BeNil())
+	res, err = storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res.Rating).To(Equal(4.33))
+	Expect(res.ReviewCount).To(Equal(3))
+
+	_, err = storage.AddReview(ctx, database.Review{ProviderID: id, Score: 6, CreatedAt: created})
+	Expect(errors.Is(err, database.ErrInvalid)).To(BeTrue())
+	_, err = storage.AddReview(ctx, database.Review{ProviderID: 999999, Score: 5, CreatedAt: created})
+	Expect(err).To(Equal(database.ErrNotFound))
+	_, _, err = storage.GetReviews(ctx, 999999, database.Page{Limit: 10})
+	Expect(err).To(Equal(database.ErrNotFound))
+
+	Expect(storage.DeleteProvider(ctx, id)).To(BeNil())
+	_, _, err = storage.GetReviews(ctx, id, database.Page{Limit: 10})
+	Expect(err).To(Equal(database.ErrNotFound))
+}
+
+func testLeads(ctx context.Context, storage handlers.Storage) {
+	provider := database.Provider{Name: "p0", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood)}
+	providerID, err := storage.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+
+	lead := database.Lead{
+		Material:    database.FloorWood,
+		Address:     database.Address{Lat: 10.01, Long: 9.99},
+		Area:        42.5,
+		PhoneNumber: "1-800-2000",
+		CreatedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ProviderIDs: []database.ID{providerID, providerID + 1000},
+	}
+	database.Routing{OfferCount: 1, OfferTTL: time.Hour}.Offer(&lead, lead.CreatedAt)
+	lead.ID, err = storage.AddLead(ctx, lead)
+	Expect(err).To(BeNil())
+	res, err := storage.GetLead(ctx, lead.ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(lead))
+
+	// shown providers are kept after they are deleted
+	Expect(storage.DeleteProvider(ctx, providerID)).To(BeNil())
+	res, err = storage.GetLead(ctx, lead.ID)
+	Expect(err).To(BeNil())
+	Expect(res.ProviderIDs).To(Equal(lead.ProviderIDs))
+
+	empty := lead
+	empty.ProviderIDs = []database.ID{}
+	database.Routing{OfferCount: 1, OfferTTL: time.Hour}.Offer(&empty, empty.CreatedAt)
+	Expect(empty.Status).To(Equal(database.LeadExpired))
+	empty.ID, err = storage.AddLead(ctx, empty)
+	Expect(err).To(BeNil())
+	Expect(empty.ID).NotTo(Equal(lead.ID))
+	res, err = storage.GetLead(ctx, empty.ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(empty))
+
+	invalid := lead
+	invalid.Material = "marble"
+	_, err = storage.AddLead(ctx, invalid)
+	Expect(err).To(Equal(database.ErrInvalid))
+	invalid = lead
+	invalid.Area = 0
+	_, err = storage.AddLead(ctx, invalid)
+	Expect(errors.Is(err, database.ErrInvalid)).To(BeTrue())
+	_, err = storage.GetLead(ctx, empty.ID+1000)
+	Expect(err).To(Equal(database.ErrNotFound))
+}
+
+func testLeadOffers(ctx context.Context, storage handlers.Storage) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	routing := database.Routing{OfferCount: 2, OfferTTL: time.Hour}
+	newLead := func(providerIDs ...database.ID) database.Lead {
+		lead := database.Lead{Material: database.FloorWood, Area: 10, PhoneNumber: "1-800-2000", CreatedAt: created, ProviderIDs: providerIDs}
+		routing.Offer(&lead, created)
+		id, err := storage.AddLead(ctx, lead)
+		Expect(err).To(BeNil())
+		lead.ID = id
+		return lead
+	}
+	expectStatus := func(id database.ID, status database.LeadStatus, offers ...database.OfferStatus) {
+		lead, err := storage.GetLead(ctx, id)
+		Expect(err).To(BeNil())
+		Expect(lead.Status).To(Equal(status))
+		Expect(lead.Offers).To(HaveLen(len(offers)))
+		for i, offer := range offers {
+			Expect(lead.Offers[i].Status).To(Equal(offer))
+		}
+	}
+
+	// lead is offered to top providers only, the first one to accept gets it
+	lead := newLead(1, 2, 3)
+	Expect(lead.Offers).To(Equal([]database.LeadOffer{
+		{ProviderID: 1, Status: database.OfferPending, ExpiresAt: created.Add(time.Hour)},
+		{ProviderID: 2, Status: database.OfferPending, ExpiresAt: created.Add(time.Hour)},
+	}))
+	_, err := storage.ApplyLeadAction(ctx, lead.ID, 3, database.ActionAccept, created)
+	Expect(errors.Is(err, database.ErrNotFound)).To(BeTrue())
+	res, err := storage.ApplyLeadAction(ctx, lead.ID, 2, database.ActionAccept, created.Add(time.Minute))
+	Expect(err).To(BeNil())
+	Expect(res.Status).To(Equal(database.LeadAccepted))
+	Expect(res.AcceptedBy()).To(Equal(database.ID(2)))
+	Expect(res.PhoneNumber).To(Equal(lead.PhoneNumber))
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 1, database.ActionAccept, created.Add(time.Minute))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	expectStatus(lead.ID, database.LeadAccepted, database.OfferWithdrawn, database.OfferAccepted)
+
+	// accepted lead moves through contacted to won, steps can not be skipped or repeated
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 2, database.ActionWin, created.Add(time.Minute))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 1, database.ActionContact, created.Add(time.Minute))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 2, database.ActionContact, created.Add(2*time.Hour))
+	Expect(err).To(BeNil())
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 2, database.ActionWin, created.Add(2*time.Hour))
+	Expect(err).To(BeNil())
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 2, database.ActionLose, created.Add(2*time.Hour))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	expectStatus(lead.ID, database.LeadWon, database.OfferWithdrawn, database.OfferAccepted)
+
+	// lead declined by everyone is expired
+	lead = newLead(1, 2)
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 1, database.ActionDecline, created)
+	Expect(err).To(BeNil())
+	expectStatus(lead.ID, database.LeadOffered, database.OfferDeclined, database.OfferPending)
+	res, err = storage.ApplyLeadAction(ctx, lead.ID, 2, database.ActionDecline, created)
+	Expect(err).To(BeNil())
+	Expect(res.Status).To(Equal(database.LeadExpired))
+	expectStatus(lead.ID, database.LeadExpired, database.OfferDeclined, database.OfferDeclined)
+
+	// unanswered offers expire, and expiry is saved by a failed action
+	lead = newLead(1, 2)
+	_, err = storage.ApplyLeadAction(ctx, lead.ID, 1, database.ActionAccept, created.Add(time.Hour))
+	Expect(errors.Is(err, database.ErrConflict)).To(BeTrue())
+	expectStatus(lead.ID, database.LeadExpired, database.OfferExpired, database.OfferExpired)
+
+	_, err = storage.ApplyLeadAction(ctx, lead.ID+1000, 1, database.ActionAccept, created)
+	Expect(err).To(Equal(database.ErrNotFound))
+}
+
+func testOfferedLeads(ctx context.Context, storage handlers.Storage) {
+	// provider ids are not used by other tests, so only leads added here are listed
+	const first, second, shown, none = database.ID(5001), database.ID(5002), database.ID(5003), database.ID(5004)
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	routing := database.Routing{OfferCount: 2, OfferTTL: time.Hour}
+	newLead := func(hour int, providerIDs ...database.ID) database.Lead {
+		lead := database.Lead{Material: database.FloorWood, Area: 10, PhoneNumber: "1-800-2000", CreatedAt: created.Add(time.Duration(hour) * time.Hour), ProviderIDs: providerIDs}
+		routing.Offer(&lead, lead.CreatedAt)
+		id, err := storage.AddLead(ctx, lead)
+		Expect(err).To(BeNil())
+		lead.ID = id
+		return lead
+	}
+	older := newLead(0, first, second, shown)
+	newer := newLead(2, first, shown)
+	middle := newLead(1, second, first)
+	accepted, err := storage.ApplyLeadAction(ctx, middle.ID, first, database.ActionAccept, middle.CreatedAt)
+	Expect(err).To(BeNil())
+
+	// leads offered to a provider are listed newest first with their saved offers
+	leads, total, err := storage.GetOfferedLeads(ctx, first, database.Page{Limit: 10})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	Expect(leads).To(Equal([]database.Lead{newer, accepted, older}))
+	leads, total, err = storage.GetOfferedLeads(ctx, first, database.Page{Limit: 1, Offset: 1})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	Expect(leads).To(Equal([]database.Lead{accepted}))
+	leads, total, err = storage.GetOfferedLeads(ctx, first, database.Page{Limit: 10, Offset: 3})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(3))
+	Expect(leads).To(BeEmpty())
+
+	// withdrawn offers are still listed
+	leads, total, err = storage.GetOfferedLeads(ctx, second, database.Page{Limit: 10})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(2))
+	Expect(leads).To(Equal([]database.Lead{accepted, older}))
+
+	// leads only shown to a provider are not listed, unknown providers have no leads
+	leads, total, err = storage.GetOfferedLeads(ctx, shown, database.Page{Limit: 10})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(1))
+	Expect(leads).To(Equal([]database.Lead{newer}))
+	leads, total, err = storage.GetOfferedLeads(ctx, none, database.Page{Limit: 10})
+	Expect(err).To(BeNil())
+	Expect(total).To(Equal(0))
+	Expect(leads).To(BeEmpty())
+}
+
+func testAppointments(ctx context.Context, storage handlers.Storage) {
+	providers := []database.Provider{
+		{Name: "p0", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: materials(database.FloorWood)},
+		{Name: "p1", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: materials(database.FloorWood)},
+	}
+	populate(ctx, storage, providers)
+	lead := database.Lead{Material: database.FloorWood, Address: database.Address{Lat: 10, Long: 10}, Area: 20, PhoneNumber: "1-800-2000", CreatedAt: time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC),
+		ProviderIDs: []database.ID{providers[0].ID, providers[1].ID}, Status: database.LeadOffered, Offers: []database.LeadOffer{}}
+	leadID, err := storage.AddLead(ctx, lead)
+	Expect(err).To(BeNil())
+
+	now := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return time.Date(2024, 7, 2, hour, 0, 0, 0, time.UTC)
+	}
+	book := func(providerID database.ID, hour int) (database.Appointment, error) {
+		a := database.Appointment{ProviderID: providerID, LeadID: leadID, CustomerName: "Ann", StartsAt: at(hour), EndsAt: at(hour + 1), Status: database.AppointmentBooked, CreatedAt: now, UpdatedAt: now}
+		var err error
+		a.ID, err = storage.BookAppointment(ctx, a)
+		return a, err
+	}
+	first, err := book(providers[0].ID, 10)
+	Expect(err).To(BeNil())
+	res, err := storage.GetAppointment(ctx, first.ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(first))
+
+	// overlapping appointments of a provider are conflicts, adjacent ones and ones of other providers are not
+	_, err = book(providers[0].ID, 10)
+	Expect(err).To(MatchError(database.ErrConflict))
+	overlapping := first
+	overlapping.StartsAt, overlapping.EndsAt = at(10).Add(30*time.Minute), at(11).Add(30*time.Minute)
+	_, err = storage.BookAppointment(ctx, overlapping)
+	Expect(err).To(MatchError(database.ErrConflict))
+	second, err := book(providers[0].ID, 11)
+	Expect(err).To(BeNil())
+	other, err := book(providers[1].ID, 10)
+	Expect(err).To(BeNil())
+	list, err := storage.GetAppointments(ctx, providers[0].ID, at(0), at(24))
+	Expect(err).To(BeNil())
+	Expect(list).To(Equal([]database.Appointment{first, second}))
+	list, err = storage.GetAppointments(ctx, providers[0].ID, at(11), at(12))
+	Expect(err).To(BeNil())
+	Expect(list).To(Equal([]database.Appointment{second}))
+
+	// unknown providers and leads are reported
+	_, err = book(providers[1].ID+1000, 10)
+	Expect(err).To(Equal(database.ErrNotFound))
+	unknownLead := first
+	unknownLead.LeadID, unknownLead.StartsAt, unknownLead.EndsAt = leadID+1000, at(15), at(16)
+	_, err = storage.BookAppointment(ctx, unknownLead)
+	Expect(err).To(Equal(database.ErrInvalid))
+	_, err = storage.GetAppointment(ctx, other.ID+1000)
+	Expect(err).To(Equal(database.ErrNotFound))
+	_, err = storage.GetAppointments(ctx, providers[1].ID+1000, at(0), at(24))
+	Expect(err).To(Equal(database.ErrNotFound))
+
+	// an appointment is rescheduled to a free time, it may overlap its own old time
+	later := now.Add(time.Hour)
+	_, err = storage.RescheduleAppointment(ctx, first.ID, at(11), at(12), later)
+	Expect(err).To(MatchError(database.ErrConflict))
+	res, err = storage.RescheduleAppointment(ctx, first.ID, at(9).Add(30*time.Minute), at(10).Add(30*time.Minute), later)
+	Expect(err).To(BeNil())
+	first.StartsAt, first.EndsAt, first.Sequence, first.UpdatedAt = at(9).Add(30*time.Minute), at(10).Add(30*time.Minute), 1, later
+	Expect(res).To(Equal(first))
+	res, err = storage.GetAppointment(ctx, first.ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(first))
+
+	// a canceled appointment frees its time and can not be changed again
+	res, err = storage.CancelAppointment(ctx, second.ID, later)
+	Expect(err).To(BeNil())
+	second.Status, second.Sequence, second.UpdatedAt = database.AppointmentCanceled, 1, later
+	Expect(res).To(Equal(second))
+	_, err = storage.CancelAppointment(ctx, second.ID, later)
+	Expect(err).To(MatchError(database.ErrConflict))
+	_, err = storage.RescheduleAppointment(ctx, second.ID, at(14), at(15), later)
+	Expect(err).To(MatchError(database.ErrConflict))
+	_, err = storage.CancelAppointment(ctx, second.ID+1000, later)
+	Expect(err).To(Equal(database.ErrNotFound))
+	third, err := book(providers[0].ID, 11)
+	Expect(err).To(BeNil())
+	list, err = storage.GetAppointments(ctx, providers[0].ID, at(0), at(24))
+	Expect(err).To(BeNil())
+	Expect(list).To(Equal([]database.Appointment{first, third}))
+	res, err = storage.GetAppointment(ctx, second.ID)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(second))
+
+	// appointments are removed with their provider
+	Expect(storage.DeleteProvider(ctx, providers[1].ID)).To(BeNil())
+	_, err = storage.GetAppointment(ctx, other.ID)
+	Expect(err).To(Equal(database.ErrNotFound))
+}
+
+func testCanceled(ctx context.Context, storage handlers.Storage) {
+	provider := database.Provider{Name: "p0", Address: database.Address{Lat: 10, Long: 10}, Radius: 10, RadiusUnit: database.Metre, Rating: 5, Materials: materials(database.FloorWood), Status: database.StatusActive}
+	id, err := storage.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	provider.ID = id
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = storage.GetProviders(canceled, database.Criteria{Material: database.FloorWood, Location: provider.Address})
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.GetProvider(canceled, id)
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.AddProvider(canceled, provider)
+	Expect(err).To(MatchError(context.Canceled))
+	Expect(storage.UpdateProvider(canceled, provider)).To(MatchError(context.Canceled))
+	Expect(storage.DeleteProvider(canceled, id)).To(MatchError(context.Canceled))
+	_, err = storage.GetMaterials(canceled)
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.AddReview(canceled, database.Review{ProviderID: id, Score: 1, CreatedAt: time.Now()})
+	Expect(err).To(MatchError(context.Canceled))
+	_, _, err = storage.GetReviews(canceled, id, database.Page{Limit: 10})
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.AddLead(canceled, database.Lead{Material: database.FloorWood, Area: 1, PhoneNumber: "1", CreatedAt: time.Now()})
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.GetLead(canceled, 1)
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.ApplyLeadAction(canceled, 1, id, database.ActionAccept, time.Now())
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.ChangeProviderStatus(canceled, database.StatusChange{ProviderID: id, To: database.StatusPaused, Actor: database.ActorProvider, ChangedAt: time.Now()})
+	Expect(err).To(MatchError(context.Canceled))
+	_, _, err = storage.GetStatusHistory(canceled, id, database.Page{Limit: 10})
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.BookAppointment(canceled, database.Appointment{ProviderID: id, LeadID: 1, StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour), Status: database.AppointmentBooked})
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.GetAppointment(canceled, 1)
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.GetAppointments(canceled, id, time.Now(), time.Now().Add(time.Hour))
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.RescheduleAppointment(canceled, 1, time.Now(), time.Now().Add(time.Hour), time.Now())
+	Expect(err).To(MatchError(context.Canceled))
+	_, err = storage.CancelAppointment(canceled, 1, time.Now())
+	Expect(err).To(MatchError(context.Canceled))
+
+	// nothing is changed by canceled calls
+	res, err := storage.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+	expired, cancel := context.WithTimeout(ctx, -time.Second)
+	defer cancel()
+	_, err = storage.GetProviders(expired, database.Criteria{Material: database.FloorWood, Location: provider.Address})
+	Expect(err).To(MatchError(context.DeadlineExceeded))
+}
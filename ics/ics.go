@@ -0,0 +1,104 @@
+// Package ics writes events as iCalendar files, see RFC 5545
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is media type of iCalendar files
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	prodID      = "-//ah//floors//EN"
+	timeLayout  = "20060102T150405Z"
+	maxLineSize = 75
+)
+
+// Geo is a location of an event
+type Geo struct {
+	Lat  float64
+	Long float64
+}
+
+// Event is a calendar event, times are written in UTC
+type Event struct {
+	// UID identifies event across its revisions
+	UID string
+	// Sequence is revision of event, calendars replace an event with one of a higher sequence
+	Sequence int
+	// Stamp is when this revision was made
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	// Geo is nil when event has no coordinates
+	Geo       *Geo
+	Cancelled bool
+}
+
+// Write writes a calendar with events to w
+func Write(w io.Writer, events ...Event) error {
+	b := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("SEQUENCE", fmt.Sprint(e.Sequence))
+		line("DTSTAMP", e.Stamp.UTC().Format(timeLayout))
+		line("DTSTART", e.Start.UTC().Format(timeLayout))
+		line("DTEND", e.End.UTC().Format(timeLayout))
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escape(e.Location))
+		}
+		if e.Geo != nil {
+			line("GEO", fmt.Sprintf("%f;%f", e.Geo.Lat, e.Geo.Long))
+		}
+		if e.Cancelled {
+			line("STATUS", "CANCELLED")
+		} else {
+			line("STATUS", "CONFIRMED")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return b.Flush()
+}
+
+// escape escapes special characters of a text value
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// writeLine writes a content line ended by CRLF, folded so no line is longer than 75 octets.
+// lines are only folded between characters, so multi-byte characters are kept whole
+func writeLine(w *bufio.Writer, line string) {
+	size := 0
+	for _, r := range line {
+		n := utf8.RuneLen(r)
+		if size+n > maxLineSize {
+			// a folded line starts with a space, which counts towards its size
+			_, _ = w.WriteString("\r\n ")
+			size = 1
+		}
+		_, _ = w.WriteRune(r)
+		size += n
+	}
+	_, _ = w.WriteString("\r\n")
+}
@@ -0,0 +1,69 @@
+package ics
+
+import (
+	"bytes"
+	. "github.com/onsi/gomega"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	RegisterTestingT(t)
+
+	start := time.Date(2024, 7, 1, 10, 0, 0, 0, time.FixedZone("CAT", 2*60*60))
+	event := Event{
+		UID:         "appointment-1@floors",
+		Sequence:    2,
+		Stamp:       time.Date(2024, 6, 20, 8, 30, 0, 0, time.UTC),
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Summary:     "Measurement; wood, 40 m²",
+		Description: "first line\nsecond line",
+		Geo:         &Geo{Lat: -26.66119, Long: 40.95858},
+	}
+	var b bytes.Buffer
+	Expect(Write(&b, event)).To(BeNil())
+	Expect(b.String()).To(Equal(strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//ah//floors//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VEVENT",
+		"UID:appointment-1@floors",
+		"SEQUENCE:2",
+		"DTSTAMP:20240620T083000Z",
+		"DTSTART:20240701T080000Z",
+		"DTEND:20240701T090000Z",
+		`SUMMARY:Measurement\; wood\, 40 m²`,
+		`DESCRIPTION:first line\nsecond line`,
+		"GEO:-26.661190;40.958580",
+		"STATUS:CONFIRMED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")))
+
+	event.Cancelled = true
+	b.Reset()
+	Expect(Write(&b, event)).To(BeNil())
+	Expect(b.String()).To(ContainSubstring("\r\nSTATUS:CANCELLED\r\n"))
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	RegisterTestingT(t)
+
+	event := Event{UID: "1", Summary: strings.Repeat("ü", 50)}
+	var b bytes.Buffer
+	Expect(Write(&b, event)).To(BeNil())
+	var unfolded []string
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		Expect(len(line)).To(BeNumerically("<=", 75))
+		if strings.HasPrefix(line, " ") {
+			unfolded[len(unfolded)-1] += line[1:]
+			continue
+		}
+		unfolded = append(unfolded, line)
+	}
+	Expect(unfolded).To(ContainElement("SUMMARY:" + strings.Repeat("ü", 50)))
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"ah/database"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BookAppointment saves a booked appointment, CreatedAt and UpdatedAt are set by caller.
+// ErrNotFound is returned for an unknown provider and ErrConflict when it overlaps another booked appointment of provider
+func (db *DataBase) BookAppointment(ctx context.Context, a database.Appointment) (database.ID, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := a.Validate(); err != nil {
+		return 0, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if _, ok := db.providers[a.ProviderID]; !ok {
+		return 0, database.ErrNotFound
+	}
+	if _, ok := db.leads[a.LeadID]; !ok {
+		return 0, database.ErrInvalid
+	}
+	a.ID = 0
+	if err := db.checkOverlap(a); err != nil {
+		return 0, err
+	}
+	db.lastAppointmentID++
+	a.ID = db.lastAppointmentID
+	a.StartsAt, a.EndsAt, a.CreatedAt, a.UpdatedAt = a.StartsAt.UTC(), a.EndsAt.UTC(), a.CreatedAt.UTC(), a.UpdatedAt.UTC()
+	db.appointments[a.ID] = a
+	return a.ID, nil
+}
+
+// GetAppointment returns an appointment by its id
+func (db *DataBase) GetAppointment(ctx context.Context, id database.ID) (database.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return database.Appointment{}, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	a, ok := db.appointments[id]
+	if !ok {
+		return database.Appointment{}, database.ErrNotFound
+	}
+	return a, nil
+}
+
+// GetAppointments returns booked appointments of a provider taking any time between from and to, ordered by start.
+// ErrNotFound is returned for an unknown provider
+func (db *DataBase) GetAppointments(ctx context.Context, providerID database.ID, from, to time.Time) ([]database.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	if _, ok := db.providers[providerID]; !ok {
+		return nil, database.ErrNotFound
+	}
+	res := []database.Appointment{}
+	for _, a := range db.appointments {
+		if a.ProviderID == providerID && a.Overlaps(from, to) {
+			res = append(res, a)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].StartsAt.Equal(res[j].StartsAt) {
+			return res[i].StartsAt.Before(res[j].StartsAt)
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// RescheduleAppointment moves a booked appointment to a new time at time now and saves the result, see Appointment.Reschedule.
+// ErrConflict is returned when new time overlaps another booked appointment of provider
+func (db *DataBase) RescheduleAppointment(ctx context.Context, id database.ID, start, end, now time.Time) (database.Appointment, error) {
+	return db.changeAppointment(ctx, id, func(a *database.Appointment) error {
+		if err := a.Reschedule(start, end, now); err != nil {
+			return err
+		}
+		return db.checkOverlap(*a)
+	})
+}
+
+// CancelAppointment cancels a booked appointment at time now and saves the result, see Appointment.Cancel
+func (db *DataBase) CancelAppointment(ctx context.Context, id database.ID, now time.Time) (database.Appointment, error) {
+	return db.changeAppointment(ctx, id, func(a *database.Appointment) error {
+		return a.Cancel(now)
+	})
+}
+
+// changeAppointment applies change to an appointment and saves the result, nothing is saved when change fails
+func (db *DataBase) changeAppointment(ctx context.Context, id database.ID, change func(a *database.Appointment) error) (database.Appointment, error) {
+	if err := ctx.Err(); err != nil {
+		return database.Appointment{}, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	a, ok := db.appointments[id]
+	if !ok {
+		return database.Appointment{}, database.ErrNotFound
+	}
+	if err := change(&a); err != nil {
+		return database.Appointment{}, err
+	}
+	db.appointments[id] = a
+	return a, nil
+}
+
+// checkOverlap returns ErrConflict when a overlaps another booked appointment of its provider
+func (db *DataBase) checkOverlap(a database.Appointment) error {
+	for _, other := range db.appointments {
+		if other.ProviderID == a.ProviderID && other.ID != a.ID && other.Overlaps(a.StartsAt, a.EndsAt) {
+			return fmt.Errorf("%w: provider has another appointment at that time", database.ErrConflict)
+		}
+	}
+	return nil
+}
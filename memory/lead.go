@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"ah/database"
+	"context"
+	"sort"
+	"time"
+)
+
+// AddLead saves a customer request with providers shown for it and offers made, CreatedAt is set by caller
+func (db *DataBase) AddLead(ctx context.Context, lead database.Lead) (database.ID, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := lead.Validate(); err != nil {
+		return 0, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if _, err := db.normalizeMaterials([]database.FloorMaterial{lead.Material}); err != nil {
+		return 0, err
+	}
+	db.lastLeadID++
+	lead.ID = db.lastLeadID
+	lead.CreatedAt = lead.CreatedAt.UTC()
+	lead = copyLead(lead)
+	for i := range lead.Offers {
+		lead.Offers[i].ExpiresAt = lead.Offers[i].ExpiresAt.UTC()
+	}
+	db.leads[lead.ID] = lead
+	return lead.ID, nil
+}
+
+// GetLead returns a lead with providers shown for it and its offers as saved, see Lead.Expire
+func (db *DataBase) GetLead(ctx context.Context, id database.ID) (database.Lead, error) {
+	if err := ctx.Err(); err != nil {
+		return database.Lead{}, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	lead, ok := db.leads[id]
+	if !ok {
+		return database.Lead{}, database.ErrNotFound
+	}
+	return copyLead(lead), nil
+}
+
+// GetOfferedLeads returns a page of leads offered to a provider, newest first, and total number of its offers.
+// leads are as saved, see Lead.Expire
+func (db *DataBase) GetOfferedLeads(ctx context.Context, providerID database.ID, page database.Page) ([]database.Lead, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	leads := []database.Lead{}
+	for _, lead := range db.leads {
+		if _, ok := lead.Offer(providerID); ok {
+			leads = append(leads, copyLead(lead))
+		}
+	}
+	sort.Slice(leads, func(i, j int) bool {
+		if !leads[i].CreatedAt.Equal(leads[j].CreatedAt) {
+			return leads[i].CreatedAt.After(leads[j].CreatedAt)
+		}
+		return leads[i].ID > leads[j].ID
+	})
+	total := len(leads)
+	start, end := page.Offset, page.Offset+page.Limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	return leads[start:end], total, nil
+}
+
+// ApplyLeadAction applies an action of a provider to a lead at time now and saves the result,
+// see Lead.Apply. Expired offers are saved as expired even when the action fails
+func (db *DataBase) ApplyLeadAction(ctx context.Context, id, providerID database.ID, action database.LeadAction, now time.Time) (database.Lead, error) {
+	if err := ctx.Err(); err != nil {
+		return database.Lead{}, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	lead, ok := db.leads[id]
+	if !ok {
+		return database.Lead{}, database.ErrNotFound
+	}
+	lead = copyLead(lead)
+	err := lead.Apply(providerID, action, now)
+	db.leads[id] = lead
+	if err != nil {
+		return database.Lead{}, err
+	}
+	return copyLead(lead), nil
+}
+
+func copyLead(lead database.Lead) database.Lead {
+	lead.ProviderIDs = append([]database.ID{}, lead.ProviderIDs...)
+	lead.Offers = append([]database.LeadOffer{}, lead.Offers...)
+	return lead
+}
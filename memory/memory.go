@@ -0,0 +1,465 @@
+package memory
+
+import (
+	"ah/database"
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// earthRadius is mean radius of earth in meters, same value used by mysql st_distance_sphere
+const earthRadius = 6370986
+
+// DataBase implements Storage interface in memory, intended for development and tests
+type DataBase struct {
+	lock      sync.RWMutex
+	lastID    database.ID
+	providers map[database.ID]database.Provider
+	materials []database.Material
+	// reviews are kept by provider id in order they were added
+	reviews      map[database.ID][]database.Review
+	lastReviewID database.ID
+	leads        map[database.ID]database.Lead
+	lastLeadID   database.ID
+	// statusChanges are kept by provider id in order they were made
+	statusChanges      map[database.ID][]database.StatusChange
+	lastStatusChangeID database.ID
+	appointments       map[database.ID]database.Appointment
+	lastAppointmentID  database.ID
+}
+
+// New creates an empty in-memory storage with default material catalogue
+func New() *DataBase {
+	return &DataBase{
+		providers:     map[database.ID]database.Provider{},
+		reviews:       map[database.ID][]database.Review{},
+		leads:         map[database.ID]database.Lead{},
+		statusChanges: map[database.ID][]database.StatusChange{},
+		appointments:  map[database.ID]database.Appointment{},
+		materials: []database.Material{
+			{ID: 1, Name: database.FloorWood},
+			{ID: 2, Name: database.FloorCarpet},
+			{ID: 3, Name: database.FloorTile},
+		},
+	}
+}
+
+// Clear remove all data from storage
+func (db *DataBase) Clear() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.providers = map[database.ID]database.Provider{}
+	db.reviews = map[database.ID][]database.Review{}
+	db.leads = map[database.ID]database.Lead{}
+	db.statusChanges = map[database.ID][]database.StatusChange{}
+	db.appointments = map[database.ID]database.Appointment{}
+	return nil
+}
+
+// GetProviders get a list of providers matching the criteria, ordered by rating first then distance, ties are broken by id
+func (db *DataBase) GetProviders(ctx context.Context, criteria database.Criteria) ([]database.Provider, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	res := []database.Provider{}
+	for _, p := range db.providers {
+		if p.StatusAt(criteria.Now) != database.StatusActive || !p.HasMaterial(criteria.Material) {
+			continue
+		}
+		p = p.ForMaterial(criteria.Material)
+		if criteria.Area > 0 && !p.JobAreaFor(criteria.Material).Covers(criteria.Area) {
+			continue
+		}
+		distance := Distance(criteria.Location, p.Address)
+		covered := p.Covers(criteria.Location, distance)
+		// the closest of covering branches is reported, an address wins a tie with its branches
+		var closest *database.Branch
+		for i, branch := range p.Branches {
+			branchDistance := Distance(criteria.Location, branch.Address)
+			if branch.Covers(branchDistance) && (!covered || branchDistance < distance) {
+				covered, distance, closest = true, branchDistance, &p.Branches[i]
+			}
+		}
+		if !covered {
+			continue
+		}
+		p = copyProvider(p)
+		p.Distance = distance
+		if closest != nil {
+			branch := *closest
+			p.ClosestBranch = &branch
+		}
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Rating != res[j].Rating {
+			return res[i].Rating > res[j].Rating
+		}
+		if res[i].Distance != res[j].Distance {
+			return res[i].Distance < res[j].Distance
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// GetProvider get a single provider by its id
+func (db *DataBase) GetProvider(ctx context.Context, id database.ID) (database.Provider, error) {
+	if err := ctx.Err(); err != nil {
+		return database.Provider{}, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	p, ok := db.providers[id]
+	if !ok {
+		return database.Provider{}, database.ErrNotFound
+	}
+	return copyProvider(p), nil
+}
+
+// AddProvider adds a new provider
+func (db *DataBase) AddProvider(ctx context.Context, p database.Provider) (database.ID, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return db.addProvider(p)
+}
+
+// UpdateProvider replaces all fields of an existing provider but its status, see ChangeProviderStatus
+func (db *DataBase) UpdateProvider(ctx context.Context, p database.Provider) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return db.updateProvider(p)
+}
+
+// ImportProviders saves a batch of providers, a failing provider is reported in its result without affecting others.
+// with upsert a provider with an existing external id is updated instead of added
+func (db *DataBase) ImportProviders(ctx context.Context, providers []database.Provider, options database.ImportOptions) ([]database.ImportResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if options.DryRun {
+		// changes are made on a copy and thrown away
+		saved, lastID := db.providers, db.lastID
+		savedChanges, lastChangeID := db.statusChanges, db.lastStatusChangeID
+		db.providers = make(map[database.ID]database.Provider, len(saved))
+		for id, p := range saved {
+			db.providers[id] = p
+		}
+		db.statusChanges = make(map[database.ID][]database.StatusChange, len(savedChanges))
+		for id, changes := range savedChanges {
+			db.statusChanges[id] = changes
+		}
+		defer func() {
+			db.providers, db.lastID = saved, lastID
+			db.statusChanges, db.lastStatusChangeID = savedChanges, lastChangeID
+		}()
+	}
+	results := make([]database.ImportResult, len(providers))
+	for i, p := range providers {
+		if err := p.Validate(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if id, ok := db.findExternalID(p.ExternalID); ok && options.Upsert {
+			p.ID = id
+			change, changed := p.ImportStatus(db.providers[id], options.Now)
+			results[i].Err = db.updateProvider(p)
+			results[i].ID, results[i].Updated = id, results[i].Err == nil
+			if results[i].Err == nil && changed {
+				db.setStatus(p, change)
+			}
+			continue
+		}
+		change, _ := p.ImportStatus(database.Provider{}, options.Now)
+		results[i].ID, results[i].Err = db.addProvider(p)
+		if results[i].Err == nil {
+			change.ProviderID = results[i].ID
+			db.recordStatusChanges(change)
+		}
+	}
+	return results, nil
+}
+
+func (db *DataBase) addProvider(p database.Provider) (database.ID, error) {
+	if !p.RadiusUnit.Valid() || !p.JobArea.Valid() || p.ServiceArea.Validate() != nil || !validBranches(p) || !validAvailability(p) || !validSchedule(p) {
+		return 0, database.ErrInvalid
+	}
+	if p.Status == "" {
+		p.Status = database.StatusActive
+	}
+	if !p.Status.Valid() {
+		return 0, database.ErrInvalid
+	}
+	materials, err := db.normalizeMaterials(p.Materials)
+	if err != nil {
+		return 0, err
+	}
+	p.Materials = materials
+	if err := checkMaterials(p); err != nil {
+		return 0, err
+	}
+	if _, ok := db.findExternalID(p.ExternalID); ok {
+		return 0, database.ErrDuplicateEntry
+	}
+	db.lastID++
+	p.ID = db.lastID
+	p = copyProvider(p)
+	p.ReviewCount = 0
+	db.providers[p.ID] = p
+	return p.ID, nil
+}
+
+func (db *DataBase) updateProvider(p database.Provider) error {
+	saved, ok := db.providers[p.ID]
+	if !ok {
+		return database.ErrNotFound
+	}
+	// status is only changed by ChangeProviderStatus
+	p.Status, p.ResumeAt = saved.Status, saved.ResumeAt
+	if !p.RadiusUnit.Valid() || !p.JobArea.Valid() || p.ServiceArea.Validate() != nil || !validBranches(p) || !validAvailability(p) || !validSchedule(p) {
+		return database.ErrInvalid
+	}
+	materials, err := db.normalizeMaterials(p.Materials)
+	if err != nil {
+		return err
+	}
+	p.Materials = materials
+	if err := checkMaterials(p); err != nil {
+		return err
+	}
+	if id, ok := db.findExternalID(p.ExternalID); ok && id != p.ID {
+		return database.ErrDuplicateEntry
+	}
+	p = copyProvider(p)
+	p.ReviewCount = len(db.reviews[p.ID])
+	db.providers[p.ID] = p
+	db.updateRating(p.ID)
+	return nil
+}
+
+// findExternalID returns id of provider with the given external id, empty external ids never match
+func (db *DataBase) findExternalID(externalID string) (database.ID, bool) {
+	if externalID == "" {
+		return 0, false
+	}
+	for id, p := range db.providers {
+		if p.ExternalID == externalID {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// ExportProviders calls f for every provider ordered by id, an error returned by f stops the export
+func (db *DataBase) ExportProviders(ctx context.Context, f func(database.Provider) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.lock.RLock()
+	providers := make([]database.Provider, 0, len(db.providers))
+	for _, p := range db.providers {
+		providers = append(providers, copyProvider(p))
+	}
+	db.lock.RUnlock()
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].ID < providers[j].ID
+	})
+	for _, p := range providers {
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteProvider removes a provider
+func (db *DataBase) DeleteProvider(ctx context.Context, id database.ID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if _, ok := db.providers[id]; !ok {
+		return database.ErrNotFound
+	}
+	delete(db.providers, id)
+	delete(db.reviews, id)
+	delete(db.statusChanges, id)
+	for appointmentID, a := range db.appointments {
+		if a.ProviderID == id {
+			delete(db.appointments, appointmentID)
+		}
+	}
+	return nil
+}
+
+// GetMaterials get all materials in catalogue
+func (db *DataBase) GetMaterials(ctx context.Context) ([]database.Material, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	res := make([]database.Material, len(db.materials))
+	copy(res, db.materials)
+	return res, nil
+}
+
+// normalizeMaterials removes duplicates and orders materials the way catalogue does
+func (db *DataBase) normalizeMaterials(materials []database.FloorMaterial) ([]database.FloorMaterial, error) {
+	requested := map[database.FloorMaterial]bool{}
+	for _, material := range materials {
+		requested[material] = true
+	}
+	var res []database.FloorMaterial
+	for _, material := range db.materials {
+		if requested[material.Name] {
+			res = append(res, material.Name)
+			delete(requested, material.Name)
+		}
+	}
+	if len(requested) != 0 {
+		return nil, database.ErrInvalid
+	}
+	return res, nil
+}
+
+// checkMaterials makes sure rates, valid job areas, radii and ratings are only given for materials of provider
+func checkMaterials(p database.Provider) error {
+	for material := range p.Rates {
+		if !p.HasMaterial(material) {
+			return database.ErrInvalid
+		}
+	}
+	for material, area := range p.MaterialJobAreas {
+		if !p.HasMaterial(material) || !area.Valid() {
+			return database.ErrInvalid
+		}
+	}
+	for material, radius := range p.MaterialRadii {
+		if !p.HasMaterial(material) || !(radius.Radius > 0) || !radius.RadiusUnit.Valid() {
+			return database.ErrInvalid
+		}
+	}
+	for material, rating := range p.MaterialRatings {
+		if !p.HasMaterial(material) || rating < 0 || rating > 5 {
+			return database.ErrInvalid
+		}
+	}
+	return nil
+}
+
+func validBranches(p database.Provider) bool {
+	for _, branch := range p.Branches {
+		if branch.Validate() != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func validAvailability(p database.Provider) bool {
+	for _, ranges := range [][]database.DateRange{p.Availability, p.Blackouts} {
+		for _, r := range ranges {
+			if !r.Valid() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validSchedule(p database.Provider) bool {
+	if _, err := p.Location(); err != nil || len(p.TimeZone) > 64 {
+		return false
+	}
+	for _, h := range p.WorkingHours {
+		if !h.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+func copyProvider(p database.Provider) database.Provider {
+	if p.Materials != nil {
+		materials := make([]database.FloorMaterial, len(p.Materials))
+		copy(materials, p.Materials)
+		p.Materials = materials
+	}
+	if p.Rates != nil {
+		rates := make(map[database.FloorMaterial]float64, len(p.Rates))
+		for material, rate := range p.Rates {
+			rates[material] = rate
+		}
+		p.Rates = rates
+	}
+	if p.MaterialJobAreas != nil {
+		areas := make(map[database.FloorMaterial]database.AreaRange, len(p.MaterialJobAreas))
+		for material, area := range p.MaterialJobAreas {
+			areas[material] = area
+		}
+		p.MaterialJobAreas = areas
+	}
+	if p.MaterialRadii != nil {
+		radii := make(map[database.FloorMaterial]database.MaterialRadius, len(p.MaterialRadii))
+		for material, radius := range p.MaterialRadii {
+			radii[material] = radius
+		}
+		p.MaterialRadii = radii
+	}
+	if p.MaterialRatings != nil {
+		ratings := make(map[database.FloorMaterial]float64, len(p.MaterialRatings))
+		for material, rating := range p.MaterialRatings {
+			ratings[material] = rating
+		}
+		p.MaterialRatings = ratings
+	}
+	if p.ServiceArea != nil {
+		area := make(database.ServiceArea, len(p.ServiceArea))
+		for i, polygon := range p.ServiceArea {
+			area[i] = make(database.Polygon, len(polygon))
+			for j, ring := range polygon {
+				area[i][j] = append([]database.Address(nil), ring...)
+			}
+		}
+		p.ServiceArea = area
+	}
+	if p.Branches != nil {
+		p.Branches = append([]database.Branch(nil), p.Branches...)
+	}
+	if p.Availability != nil {
+		p.Availability = append([]database.DateRange(nil), p.Availability...)
+	}
+	if p.Blackouts != nil {
+		p.Blackouts = append([]database.DateRange(nil), p.Blackouts...)
+	}
+	if p.WorkingHours != nil {
+		p.WorkingHours = append([]database.WorkingHours(nil), p.WorkingHours...)
+	}
+	// closest branch is only set for matched providers
+	p.ClosestBranch = nil
+	return p
+}
+
+// Distance returns great-circle distance between two locations in meters
+func Distance(a, b database.Address) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLong := (b.Long - a.Long) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLong/2)*math.Sin(dLong/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
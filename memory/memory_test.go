@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"ah/database"
+	"ah/database/storagetest"
+	"ah/server/handlers"
+	"context"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+var _ handlers.Storage = (*DataBase)(nil)
+
+func TestDistance(t *testing.T) {
+	RegisterTestingT(t)
+	Expect(Distance(database.Address{Lat: 10, Long: 20}, database.Address{Lat: 10, Long: 20})).To(BeZero())
+	// one degree of latitude
+	Expect(Distance(database.Address{Lat: 0, Long: 0}, database.Address{Lat: 1, Long: 0})).To(BeNumerically("~", 111195, 10))
+	// one degree of longitude shrinks with latitude
+	Expect(Distance(database.Address{Lat: 60, Long: 0}, database.Address{Lat: 60, Long: 1})).To(BeNumerically("~", 55597, 10))
+	// shortest path crosses the antimeridian
+	Expect(Distance(database.Address{Lat: 0, Long: 179.5}, database.Address{Lat: 0, Long: -179.5})).To(BeNumerically("~", 111195, 10))
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) handlers.Storage {
+		return New()
+	})
+}
+
+func TestReturnedProvidersAreCopies(t *testing.T) {
+	RegisterTestingT(t)
+	db := New()
+	ctx := context.Background()
+	provider := database.Provider{
+		Name:       "p0",
+		Address:    database.Address{Lat: -26.66119, Long: 40.95858},
+		Radius:     10,
+		RadiusUnit: database.Metre,
+		Rating:     4,
+		Materials:  []database.FloorMaterial{database.FloorWood},
+		Status:     database.StatusActive,
+	}
+	id, err := db.AddProvider(ctx, provider)
+	Expect(err).To(BeNil())
+	provider.ID = id
+
+	res, err := db.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	res.Materials[0] = database.FloorCarpet
+	matches, err := db.GetProviders(ctx, database.Criteria{Material: database.FloorWood, Location: provider.Address})
+	Expect(err).To(BeNil())
+	matches[0].Materials[0] = database.FloorTile
+	res, err = db.GetProvider(ctx, id)
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(provider))
+}
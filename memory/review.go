@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"ah/database"
+	"context"
+	"sort"
+)
+
+// AddReview adds a review and recomputes rating of its provider, CreatedAt is set by caller.
+// a lead reviews a provider only once
+func (db *DataBase) AddReview(ctx context.Context, r database.Review) (database.ID, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := r.Validate(); err != nil {
+		return 0, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if _, ok := db.providers[r.ProviderID]; !ok {
+		return 0, database.ErrNotFound
+	}
+	for _, other := range db.reviews[r.ProviderID] {
+		if r.LeadID != 0 && other.LeadID == r.LeadID {
+			return 0, database.ErrDuplicateEntry
+		}
+	}
+	db.lastReviewID++
+	r.ID = db.lastReviewID
+	r.CreatedAt = r.CreatedAt.UTC()
+	db.reviews[r.ProviderID] = append(db.reviews[r.ProviderID], r)
+	db.updateRating(r.ProviderID)
+	return r.ID, nil
+}
+
+// GetReviews returns a page of reviews of a provider, newest first, and total number of its reviews
+func (db *DataBase) GetReviews(ctx context.Context, providerID database.ID, page database.Page) ([]database.Review, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	if _, ok := db.providers[providerID]; !ok {
+		return nil, 0, database.ErrNotFound
+	}
+	reviews := append([]database.Review{}, db.reviews[providerID]...)
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].ID > reviews[j].ID
+	})
+	total := len(reviews)
+	start, end := page.Offset, page.Offset+page.Limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	return reviews[start:end], total, nil
+}
+
+// updateRating sets rating and review count of a reviewed provider from its reviews,
+// providers without reviews keep the rating they were given
+func (db *DataBase) updateRating(providerID database.ID) {
+	reviews := db.reviews[providerID]
+	if len(reviews) == 0 {
+		return
+	}
+	sum := 0
+	for _, r := range reviews {
+		sum += r.Score
+	}
+	p := db.providers[providerID]
+	p.Rating = database.AverageScore(sum, len(reviews))
+	p.ReviewCount = len(reviews)
+	db.providers[providerID] = p
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"ah/database"
+	"context"
+	"sort"
+)
+
+// ChangeProviderStatus moves a provider to status of change and records the change, see Provider.ChangeStatus.
+// ChangedAt is set by caller, nothing is saved when the change fails
+func (db *DataBase) ChangeProviderStatus(ctx context.Context, change database.StatusChange) (database.Provider, error) {
+	if err := ctx.Err(); err != nil {
+		return database.Provider{}, err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	p, ok := db.providers[change.ProviderID]
+	if !ok {
+		return database.Provider{}, database.ErrNotFound
+	}
+	change.ChangedAt = change.ChangedAt.UTC()
+	change.ResumeAt = change.ResumeAt.UTC()
+	changes, err := p.ChangeStatus(change)
+	if err != nil {
+		return database.Provider{}, err
+	}
+	db.recordStatusChanges(changes...)
+	db.providers[p.ID] = p
+	return copyProvider(p), nil
+}
+
+// setStatus sets status of a saved provider to one of p and records changes made to it
+func (db *DataBase) setStatus(p database.Provider, changes ...database.StatusChange) {
+	saved := db.providers[p.ID]
+	saved.Status, saved.ResumeAt = p.Status, p.ResumeAt.UTC()
+	db.providers[p.ID] = saved
+	db.recordStatusChanges(changes...)
+}
+
+// recordStatusChanges adds changes to status history
+func (db *DataBase) recordStatusChanges(changes ...database.StatusChange) {
+	for _, c := range changes {
+		db.lastStatusChangeID++
+		c.ID = db.lastStatusChangeID
+		c.ChangedAt, c.ResumeAt = c.ChangedAt.UTC(), c.ResumeAt.UTC()
+		db.statusChanges[c.ProviderID] = append(db.statusChanges[c.ProviderID], c)
+	}
+}
+
+// GetStatusHistory returns a page of status changes of a provider, newest first, and total number of its changes
+func (db *DataBase) GetStatusHistory(ctx context.Context, providerID database.ID, page database.Page) ([]database.StatusChange, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	if _, ok := db.providers[providerID]; !ok {
+		return nil, 0, database.ErrNotFound
+	}
+	changes := append([]database.StatusChange{}, db.statusChanges[providerID]...)
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].ChangedAt.Equal(changes[j].ChangedAt) {
+			return changes[i].ChangedAt.After(changes[j].ChangedAt)
+		}
+		return changes[i].ID > changes[j].ID
+	})
+	total := len(changes)
+	start, end := page.Offset, page.Offset+page.Limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	return changes[start:end], total, nil
+}
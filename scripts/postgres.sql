@@ -0,0 +1,7 @@
+-- creates floor database for postgres backend, run as a superuser:
+-- psql -U postgres -f ./scripts/postgres.sql
+-- tables are created by migrations, postgis extension is created here since it needs superuser privileges
+CREATE USER flooruser WITH PASSWORD 'floorpass';
+CREATE DATABASE floor OWNER flooruser;
+\connect floor
+CREATE EXTENSION IF NOT EXISTS postgis;
@@ -2,10 +2,22 @@ USE `floor` ;
 
 DELETE FROM Provider ;
 
-INSERT INTO Provider VALUES (NULL, 'provider1', ST_GeomFromText('POINT(-26.66119 40.95858)'), 10.0, 3.5, 1, 1, 1);
-INSERT INTO Provider VALUES (NULL, 'provider2', ST_GeomFromText('POINT(-26.66120 40.95858)'), 10.0, 4.5, 0, 1, 1);
-INSERT INTO Provider VALUES (NULL, 'provider3', ST_GeomFromText('POINT(-26.66116 40.95858)'), 10.0, 4.5, 1, 0, 0);
-INSERT INTO Provider VALUES (NULL, 'provider4', ST_GeomFromText('POINT(-26.66117 40.95858)'), 10.0, 4.7, 1, 1, 0);
-INSERT INTO Provider VALUES (NULL, 'provider5', ST_GeomFromText('POINT(-26.66115 40.95858)'), 10.0, 4.5, 1, 0, 0);
-INSERT INTO Provider VALUES (NULL, 'provider6', ST_GeomFromText('POINT(-26.66118 40.95858)'), 2.0, 4.1, 1, 0, 1);
-INSERT INTO Provider VALUES (NULL, 'provider7', ST_GeomFromText('POINT(-26.66116 40.95858)'), 10.0, 4.8, 1, 0, 0);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (1, 'provider1', ST_GeomFromText('POINT(-26.66119 40.95858)', 4326), 10.0, 'km', 3.5);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (2, 'provider2', ST_GeomFromText('POINT(-26.66120 40.95858)', 4326), 10.0, 'km', 4.5);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (3, 'provider3', ST_GeomFromText('POINT(-26.66116 40.95858)', 4326), 10.0, 'km', 4.5);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (4, 'provider4', ST_GeomFromText('POINT(-26.66117 40.95858)', 4326), 10.0, 'km', 4.7);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (5, 'provider5', ST_GeomFromText('POINT(-26.66115 40.95858)', 4326), 10.0, 'km', 4.5);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (6, 'provider6', ST_GeomFromText('POINT(-26.66118 40.95858)', 4326), 2.0, 'km', 4.1);
+INSERT INTO Provider (Id, Name, Address, Radius, RadiusUnit, Rating) VALUES (7, 'provider7', ST_GeomFromText('POINT(-26.66116 40.95858)', 4326), 10.0, 'km', 4.8);
+
+INSERT INTO ProviderMaterial (ProviderId, MaterialId)
+SELECT p.Id, m.Id FROM Provider p JOIN Material m
+WHERE (p.Name, m.Name) IN (
+    ('provider1', 'wood'), ('provider1', 'carpet'), ('provider1', 'tile'),
+    ('provider2', 'carpet'), ('provider2', 'tile'),
+    ('provider3', 'wood'),
+    ('provider4', 'wood'), ('provider4', 'carpet'),
+    ('provider5', 'wood'),
+    ('provider6', 'wood'), ('provider6', 'tile'),
+    ('provider7', 'wood')
+);
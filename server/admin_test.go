@@ -0,0 +1,219 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type importReport struct {
+	Rows    int  `json:"rows"`
+	Created int  `json:"created"`
+	Updated int  `json:"updated"`
+	Failed  int  `json:"failed"`
+	DryRun  bool `json:"dry_run"`
+	Errors  []struct {
+		Row        int    `json:"row"`
+		ExternalID string `json:"external_id"`
+		Error      string `json:"error"`
+	} `json:"errors"`
+}
+
+func sendImportRequest(query string, body string) (importReport, int) {
+	resp := execTokenRequest(testAdminToken, http.MethodPost, "/v1/admin/providers/import"+query, body)
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	Expect(resp.Body.Close()).To(BeNil())
+	response := handlers.Response{
+		Data: &importReport{},
+	}
+	err = json.Unmarshal(respBody, &response)
+	Expect(err).To(BeNil())
+	return *response.Data.(*importReport), resp.StatusCode
+}
+
+func TestImportProviders(t *testing.T) {
+	initTest(t, nil)
+	var imported []database.Provider
+	db.ImportProvidersFunc = func(providers []database.Provider, options database.ImportOptions) ([]database.ImportResult, error) {
+		Expect(options.Now).To(BeTemporally("~", time.Now(), time.Minute))
+		options.Now = time.Time{}
+		Expect(options).To(Equal(database.ImportOptions{Upsert: true}))
+		imported = append(imported, providers...)
+		return []database.ImportResult{{ID: 1}, {ID: 2, Updated: true}}, nil
+	}
+	body := `external_id,name,lat,long,operating_radius,experience
+e1,p1,-26.66119,40.95858,10,wood;tile
+e2,p2,-26.66119,40.95858,5,wood
+e3,,-26.66119,40.95858,5,wood
+`
+	report, status := sendImportRequest("?format=csv&upsert=true", body)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(imported).To(HaveLen(2))
+	Expect(imported[0].ExternalID).To(Equal("e1"))
+	Expect(imported[0].Materials).To(Equal([]database.FloorMaterial{database.FloorWood, database.FloorTile}))
+	Expect(report.Rows).To(Equal(3))
+	Expect(report.Created).To(Equal(1))
+	Expect(report.Updated).To(Equal(1))
+	Expect(report.Failed).To(Equal(1))
+	Expect(report.Errors).To(HaveLen(1))
+	Expect(report.Errors[0].Row).To(Equal(3))
+	Expect(report.Errors[0].ExternalID).To(Equal("e3"))
+}
+
+func TestImportProvidersMalformed(t *testing.T) {
+	initTest(t, nil)
+	db.ImportProvidersFunc = func(providers []database.Provider, _ database.ImportOptions) ([]database.ImportResult, error) {
+		return make([]database.ImportResult, len(providers)), nil
+	}
+	_, status := sendImportRequest("?format=xml", "")
+	Expect(status).To(Equal(http.StatusBadRequest))
+	_, status = sendImportRequest("?dry_run=maybe", "[]")
+	Expect(status).To(Equal(http.StatusBadRequest))
+	_, status = sendImportRequest("?format=csv", "name,lat\n")
+	Expect(status).To(Equal(http.StatusBadRequest))
+	_, status = sendImportRequest("", `[{"name":"p1","address":{"lat":1,"long":1},"operating_radius":1}`)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestAdminAuth(t *testing.T) {
+	RegisterTestingT(t)
+	router := gin.New()
+	router.GET("/", handlers.AdminAuth("secret"), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	for header, status := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer secret": http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(status), header)
+	}
+}
+
+func TestAdminAuthWithoutToken(t *testing.T) {
+	RegisterTestingT(t)
+	router := gin.New()
+	router.GET("/", handlers.AdminAuth(""), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	for _, header := range []string{"", "Bearer", "Bearer ", "Bearer secret"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable), header)
+	}
+}
+
+func TestProviderWritesNeedAdmin(t *testing.T) {
+	initTest(t, nil)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/v1/admin/providers"},
+		{http.MethodPut, "/v1/admin/providers/2"},
+		{http.MethodPatch, "/v1/admin/providers/2"},
+		{http.MethodDelete, "/v1/admin/providers/2"},
+	} {
+		for _, token := range []string{"", providerToken(2)} {
+			resp := execTokenRequest(token, route.method, route.path, "{}")
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized), route.method+" "+route.path)
+		}
+	}
+	// providers are no longer changed through public paths
+	resp := execTokenRequest(testAdminToken, http.MethodDelete, "/v1/providers/2", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	resp = execRequest(http.MethodPost, "/v1/providers", "{}")
+	Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+}
+
+func TestIssueProviderToken(t *testing.T) {
+	initTest(t, nil)
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		if id != 9 {
+			return database.Provider{}, database.ErrNotFound
+		}
+		return database.Provider{ID: 9}, nil
+	}
+	var token handlers.ProviderToken
+	status := sendTokenDataRequest(testAdminToken, http.MethodPost, "/v1/admin/providers/9/token", "", &token)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(token).To(Equal(handlers.ProviderToken{ProviderID: 9, Token: providerToken(9)}))
+
+	status = sendTokenDataRequest(testAdminToken, http.MethodPost, "/v1/admin/providers/8/token", "", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+	status = sendDataRequest(http.MethodPost, "/v1/admin/providers/9/token", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+}
+
+func TestSigner(t *testing.T) {
+	RegisterTestingT(t)
+	signer := handlers.NewSigner("secret")
+	token := signer.Sign(handlers.ScopeProvider, 9)
+	id, ok := signer.Verify(handlers.ScopeProvider, token)
+	Expect(ok).To(BeTrue())
+	Expect(id).To(Equal(database.ID(9)))
+
+	for _, token := range []string{"", "9", "9.", "x." + token[2:], "8." + token[2:], token + "0", handlers.NewSigner("other").Sign(handlers.ScopeProvider, 9)} {
+		_, ok = signer.Verify(handlers.ScopeProvider, token)
+		Expect(ok).To(BeFalse(), token)
+	}
+	// no token is valid without a secret
+	_, ok = handlers.NewSigner("").Verify(handlers.ScopeProvider, handlers.NewSigner("").Sign(handlers.ScopeProvider, 9))
+	Expect(ok).To(BeFalse())
+}
+
+func TestExportProviders(t *testing.T) {
+	initTest(t, nil)
+	db.ExportProvidersFunc = func(f func(database.Provider) error) error {
+		return f(database.Provider{ID: 1, ExternalID: "e1", Name: "p1", Address: database.Address{Lat: -26.66119, Long: 40.95858}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 4.5, Materials: []database.FloorMaterial{database.FloorWood},
+			Rates: map[database.FloorMaterial]float64{database.FloorWood: 25}, MinimumCharge: 300, JobArea: database.AreaRange{Min: 50},
+			Status: database.StatusPaused, ResumeAt: time.Date(2024, 8, 15, 0, 0, 0, 0, time.UTC)})
+	}
+	resp := execTokenRequest(testAdminToken, http.MethodGet, "/v1/providers/export?format=csv", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	Expect(resp.Header.Get("Content-Type")).To(Equal("text/csv"))
+	Expect(resp.Header.Get("Content-Disposition")).To(Equal(`attachment; filename="providers.csv"`))
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	Expect(resp.Body.Close()).To(BeNil())
+	Expect(string(respBody)).To(Equal(`id,external_id,name,lat,long,operating_radius,radius_unit,rating,experience,rates,minimum_charge,travel_rate,min_area,max_area,material_areas,material_radii,material_ratings,service_area,branches,availability,blackouts,time_zone,working_hours,status,resume_at
+1,e1,p1,-26.66119,40.95858,10,km,4.5,wood,wood:25,300,0,50,0,,,,,,,,,,paused,2024-08-15T00:00:00Z
+`))
+
+	resp = execTokenRequest(testAdminToken, http.MethodGet, "/v1/providers/export", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	Expect(resp.Header.Get("Content-Type")).To(Equal("application/geo+json"))
+	Expect(resp.Body.Close()).To(BeNil())
+}
+
+func TestExportProvidersStorageError(t *testing.T) {
+	initTest(t, nil)
+	db.ExportProvidersFunc = func(func(database.Provider) error) error {
+		return context.DeadlineExceeded
+	}
+	resp := execTokenRequest(testAdminToken, http.MethodGet, "/v1/providers/export?format=ndjson", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusGatewayTimeout))
+	Expect(resp.Header.Get("Content-Type")).To(HavePrefix("application/json"))
+	Expect(resp.Body.Close()).To(BeNil())
+
+	resp = execTokenRequest(testAdminToken, http.MethodGet, "/v1/providers/export?format=xml", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	Expect(resp.Body.Close()).To(BeNil())
+}
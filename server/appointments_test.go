@@ -0,0 +1,313 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// allWeek are working hours covering every day
+var allWeek = []database.WorkingHours{
+	{Weekday: time.Sunday, End: 24 * 60}, {Weekday: time.Monday, End: 24 * 60}, {Weekday: time.Tuesday, End: 24 * 60}, {Weekday: time.Wednesday, End: 24 * 60},
+	{Weekday: time.Thursday, End: 24 * 60}, {Weekday: time.Friday, End: 24 * 60}, {Weekday: time.Saturday, End: 24 * 60},
+}
+
+func initAppointmentTest(t *testing.T) {
+	initTest(t, nil)
+	db.GetLeadFunc = func(id database.ID) (database.Lead, error) {
+		if id != 12 {
+			return database.Lead{}, database.ErrNotFound
+		}
+		return database.Lead{ID: 12, Material: database.FloorTile, Address: database.Address{Lat: 1, Long: 2}, Area: 30, PhoneNumber: "1-800-2", ProviderIDs: []database.ID{9, 4}, Status: database.LeadOffered,
+			Offers: []database.LeadOffer{{ProviderID: 9, Status: database.OfferAccepted}, {ProviderID: 4, Status: database.OfferPending}}}, nil
+	}
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		switch id {
+		case 9:
+			return database.Provider{ID: 9, Name: "p9", Status: database.StatusActive, WorkingHours: allWeek}, nil
+		case 4:
+			return database.Provider{ID: 4, Name: "p4", Status: database.StatusActive}, nil
+		}
+		return database.Provider{}, database.ErrNotFound
+	}
+}
+
+// sendAppointmentRequest sends request with token of lead 12 the appointments are booked for
+func sendAppointmentRequest(method string, path string, request interface{}) (handlers.Appointment, int) {
+	return sendTokenAppointmentRequest(leadToken(12), method, path, request)
+}
+
+func sendTokenAppointmentRequest(token string, method string, path string, request interface{}) (handlers.Appointment, int) {
+	body := ""
+	if request != nil {
+		b, err := json.Marshal(request)
+		Expect(err).To(BeNil())
+		body = string(b)
+	}
+	var appointment handlers.Appointment
+	status := sendTokenDataRequest(token, method, path, body, &appointment)
+	return appointment, status
+}
+
+func TestBookAppointment(t *testing.T) {
+	initAppointmentTest(t)
+	var booked database.Appointment
+	db.BookAppointmentFunc = func(a database.Appointment) (database.ID, error) {
+		booked = a
+		return 5, nil
+	}
+	start := time.Now().UTC().Truncate(time.Hour).Add(48 * time.Hour)
+	appointment, status := sendAppointmentRequest(http.MethodPost, "/v1/appointments", handlers.AppointmentRequest{ProviderID: 9, StartsAt: start, CustomerName: "Ann"})
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(booked.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	Expect(appointment).To(Equal(handlers.Appointment{ID: 5, ProviderID: 9, LeadID: 12, CustomerName: "Ann", StartsAt: start, EndsAt: start.Add(time.Hour),
+		Status: "booked", CreatedAt: booked.CreatedAt, UpdatedAt: booked.CreatedAt}))
+	Expect(booked.EndsAt).To(Equal(start.Add(time.Hour)))
+
+	// provider should accept the lead, offers pending or not made are not enough
+	for _, providerID := range []database.ID{4, 7} {
+		_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments", handlers.AppointmentRequest{ProviderID: providerID, StartsAt: start})
+		Expect(status).To(Equal(http.StatusForbidden), fmt.Sprint(providerID))
+	}
+
+	// provider should be working at that time
+	for _, req := range []handlers.AppointmentRequest{
+		{ProviderID: 9, StartsAt: start.Add(-72 * time.Hour)},
+		{ProviderID: 9, StartsAt: start.AddDate(0, 0, 61)},
+	} {
+		_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments", req)
+		Expect(status).To(Equal(http.StatusUnprocessableEntity), fmt.Sprint(req))
+	}
+	_, status = sendTokenAppointmentRequest(leadToken(13), http.MethodPost, "/v1/appointments", handlers.AppointmentRequest{ProviderID: 9, StartsAt: start})
+	Expect(status).To(Equal(http.StatusNotFound))
+	_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments", handlers.AppointmentRequest{StartsAt: start})
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	// only customer holding token of the lead books for it
+	for _, token := range []string{"", "12", leadToken(12) + "0", providerToken(12), handlers.NewSigner("other").Sign(handlers.ScopeLead, 12)} {
+		_, status = sendTokenAppointmentRequest(token, http.MethodPost, "/v1/appointments", handlers.AppointmentRequest{ProviderID: 9, StartsAt: start})
+		Expect(status).To(Equal(http.StatusUnauthorized), token)
+	}
+
+	// the time may be taken meanwhile
+	db.BookAppointmentFunc = func(database.Appointment) (database.ID, error) {
+		return 0, fmt.Errorf("%w: taken", database.ErrConflict)
+	}
+	_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments", handlers.AppointmentRequest{ProviderID: 9, StartsAt: start})
+	Expect(status).To(Equal(http.StatusConflict))
+}
+
+func TestGetSlots(t *testing.T) {
+	initAppointmentTest(t)
+	day := time.Now().UTC().AddDate(0, 0, 3)
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		if id != 9 {
+			return database.Provider{}, database.ErrNotFound
+		}
+		return database.Provider{ID: 9, Status: database.StatusActive, WorkingHours: []database.WorkingHours{{Weekday: date.Weekday(), Start: 9 * 60, End: 12 * 60}}}, nil
+	}
+	var from, to time.Time
+	db.GetAppointmentsFunc = func(providerID database.ID, f, t time.Time) ([]database.Appointment, error) {
+		from, to = f, t
+		return []database.Appointment{{ID: 1, ProviderID: 9, StartsAt: date.Add(10 * time.Hour), EndsAt: date.Add(11 * time.Hour), Status: database.AppointmentBooked}}, nil
+	}
+	var slots handlers.Slots
+	status := sendDataRequest(http.MethodGet, "/v1/providers/9/slots?days=1&from="+date.Format(database.DateLayout), "", &slots)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(from).To(Equal(date))
+	Expect(to).To(Equal(date.AddDate(0, 0, 1)))
+	Expect(slots).To(Equal(handlers.Slots{ProviderID: 9, Slots: []handlers.Slot{
+		{StartsAt: date.Add(9 * time.Hour), EndsAt: date.Add(10 * time.Hour)},
+		{StartsAt: date.Add(11 * time.Hour), EndsAt: date.Add(12 * time.Hour)},
+	}}))
+
+	// slots of a week from now are listed by default
+	status = sendDataRequest(http.MethodGet, "/v1/providers/9/slots", "", &slots)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(slots.Slots).To(HaveLen(2))
+
+	status = sendDataRequest(http.MethodGet, "/v1/providers/9/slots?days=40", "", &slots)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	status = sendDataRequest(http.MethodGet, "/v1/providers/9/slots?from=soon", "", &slots)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	status = sendDataRequest(http.MethodGet, "/v1/providers/8/slots", "", &slots)
+	Expect(status).To(Equal(http.StatusNotFound))
+}
+
+func TestGetProviderAppointments(t *testing.T) {
+	initAppointmentTest(t)
+	day := time.Now().UTC().AddDate(0, 0, 3)
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	var from, to time.Time
+	db.GetAppointmentsFunc = func(providerID database.ID, f, t time.Time) ([]database.Appointment, error) {
+		if providerID != 9 {
+			return nil, database.ErrNotFound
+		}
+		from, to = f, t
+		return []database.Appointment{{ID: 1, ProviderID: 9, LeadID: 12, CustomerName: "Ann", StartsAt: date.Add(10 * time.Hour), EndsAt: date.Add(11 * time.Hour), Status: database.AppointmentBooked}}, nil
+	}
+	var appointments handlers.ProviderAppointments
+	status := sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/providers/9/appointments?days=2&from="+date.Format(database.DateLayout), "", &appointments)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(from).To(Equal(date))
+	Expect(to).To(Equal(date.AddDate(0, 0, 2)))
+	Expect(appointments).To(Equal(handlers.ProviderAppointments{ProviderID: 9, Appointments: []handlers.Appointment{
+		{ID: 1, ProviderID: 9, LeadID: 12, CustomerName: "Ann", StartsAt: date.Add(10 * time.Hour), EndsAt: date.Add(11 * time.Hour), Status: "booked"},
+	}}))
+
+	// appointments of a week from now are listed by default
+	status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/providers/9/appointments", "", &appointments)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(from).To(BeTemporally("~", time.Now(), time.Minute))
+	Expect(to.Sub(from)).To(BeNumerically(">", 6*24*time.Hour))
+	Expect(to.Sub(from)).To(BeNumerically("<=", 7*24*time.Hour))
+
+	status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/providers/9/appointments?days=40", "", nil)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/providers/9/appointments?from=soon", "", nil)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	status = sendTokenDataRequest(providerToken(8), http.MethodGet, "/v1/providers/8/appointments", "", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	// only the provider itself lists its appointments
+	status = sendDataRequest(http.MethodGet, "/v1/providers/9/appointments", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	status = sendTokenDataRequest(leadToken(9), http.MethodGet, "/v1/providers/9/appointments", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	status = sendTokenDataRequest(providerToken(4), http.MethodGet, "/v1/providers/9/appointments", "", nil)
+	Expect(status).To(Equal(http.StatusForbidden))
+}
+
+func TestRescheduleAndCancelAppointment(t *testing.T) {
+	initAppointmentTest(t)
+	start := time.Now().UTC().Truncate(time.Hour).Add(48 * time.Hour)
+	saved := database.Appointment{ID: 5, ProviderID: 9, LeadID: 12, StartsAt: start, EndsAt: start.Add(time.Hour), Status: database.AppointmentBooked}
+	db.GetAppointmentFunc = func(id database.ID) (database.Appointment, error) {
+		if id != 5 {
+			return database.Appointment{}, database.ErrNotFound
+		}
+		return saved, nil
+	}
+	var newStart, newEnd time.Time
+	db.RescheduleAppointmentFunc = func(id database.ID, start, end, now time.Time) (database.Appointment, error) {
+		newStart, newEnd = start, end
+		a := saved
+		Expect(a.Reschedule(start, end, now)).To(BeNil())
+		return a, nil
+	}
+	appointment, status := sendAppointmentRequest(http.MethodPost, "/v1/appointments/5/reschedule", handlers.RescheduleRequest{StartsAt: start.Add(3 * time.Hour)})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(newStart).To(Equal(start.Add(3 * time.Hour)))
+	Expect(newEnd).To(Equal(start.Add(4 * time.Hour)))
+	Expect(appointment.StartsAt).To(Equal(newStart))
+	Expect(appointment.Sequence).To(Equal(1))
+
+	_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments/5/reschedule", handlers.RescheduleRequest{StartsAt: start.Add(-72 * time.Hour)})
+	Expect(status).To(Equal(http.StatusUnprocessableEntity))
+	_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments/6/reschedule", handlers.RescheduleRequest{StartsAt: start})
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	canceled := false
+	db.CancelAppointmentFunc = func(id database.ID, now time.Time) (database.Appointment, error) {
+		canceled = true
+		return saved, nil
+	}
+	// appointment is changed only with token of its lead
+	for _, token := range []string{"", providerToken(9)} {
+		_, status = sendTokenAppointmentRequest(token, http.MethodPost, "/v1/appointments/5/cancel", nil)
+		Expect(status).To(Equal(http.StatusUnauthorized))
+	}
+	_, status = sendTokenAppointmentRequest(leadToken(13), http.MethodPost, "/v1/appointments/5/cancel", nil)
+	Expect(status).To(Equal(http.StatusForbidden))
+	_, status = sendTokenAppointmentRequest(leadToken(13), http.MethodPost, "/v1/appointments/5/reschedule", handlers.RescheduleRequest{StartsAt: start.Add(3 * time.Hour)})
+	Expect(status).To(Equal(http.StatusForbidden))
+	_, status = sendTokenAppointmentRequest(leadToken(13), http.MethodGet, "/v1/appointments/5", nil)
+	Expect(status).To(Equal(http.StatusForbidden))
+	Expect(canceled).To(BeFalse())
+
+	db.CancelAppointmentFunc = func(id database.ID, now time.Time) (database.Appointment, error) {
+		a := saved
+		if err := a.Cancel(now); err != nil {
+			return database.Appointment{}, err
+		}
+		return a, nil
+	}
+	appointment, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments/5/cancel", nil)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(appointment.Status).To(Equal("canceled"))
+
+	// canceled appointments can not be changed
+	saved.Status = database.AppointmentCanceled
+	_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments/5/cancel", nil)
+	Expect(status).To(Equal(http.StatusConflict))
+	_, status = sendAppointmentRequest(http.MethodPost, "/v1/appointments/5/reschedule", handlers.RescheduleRequest{StartsAt: start})
+	Expect(status).To(Equal(http.StatusConflict))
+	appointment, status = sendAppointmentRequest(http.MethodGet, "/v1/appointments/5", nil)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(appointment.Status).To(Equal("canceled"))
+}
+
+func TestAppointmentCalendar(t *testing.T) {
+	initAppointmentTest(t)
+	start := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	saved := database.Appointment{ID: 5, ProviderID: 9, LeadID: 12, CustomerName: "Ann", StartsAt: start, EndsAt: start.Add(time.Hour), Status: database.AppointmentBooked, Sequence: 1, UpdatedAt: start.Add(-time.Hour)}
+	db.GetAppointmentFunc = func(database.ID) (database.Appointment, error) {
+		return saved, nil
+	}
+	calendar := func(token string, query string) (string, int) {
+		resp := execTokenRequest(token, http.MethodGet, "/v1/appointments/5/ics"+query, "")
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).To(BeNil())
+		Expect(resp.Body.Close()).To(BeNil())
+		if resp.StatusCode == http.StatusOK {
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/calendar; charset=utf-8"))
+			Expect(resp.Header.Get("Content-Disposition")).To(Equal(`attachment; filename="appointment-5.ics"`))
+		}
+		return string(body), resp.StatusCode
+	}
+	body, status := calendar(leadToken(12), "")
+	Expect(status).To(Equal(http.StatusOK))
+	for _, line := range []string{"UID:appointment-5@ah-floors", "SEQUENCE:1", "DTSTART:20240701T080000Z", "DTEND:20240701T090000Z",
+		"SUMMARY:Floor measurement by p9", "GEO:1.000000;2.000000", "STATUS:CONFIRMED"} {
+		Expect(body).To(ContainSubstring("\r\n" + line + "\r\n"))
+	}
+	// customer phone number is only in calendar of provider
+	Expect(body).NotTo(ContainSubstring("1-800-2"))
+	body, status = calendar(providerToken(9), "?party=provider")
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(body).To(ContainSubstring("\r\nSUMMARY:Floor measurement for Ann\r\n"))
+	Expect(body).To(ContainSubstring(`Phone: 1-800-2`))
+
+	// calendar of provider is only for the provider after it accepted the lead
+	for _, token := range []string{"", leadToken(12), providerToken(9) + "0"} {
+		_, status = calendar(token, "?party=provider")
+		Expect(status).To(Equal(http.StatusUnauthorized), token)
+	}
+	_, status = calendar(providerToken(4), "?party=provider")
+	Expect(status).To(Equal(http.StatusForbidden))
+	saved.ProviderID = 4
+	_, status = calendar(providerToken(4), "?party=provider")
+	Expect(status).To(Equal(http.StatusForbidden))
+	saved.ProviderID = 9
+	// calendar of customer is only for holder of token of the lead
+	for _, token := range []string{"", providerToken(9)} {
+		_, status = calendar(token, "")
+		Expect(status).To(Equal(http.StatusUnauthorized), token)
+	}
+	_, status = calendar(leadToken(13), "?party=customer")
+	Expect(status).To(Equal(http.StatusForbidden))
+
+	saved.Status = database.AppointmentCanceled
+	body, status = calendar(leadToken(12), "?party=customer")
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(body).To(ContainSubstring("\r\nSTATUS:CANCELLED\r\n"))
+	_, status = calendar(leadToken(12), "?party=someone")
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
@@ -1,8 +1,56 @@
 package server
 
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"time"
+)
+
 // Config contains api server configurations
 type Config struct {
 	ListenAddress string `env:"AH_FLOORS_HTTP_LISTEN_ADDRESS" env-default:"localhost:8000"`
 	ReadTimeout   uint   `env:"AH_FLOORS_SERVER_READ_TIMEOUT" env-default:"5"`
 	WriteTimeout  uint   `env:"AH_FLOORS_SERVER_WRITE_TIMEOUT" env-default:"5"`
+	// AdminToken is bearer token required by admin endpoints, admin endpoints are disabled when empty
+	AdminToken string `env:"AH_FLOORS_ADMIN_TOKEN" env-default:""`
+	// TokenSecret signs provider tokens, endpoints of providers are disabled when empty and changing it revokes issued tokens
+	TokenSecret string `env:"AH_FLOORS_TOKEN_SECRET" env-default:""`
+	// Ranking* configure how matched providers are ranked, see database.Ranking
+	RankingMethod      string  `env:"AH_FLOORS_RANKING_METHOD" env-default:"bayesian"`
+	RankingPriorMean   float64 `env:"AH_FLOORS_RANKING_PRIOR_MEAN" env-default:"4"`
+	RankingPriorWeight float64 `env:"AH_FLOORS_RANKING_PRIOR_WEIGHT" env-default:"10"`
+	// LeadOfferCount is number of top matched providers a lead is offered to
+	LeadOfferCount int `env:"AH_FLOORS_LEAD_OFFER_COUNT" env-default:"3"`
+	// LeadOfferTTL is how many seconds providers have to accept or decline an offered lead
+	LeadOfferTTL uint `env:"AH_FLOORS_LEAD_OFFER_TTL" env-default:"86400"`
+	// AppointmentDuration is how many minutes an on-site measurement appointment takes
+	AppointmentDuration uint `env:"AH_FLOORS_APPOINTMENT_DURATION" env-default:"60"`
+	// AppointmentHorizon is how many days ahead appointments can be booked
+	AppointmentHorizon int `env:"AH_FLOORS_APPOINTMENT_HORIZON" env-default:"60"`
+}
+
+func (config Config) ranking() database.Ranking {
+	return database.Ranking{
+		Method:      database.RankingMethod(config.RankingMethod),
+		PriorMean:   config.RankingPriorMean,
+		PriorWeight: config.RankingPriorWeight,
+	}
+}
+
+func (config Config) routing() database.Routing {
+	return database.Routing{
+		OfferCount: config.LeadOfferCount,
+		OfferTTL:   time.Duration(config.LeadOfferTTL) * time.Second,
+	}
+}
+
+func (config Config) signer() handlers.Signer {
+	return handlers.NewSigner(config.TokenSecret)
+}
+
+func (config Config) scheduling() database.Scheduling {
+	return database.Scheduling{
+		SlotDuration: time.Duration(config.AppointmentDuration) * time.Minute,
+		HorizonDays:  config.AppointmentHorizon,
+	}
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"ah/bulk"
+	"ah/database"
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminAuth allows only requests with the given bearer token, every request is refused when token is empty
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if token == "" {
+			ErrorResponse(ctx, http.StatusServiceUnavailable, "admin token is not configured", nil)
+			ctx.Abort()
+			return
+		}
+		header := ctx.GetHeader("Authorization")
+		provided := strings.TrimPrefix(header, "Bearer ")
+		if provided == header || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			ErrorResponse(ctx, http.StatusUnauthorized, "invalid admin token", nil)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// getFormat reads file format from format query parameter
+func getFormat(ctx *gin.Context, defaultFormat bulk.Format) (bulk.Format, bool) {
+	format, err := bulk.ParseFormat(ctx.DefaultQuery("format", string(defaultFormat)))
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, err.Error(), err)
+		return "", false
+	}
+	return format, true
+}
+
+// getFlag reads a boolean query parameter, absent parameters are false
+func getFlag(ctx *gin.Context, name string) (bool, bool) {
+	value, err := strconv.ParseBool(ctx.DefaultQuery(name, "false"))
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "invalid "+name+" parameter", err)
+		return false, false
+	}
+	return value, true
+}
+
+// ImportProviders imports providers from request body, responding with a per row report
+func ImportProviders(ctx *gin.Context) {
+	format, ok := getFormat(ctx, bulk.JSON)
+	if !ok {
+		return
+	}
+	var options database.ImportOptions
+	if options.Upsert, ok = getFlag(ctx, "upsert"); !ok {
+		return
+	}
+	if options.DryRun, ok = getFlag(ctx, "dry_run"); !ok {
+		return
+	}
+	options.Now = time.Now().UTC().Truncate(time.Second)
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	reader, err := bulk.NewReader(ctx.Request.Body, format)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "reading file failed", err)
+		return
+	}
+	report, err := bulk.Import(ctx.Request.Context(), storage, reader, options)
+	if errors.Is(err, bulk.ErrMalformedFile) {
+		ErrorResponse(ctx, http.StatusBadRequest, "reading file failed", err)
+		return
+	}
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "providers imported", report)
+}
+
+// ExportProviders streams all providers as a file in the requested format, geojson by default
+func ExportProviders(ctx *gin.Context) {
+	format, ok := getFormat(ctx, bulk.GeoJSON)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	writer, err := bulk.NewWriter(ctx.Writer, format)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	ctx.Header("Content-Type", format.ContentType())
+	ctx.Header("Content-Disposition", `attachment; filename="providers.`+string(format)+`"`)
+	count, err := bulk.Export(ctx.Request.Context(), storage, writer)
+	if err == nil {
+		return
+	}
+	if !ctx.Writer.Written() {
+		// nothing is sent yet, so the error is reported as usual
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	// status is already sent, the file is left unfinished
+	zap.L().Error("exporting providers failed", zap.Int("exported", count), zap.Error(err))
+}
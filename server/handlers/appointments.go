@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"ah/database"
+	"ah/ics"
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// party a calendar is made for
+const (
+	PartyCustomer = "customer"
+	PartyProvider = "provider"
+)
+
+// defaultSlotDays is number of days free slots are listed for when not requested
+const defaultSlotDays = 7
+
+// Appointment is an on-site measurement booked by a customer with a provider matched for its lead
+type Appointment struct {
+	ID           database.ID `json:"id"`
+	ProviderID   database.ID `json:"provider_id"`
+	LeadID       database.ID `json:"lead_id"`
+	CustomerName string      `json:"customer_name,omitempty"`
+	StartsAt     time.Time   `json:"starts_at"`
+	EndsAt       time.Time   `json:"ends_at"`
+	// Status is booked or canceled
+	Status string `json:"status"`
+	// Sequence counts changes of appointment
+	Sequence  int       `json:"sequence"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AppointmentRequest books an appointment with a provider that accepted the lead of the lead token
+type AppointmentRequest struct {
+	ProviderID   database.ID `json:"provider_id" binding:"required,min=1"`
+	StartsAt     time.Time   `json:"starts_at" binding:"required"`
+	CustomerName string      `json:"customer_name" binding:"max=45"`
+}
+
+// RescheduleRequest moves an appointment to a new time
+type RescheduleRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+}
+
+// SlotQuery selects days free slots are listed for, from is a date in time zone of provider
+type SlotQuery struct {
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	Days int    `form:"days" binding:"omitempty,min=1,max=31"`
+}
+
+// period returns time from start of query to end of its last day in time zone of provider, from now when not requested
+func (query SlotQuery) period(dbProvider database.Provider, now time.Time) (time.Time, time.Time, error) {
+	loc, err := dbProvider.Location()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	from := now
+	if query.From != "" {
+		from, _ = time.ParseInLocation(database.DateLayout, query.From, loc)
+	}
+	local := from.In(loc)
+	to := time.Date(local.Year(), local.Month(), local.Day()+query.days(), 0, 0, 0, 0, loc)
+	return from, to, nil
+}
+
+// days returns number of days of query, defaultSlotDays when not requested
+func (query SlotQuery) days() int {
+	if query.Days == 0 {
+		return defaultSlotDays
+	}
+	return query.Days
+}
+
+// CalendarQuery selects party a calendar is made for
+type CalendarQuery struct {
+	Party string `form:"party" binding:"omitempty,oneof=customer provider"`
+}
+
+// Slot is a free time of a provider an appointment can be booked at
+type Slot struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Slots are free slots of a provider
+type Slots struct {
+	ProviderID database.ID `json:"provider_id"`
+	// TimeZone is IANA name of time zone of provider, UTC when empty
+	TimeZone string `json:"time_zone,omitempty"`
+	Slots    []Slot `json:"slots"`
+}
+
+// ProviderAppointments are booked appointments of a provider
+type ProviderAppointments struct {
+	ProviderID database.ID `json:"provider_id"`
+	// TimeZone is IANA name of time zone of provider, UTC when empty
+	TimeZone     string        `json:"time_zone,omitempty"`
+	Appointments []Appointment `json:"appointments"`
+}
+
+func fromDBAppointment(dbAppointment database.Appointment) Appointment {
+	return Appointment{
+		ID:           dbAppointment.ID,
+		ProviderID:   dbAppointment.ProviderID,
+		LeadID:       dbAppointment.LeadID,
+		CustomerName: dbAppointment.CustomerName,
+		StartsAt:     dbAppointment.StartsAt,
+		EndsAt:       dbAppointment.EndsAt,
+		Status:       string(dbAppointment.Status),
+		Sequence:     dbAppointment.Sequence,
+		CreatedAt:    dbAppointment.CreatedAt,
+		UpdatedAt:    dbAppointment.UpdatedAt,
+	}
+}
+
+// toEvent makes a calendar event of an appointment for party, provider is shown customer phone number
+// as customer chose to book it
+func toEvent(dbAppointment database.Appointment, dbLead database.Lead, dbProvider database.Provider, party string) ics.Event {
+	job := fmt.Sprintf("On-site measurement of %g m² of %s flooring", dbLead.Area, dbLead.Material)
+	event := ics.Event{
+		UID:       fmt.Sprintf("appointment-%d@ah-floors", dbAppointment.ID),
+		Sequence:  dbAppointment.Sequence,
+		Stamp:     dbAppointment.UpdatedAt,
+		Start:     dbAppointment.StartsAt,
+		End:       dbAppointment.EndsAt,
+		Location:  fmt.Sprintf("%f, %f", dbLead.Address.Lat, dbLead.Address.Long),
+		Geo:       &ics.Geo{Lat: dbLead.Address.Lat, Long: dbLead.Address.Long},
+		Cancelled: dbAppointment.Status == database.AppointmentCanceled,
+	}
+	if party == PartyProvider {
+		customer := dbAppointment.CustomerName
+		if customer == "" {
+			customer = "customer"
+		}
+		event.Summary = "Floor measurement for " + customer
+		event.Description = fmt.Sprintf("%s.\nCustomer: %s\nPhone: %s", job, customer, dbLead.PhoneNumber)
+		return event
+	}
+	event.Summary = "Floor measurement by " + dbProvider.Name
+	event.Description = fmt.Sprintf("%s by %s.", job, dbProvider.Name)
+	return event
+}
+
+// GetSlots returns free slots of a provider appointments can be booked at
+func GetSlots(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	var query SlotQuery
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	now := time.Now().UTC()
+	from, to, err := query.period(dbProvider, now)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	booked, err := storage.GetAppointments(ctx.Request.Context(), id, from, to)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	scheduling := getScheduling(ctx)
+	starts, err := scheduling.Slots(dbProvider, booked, from, query.days(), now)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	resp := Slots{ProviderID: id, TimeZone: dbProvider.TimeZone, Slots: []Slot{}}
+	for _, start := range starts {
+		resp.Slots = append(resp.Slots, Slot{StartsAt: start, EndsAt: start.Add(scheduling.SlotDuration)})
+	}
+	SuccessResponse(ctx, http.StatusOK, "free slots", resp)
+}
+
+// GetProviderAppointments returns booked appointments of the authenticated provider, ordered by start
+func GetProviderAppointments(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	var query SlotQuery
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	from, to, err := query.period(dbProvider, time.Now().UTC())
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	dbAppointments, err := storage.GetAppointments(ctx.Request.Context(), id, from, to)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	resp := ProviderAppointments{ProviderID: id, TimeZone: dbProvider.TimeZone, Appointments: []Appointment{}}
+	for _, dbAppointment := range dbAppointments {
+		resp.Appointments = append(resp.Appointments, fromDBAppointment(dbAppointment))
+	}
+	SuccessResponse(ctx, http.StatusOK, "list of appointments", resp)
+}
+
+// BookAppointment books an appointment of a customer with a provider that accepted its lead
+func BookAppointment(ctx *gin.Context) {
+	var req AppointmentRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbLead, err := storage.GetLead(ctx.Request.Context(), getAuthLead(ctx))
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	offer, ok := dbLead.Offer(req.ProviderID)
+	if !ok || offer.Status != database.OfferAccepted {
+		ErrorResponse(ctx, http.StatusForbidden, "lead is not accepted by the provider", nil)
+		return
+	}
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), req.ProviderID)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	scheduling := getScheduling(ctx)
+	now := time.Now().UTC().Truncate(time.Second)
+	start := req.StartsAt.UTC().Truncate(time.Second)
+	dbAppointment := database.Appointment{
+		ProviderID:   req.ProviderID,
+		LeadID:       dbLead.ID,
+		CustomerName: req.CustomerName,
+		StartsAt:     start,
+		EndsAt:       start.Add(scheduling.SlotDuration),
+		Status:       database.AppointmentBooked,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	err = scheduling.Check(dbProvider, dbAppointment, now)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	dbAppointment.ID, err = storage.BookAppointment(ctx.Request.Context(), dbAppointment)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusCreated, "appointment booked", fromDBAppointment(dbAppointment))
+}
+
+// ownAppointment reports whether appointment is of the lead authenticated by LeadAuth, responding with an error when not
+func ownAppointment(ctx *gin.Context, dbAppointment database.Appointment) bool {
+	if dbAppointment.LeadID != getAuthLead(ctx) {
+		ErrorResponse(ctx, http.StatusForbidden, "lead token is of another lead", nil)
+		return false
+	}
+	return true
+}
+
+// GetAppointment returns a single appointment of the lead
+func GetAppointment(ctx *gin.Context) {
+	id, ok := getID(ctx, "appointment")
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbAppointment, err := storage.GetAppointment(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	if !ownAppointment(ctx, dbAppointment) {
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "appointment", fromDBAppointment(dbAppointment))
+}
+
+// GetAppointmentCalendar returns an appointment as an iCalendar file for customer with token of its lead
+// or for provider with its own token after accepting the lead
+func GetAppointmentCalendar(ctx *gin.Context) {
+	id, ok := getID(ctx, "appointment")
+	if !ok {
+		return
+	}
+	var query CalendarQuery
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	scope := ScopeLead
+	if query.Party == PartyProvider {
+		scope = ScopeProvider
+	}
+	authID, ok := verifyToken(ctx, scope)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbAppointment, err := storage.GetAppointment(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	if scope == ScopeLead && authID != dbAppointment.LeadID {
+		ErrorResponse(ctx, http.StatusForbidden, "lead token is of another lead", nil)
+		return
+	}
+	if scope == ScopeProvider && authID != dbAppointment.ProviderID {
+		ErrorResponse(ctx, http.StatusForbidden, "provider token is of another provider", nil)
+		return
+	}
+	dbLead, err := storage.GetLead(ctx.Request.Context(), dbAppointment.LeadID)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	// customer phone number is shown only to provider that accepted the lead
+	if scope == ScopeProvider {
+		offer, ok := dbLead.Offer(authID)
+		if !ok || offer.Status != database.OfferAccepted {
+			ErrorResponse(ctx, http.StatusForbidden, "lead is not accepted by the provider", nil)
+			return
+		}
+	}
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), dbAppointment.ProviderID)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	var b bytes.Buffer
+	err = ics.Write(&b, toEvent(dbAppointment, dbLead, dbProvider, query.Party))
+	if err != nil {
+		ErrorResponse(ctx, http.StatusInternalServerError, "writing calendar failed", err)
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="appointment-%d.ics"`, id))
+	ctx.Data(http.StatusOK, ics.ContentType, b.Bytes())
+}
+
+// RescheduleAppointment moves a booked appointment of the lead to another free slot of its provider
+func RescheduleAppointment(ctx *gin.Context) {
+	id, ok := getID(ctx, "appointment")
+	if !ok {
+		return
+	}
+	var req RescheduleRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbAppointment, err := storage.GetAppointment(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	if !ownAppointment(ctx, dbAppointment) {
+		return
+	}
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), dbAppointment.ProviderID)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	scheduling := getScheduling(ctx)
+	now := time.Now().UTC().Truncate(time.Second)
+	start := req.StartsAt.UTC().Truncate(time.Second)
+	end := start.Add(scheduling.SlotDuration)
+	// new time is checked against working hours here, storage checks it against other appointments
+	err = dbAppointment.Reschedule(start, end, now)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	err = scheduling.Check(dbProvider, dbAppointment, now)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	dbAppointment, err = storage.RescheduleAppointment(ctx.Request.Context(), id, start, end, now)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "appointment rescheduled", fromDBAppointment(dbAppointment))
+}
+
+// CancelAppointment cancels a booked appointment of the lead
+func CancelAppointment(ctx *gin.Context) {
+	id, ok := getID(ctx, "appointment")
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbAppointment, err := storage.GetAppointment(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	if !ownAppointment(ctx, dbAppointment) {
+		return
+	}
+	dbAppointment, err = storage.CancelAppointment(ctx.Request.Context(), id, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "appointment canceled", fromDBAppointment(dbAppointment))
+}
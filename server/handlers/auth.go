@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"ah/database"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TokenScope is what a signed token grants access to, a token of one scope is never valid for another
+type TokenScope string
+
+const (
+	// ScopeProvider tokens act on behalf of a provider
+	ScopeProvider TokenScope = "provider"
+	// ScopeLead tokens act on behalf of customer of a lead, they are returned with matches of the lead
+	ScopeLead TokenScope = "lead"
+)
+
+// Signer issues and verifies bearer tokens bound to an id, tokens are signatures of the id so they are not stored
+// and changing the secret revokes all of them
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner returns a signer using secret, no token is valid when secret is empty
+func NewSigner(secret string) Signer {
+	return Signer{secret: []byte(secret)}
+}
+
+// Enabled reports whether the signer has a secret to sign with
+func (s Signer) Enabled() bool {
+	return len(s.secret) > 0
+}
+
+func (s Signer) signature(scope TokenScope, id database.ID) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(string(scope) + ":" + strconv.FormatInt(int64(id), 10)))
+	return mac.Sum(nil)
+}
+
+// Sign returns token of id in scope formatted as <id>.<signature>
+func (s Signer) Sign(scope TokenScope, id database.ID) string {
+	return strconv.FormatInt(int64(id), 10) + "." + hex.EncodeToString(s.signature(scope, id))
+}
+
+// Verify returns id the token was signed for in scope, false when the token is malformed or forged
+func (s Signer) Verify(scope TokenScope, token string) (database.ID, bool) {
+	if !s.Enabled() {
+		return 0, false
+	}
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	signature, err := hex.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.signature(scope, database.ID(id))) {
+		return 0, false
+	}
+	return database.ID(id), true
+}
+
+func getSigner(ctx *gin.Context) Signer {
+	signer, exists := ctx.Get("signer")
+	if !exists {
+		return Signer{}
+	}
+	return signer.(Signer)
+}
+
+// bearerToken returns token of Authorization header, empty when there is none
+func bearerToken(ctx *gin.Context) string {
+	header := ctx.GetHeader("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header {
+		return ""
+	}
+	return token
+}
+
+// verifyToken checks bearer token of the request in scope, responding with an error when it is not valid
+func verifyToken(ctx *gin.Context, scope TokenScope) (database.ID, bool) {
+	signer := getSigner(ctx)
+	if !signer.Enabled() {
+		ErrorResponse(ctx, http.StatusServiceUnavailable, "token secret is not configured", nil)
+		return 0, false
+	}
+	id, ok := signer.Verify(scope, bearerToken(ctx))
+	if !ok {
+		ErrorResponse(ctx, http.StatusUnauthorized, "invalid "+string(scope)+" token", nil)
+		return 0, false
+	}
+	return id, true
+}
+
+// ProviderAuth allows only requests with a provider token, the provider is then available through getAuthProvider
+func ProviderAuth(ctx *gin.Context) {
+	id, ok := verifyToken(ctx, ScopeProvider)
+	if !ok {
+		ctx.Abort()
+		return
+	}
+	ctx.Set("provider", id)
+	ctx.Next()
+}
+
+// OwnProvider allows a provider authenticated by ProviderAuth to act only on itself
+func OwnProvider(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		ctx.Abort()
+		return
+	}
+	if id != getAuthProvider(ctx) {
+		ErrorResponse(ctx, http.StatusForbidden, "provider token is of another provider", nil)
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
+
+// getAuthProvider returns provider authenticated by ProviderAuth
+func getAuthProvider(ctx *gin.Context) database.ID {
+	id, _ := ctx.Get("provider")
+	return id.(database.ID)
+}
+
+// LeadAuth allows only requests with a lead token, the lead is then available through getAuthLead
+func LeadAuth(ctx *gin.Context) {
+	id, ok := verifyToken(ctx, ScopeLead)
+	if !ok {
+		ctx.Abort()
+		return
+	}
+	ctx.Set("lead", id)
+	ctx.Next()
+}
+
+// getAuthLead returns lead authenticated by LeadAuth
+func getAuthLead(ctx *gin.Context) database.ID {
+	id, _ := ctx.Get("lead")
+	return id.(database.ID)
+}
+
+// ProviderToken is a bearer token of a provider
+type ProviderToken struct {
+	ProviderID database.ID `json:"provider_id"`
+	Token      string      `json:"token"`
+}
+
+// IssueProviderToken returns token of an existing provider
+func IssueProviderToken(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+	signer := getSigner(ctx)
+	if !signer.Enabled() {
+		ErrorResponse(ctx, http.StatusServiceUnavailable, "token secret is not configured", nil)
+		return
+	}
+
+	_, err := storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "provider token", ProviderToken{ProviderID: id, Token: signer.Sign(ScopeProvider, id)})
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"ah/database"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"net/http"
+	"time"
 )
 
 func isClientError(code int) bool {
@@ -33,6 +38,26 @@ func ErrorResponse(c *gin.Context, code int, message string, err error) {
 	})
 }
 
+// StorageErrorResponse is returned in case of storage error, mapping known errors to proper status codes
+func StorageErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		ErrorResponse(c, http.StatusNotFound, "not found", err)
+	case errors.Is(err, database.ErrDuplicateEntry):
+		ErrorResponse(c, http.StatusConflict, "duplicate entry", err)
+	case errors.Is(err, database.ErrInvalid):
+		ErrorResponse(c, http.StatusUnprocessableEntity, "invalid operation", err)
+	case errors.Is(err, database.ErrConflict):
+		ErrorResponse(c, http.StatusConflict, "conflicting state", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		ErrorResponse(c, http.StatusGatewayTimeout, "storage timed out", err)
+	case errors.Is(err, context.Canceled):
+		ErrorResponse(c, http.StatusServiceUnavailable, "request canceled", err)
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "db error", err)
+	}
+}
+
 // SuccessResponse is returned after a successful request
 func SuccessResponse(ctx *gin.Context, code int, message string, data interface{}) {
 	ctx.JSON(code, Response{
@@ -41,3 +66,36 @@ func SuccessResponse(ctx *gin.Context, code int, message string, data interface{
 		Data:    data,
 	})
 }
+
+func getStorage(ctx *gin.Context) (Storage, bool) {
+	db, exists := ctx.Get("db")
+	if !exists {
+		ErrorResponse(ctx, http.StatusInternalServerError, "storage instance is not present", nil)
+		return nil, false
+	}
+	return db.(Storage), true
+}
+
+func getRanking(ctx *gin.Context) database.Ranking {
+	ranking, exists := ctx.Get("ranking")
+	if !exists {
+		return database.Ranking{Method: database.RankByRating}
+	}
+	return ranking.(database.Ranking)
+}
+
+func getRouting(ctx *gin.Context) database.Routing {
+	routing, exists := ctx.Get("routing")
+	if !exists {
+		return database.Routing{}
+	}
+	return routing.(database.Routing)
+}
+
+func getScheduling(ctx *gin.Context) database.Scheduling {
+	scheduling, exists := ctx.Get("scheduling")
+	if !exists {
+		return database.Scheduling{SlotDuration: time.Hour, HorizonDays: 60}
+	}
+	return scheduling.(database.Scheduling)
+}
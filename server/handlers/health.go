@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"ah/database"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// HealthChecker is implemented by storages depending on an external server
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	PoolStats() database.PoolStats
+}
+
+// Health is status of service and its storage
+type Health struct {
+	Status string              `json:"status"`
+	Pool   *database.PoolStats `json:"pool,omitempty"`
+}
+
+// GetHealth reports whether service is able to serve requests
+func GetHealth(ctx *gin.Context) {
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	checker, ok := storage.(HealthChecker)
+	if !ok {
+		SuccessResponse(ctx, http.StatusOK, "healthy", Health{Status: "ok"})
+		return
+	}
+	err := checker.Ping(ctx.Request.Context())
+	if err != nil {
+		ErrorResponse(ctx, http.StatusServiceUnavailable, "storage is not available", err)
+		return
+	}
+	stats := checker.PoolStats()
+	SuccessResponse(ctx, http.StatusOK, "healthy", Health{Status: "ok", Pool: &stats})
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"ah/database"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// Lead is a saved customer request with providers shown for it
+type Lead struct {
+	ID          database.ID   `json:"id"`
+	Material    string        `json:"material"`
+	Address     Address       `json:"address"`
+	Area        float64       `json:"area"`
+	PhoneNumber string        `json:"phone_number"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ProviderIDs []database.ID `json:"provider_ids"`
+	Status      string        `json:"status"`
+	Offers      []LeadOffer   `json:"offers"`
+}
+
+// LeadOffer is an offer of a lead to a provider
+type LeadOffer struct {
+	ProviderID database.ID `json:"provider_id"`
+	Status     string      `json:"status"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+// OfferedLead is a lead as seen by a provider it is offered to, phone number is only shown to provider who accepted it
+type OfferedLead struct {
+	ID          database.ID `json:"id"`
+	Material    string      `json:"material"`
+	Address     Address     `json:"address"`
+	Area        float64     `json:"area"`
+	PhoneNumber string      `json:"phone_number,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Status      string      `json:"status"`
+	OfferStatus string      `json:"offer_status"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
+
+// OfferedLeads is a page of leads offered to a provider with total number of its offers
+type OfferedLeads struct {
+	Total int           `json:"total"`
+	Leads []OfferedLead `json:"leads"`
+}
+
+func fromDBLead(dbLead database.Lead) Lead {
+	return Lead{
+		ID:          dbLead.ID,
+		Material:    string(dbLead.Material),
+		Address:     Address{Lat: dbLead.Address.Lat, Long: dbLead.Address.Long},
+		Area:        dbLead.Area,
+		PhoneNumber: dbLead.PhoneNumber,
+		CreatedAt:   dbLead.CreatedAt,
+		ProviderIDs: dbLead.ProviderIDs,
+		Status:      string(dbLead.Status),
+		Offers:      fromDBOffers(dbLead.Offers),
+	}
+}
+
+func fromDBOffers(dbOffers []database.LeadOffer) []LeadOffer {
+	offers := make([]LeadOffer, 0, len(dbOffers))
+	for _, dbOffer := range dbOffers {
+		offers = append(offers, LeadOffer{
+			ProviderID: dbOffer.ProviderID,
+			Status:     string(dbOffer.Status),
+			ExpiresAt:  dbOffer.ExpiresAt,
+		})
+	}
+	return offers
+}
+
+// toOfferedLead returns lead as seen by a provider, false when it was not offered to the provider
+func toOfferedLead(dbLead database.Lead, providerID database.ID) (OfferedLead, bool) {
+	offer, ok := dbLead.Offer(providerID)
+	if !ok {
+		return OfferedLead{}, false
+	}
+	lead := OfferedLead{
+		ID:          dbLead.ID,
+		Material:    string(dbLead.Material),
+		Address:     Address{Lat: dbLead.Address.Lat, Long: dbLead.Address.Long},
+		Area:        dbLead.Area,
+		CreatedAt:   dbLead.CreatedAt,
+		Status:      string(dbLead.Status),
+		OfferStatus: string(offer.Status),
+		ExpiresAt:   offer.ExpiresAt,
+	}
+	if offer.Status == database.OfferAccepted {
+		lead.PhoneNumber = dbLead.PhoneNumber
+	}
+	return lead, true
+}
+
+// GetLead returns a lead for follow-up
+func GetLead(ctx *gin.Context) {
+	id, ok := getID(ctx, "lead")
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbLead, err := storage.GetLead(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	dbLead.Expire(time.Now())
+	SuccessResponse(ctx, http.StatusOK, "lead", fromDBLead(dbLead))
+}
+
+// GetOfferedLeads returns a page of leads offered to the authenticated provider, newest first
+func GetOfferedLeads(ctx *gin.Context) {
+	page, ok := getPage(ctx)
+	if !ok {
+		return
+	}
+	providerID := getAuthProvider(ctx)
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbLeads, total, err := storage.GetOfferedLeads(ctx.Request.Context(), providerID, page)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	now := time.Now()
+	resp := OfferedLeads{Total: total, Leads: []OfferedLead{}}
+	for _, dbLead := range dbLeads {
+		dbLead.Expire(now)
+		lead, _ := toOfferedLead(dbLead, providerID)
+		resp.Leads = append(resp.Leads, lead)
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "list of leads", resp)
+}
+
+// GetOfferedLead returns a lead to the authenticated provider it is offered to
+func GetOfferedLead(ctx *gin.Context) {
+	id, ok := getID(ctx, "lead")
+	if !ok {
+		return
+	}
+	providerID := getAuthProvider(ctx)
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbLead, err := storage.GetLead(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	dbLead.Expire(time.Now())
+	lead, ok := toOfferedLead(dbLead, providerID)
+	if !ok {
+		ErrorResponse(ctx, http.StatusNotFound, "not found", nil)
+		return
+	}
+	SuccessResponse(ctx, http.StatusOK, "lead", lead)
+}
+
+// LeadAction returns a handler applying action of the authenticated provider to a lead it is offered to
+func LeadAction(action database.LeadAction) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, ok := getID(ctx, "lead")
+		if !ok {
+			return
+		}
+		providerID := getAuthProvider(ctx)
+		storage, ok := getStorage(ctx)
+		if !ok {
+			return
+		}
+
+		dbLead, err := storage.ApplyLeadAction(ctx.Request.Context(), id, providerID, action, time.Now().UTC().Truncate(time.Second))
+		if err != nil {
+			StorageErrorResponse(ctx, err)
+			return
+		}
+
+		lead, _ := toOfferedLead(dbLead, providerID)
+		SuccessResponse(ctx, http.StatusOK, "lead updated", lead)
+	}
+}
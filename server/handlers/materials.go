@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"ah/database"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Material is an entry of material catalogue
+type Material struct {
+	ID   database.ID `json:"id"`
+	Name string      `json:"name"`
+}
+
+// GetMaterials get list of supported floor materials
+func GetMaterials(ctx *gin.Context) {
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbMaterials, err := storage.GetMaterials(ctx.Request.Context())
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	resp := []Material{}
+	for _, dbMaterial := range dbMaterials {
+		resp = append(resp, Material{
+			ID:   dbMaterial.ID,
+			Name: string(dbMaterial.Name),
+		})
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "list of materials", resp)
+}
+
+// checkMaterials makes sure all materials are present in catalogue, responding with error otherwise
+func checkMaterials(ctx *gin.Context, storage Storage, materials ...string) bool {
+	dbMaterials, err := storage.GetMaterials(ctx.Request.Context())
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return false
+	}
+	catalogue := make(map[string]bool, len(dbMaterials))
+	for _, dbMaterial := range dbMaterials {
+		catalogue[string(dbMaterial.Name)] = true
+	}
+	for _, material := range materials {
+		if !catalogue[material] {
+			ErrorResponse(ctx, http.StatusBadRequest, "floor material is not supported", nil)
+			return false
+		}
+	}
+	return true
+}
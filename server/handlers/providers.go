@@ -2,8 +2,24 @@ package handlers
 
 import (
 	"ah/database"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRadiusUnit is unit of operating radius when not specified in request
+const DefaultRadiusUnit = database.Kilometre
+
+// orders of matched providers
+const (
+	// SortByRank orders providers by ranking score, the default
+	SortByRank = "rank"
+	// SortByPrice orders providers by estimated price, providers without an estimate come last
+	SortByPrice = "price"
 )
 
 // Address is a location on map
@@ -14,22 +30,523 @@ type Address struct {
 
 // Provider contains data of a matched provider
 type Provider struct {
-	Name            string   `json:"name"`
-	Experience      []string `json:"experience"`
-	Address         Address  `json:"address"`
-	OperatingRadius float64  `json:"operating_radius"`
-	Rating          float64  `json:"rating"`
+	ID              database.ID `json:"id"`
+	ExternalID      string      `json:"external_id,omitempty"`
+	Name            string      `json:"name"`
+	Experience      []string    `json:"experience"`
+	Address         Address     `json:"address"`
+	OperatingRadius float64     `json:"operating_radius"`
+	RadiusUnit      string      `json:"radius_unit"`
+	Rating          float64     `json:"rating"`
+	ReviewCount     int         `json:"review_count"`
+	// Rates are prices per square metre by material
+	Rates         map[string]float64 `json:"rates,omitempty"`
+	MinimumCharge float64            `json:"minimum_charge"`
+	TravelRate    float64            `json:"travel_rate"`
+	MinArea       float64            `json:"min_area"`
+	MaxArea       float64            `json:"max_area"`
+	// MaterialAreas replace min and max area for jobs with some materials
+	MaterialAreas map[string]JobArea `json:"material_areas,omitempty"`
+	// MaterialRadii and MaterialRatings replace operating radius and rating for jobs with some materials,
+	// matched providers have operating radius and rating of the requested material
+	MaterialRadii   map[string]MaterialRadius `json:"material_radii,omitempty"`
+	MaterialRatings map[string]float64        `json:"material_ratings,omitempty"`
+	// ServiceArea is a GeoJSON MultiPolygon provider works in instead of operating radius
+	ServiceArea json.RawMessage `json:"service_area,omitempty"`
+	Branches    []Branch        `json:"branches,omitempty"`
+	// Availability are date ranges provider can start jobs in, Blackouts are ones it can not
+	Availability []DateRange `json:"availability,omitempty"`
+	Blackouts    []DateRange `json:"blackouts,omitempty"`
+	// TimeZone is IANA name of time zone of working hours, UTC when empty
+	TimeZone     string         `json:"time_zone,omitempty"`
+	WorkingHours []WorkingHours `json:"working_hours,omitempty"`
+	// Status is one of pending, active, paused or suspended, only active providers are matched
+	Status string `json:"status,omitempty"`
+	// ResumeAt is when a paused provider becomes active again
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+	// RankingScore is score providers are ordered by, only present in matched providers
+	RankingScore *float64 `json:"ranking_score,omitempty"`
+	// EstimatedPrice is price of the requested job, only present in matched providers with a rate for the material
+	EstimatedPrice *float64  `json:"estimated_price,omitempty"`
+	Distance       *Distance `json:"distance,omitempty"`
+	// ClosestBranch is the branch distance is measured to, only present in matched providers closer to a branch than to address
+	ClosestBranch *Branch `json:"closest_branch,omitempty"`
+	// NextAvailableDate is the first day from requested start provider can start on, only present in matched providers
+	NextAvailableDate string `json:"next_available_date,omitempty"`
+}
+
+// DateRange is a range of days with both ends included
+type DateRange struct {
+	From string `json:"from" binding:"required,datetime=2006-01-02"`
+	To   string `json:"to" binding:"required,datetime=2006-01-02"`
+}
+
+// WorkingHours are hours provider takes appointments in on a weekday, in its time zone, end of day is 24:00
+type WorkingHours struct {
+	Weekday string `json:"weekday" binding:"required,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	Start   string `json:"start" binding:"required,datetime=15:04"`
+	End     string `json:"end" binding:"required,datetime=15:04|eq=24:00"`
+}
+
+// Branch is a depot of a provider with its own operating radius
+type Branch struct {
+	Name            string  `json:"name" binding:"required,max=45"`
+	Address         Address `json:"address" binding:"required"`
+	OperatingRadius float64 `json:"operating_radius" binding:"required,gt=0"`
+	RadiusUnit      string  `json:"radius_unit" binding:"omitempty,oneof=m km mi"`
+}
+
+// JobArea limits area of jobs in square metres, zero max area means no upper limit
+type JobArea struct {
+	MinArea float64 `json:"min_area" binding:"gte=0"`
+	MaxArea float64 `json:"max_area" binding:"omitempty,gtefield=MinArea"`
+}
+
+// MaterialRadius is operating radius for jobs with a material
+type MaterialRadius struct {
+	OperatingRadius float64 `json:"operating_radius" binding:"required,gt=0"`
+	RadiusUnit      string  `json:"radius_unit" binding:"omitempty,oneof=m km mi"`
+}
+
+// Distance is distance of a matched provider to customer
+type Distance struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
 }
 
 // CustomerRequest contains request data to find matching providers
 type CustomerRequest struct {
-	Material    string  `json:"material" binding:"required,oneof=wood carpet tile"`
+	Material    string  `json:"material" binding:"required"`
 	Address     Address `json:"address" binding:"required"`
 	Area        float64 `json:"area" binding:"required,gt=0"`
-	PhoneNumber string  `json:"phone_number" binding:"required"`
+	PhoneNumber string  `json:"phone_number" binding:"required,max=32"`
+	// MaxBudget leaves out providers whose estimated price is higher or unknown
+	MaxBudget float64 `json:"max_budget" binding:"omitempty,gt=0"`
+	Sort      string  `json:"sort" binding:"omitempty,oneof=rank price"`
+	// DesiredStartDate leaves out providers not available within FlexibilityDays of it
+	DesiredStartDate string `json:"desired_start_date" binding:"omitempty,datetime=2006-01-02"`
+	FlexibilityDays  int    `json:"flexibility_days" binding:"gte=0,lte=365"`
+}
+
+// Matches contains providers matching a customer request and id of the lead request is saved as
+type Matches struct {
+	LeadID database.ID `json:"lead_id"`
+	// LeadToken lets the customer book appointments for the lead, empty when token secret is not configured
+	LeadToken string     `json:"lead_token,omitempty"`
+	Providers []Provider `json:"providers"`
+}
+
+// ProviderRequest contains data to create or replace a provider
+type ProviderRequest struct {
+	ExternalID      string   `json:"external_id" binding:"max=64"`
+	Name            string   `json:"name" binding:"required,max=45"`
+	Experience      []string `json:"experience" binding:"dive,required"`
+	Address         Address  `json:"address" binding:"required"`
+	OperatingRadius float64  `json:"operating_radius" binding:"required,gt=0"`
+	RadiusUnit      string   `json:"radius_unit" binding:"omitempty,oneof=m km mi"`
+	Rating          float64  `json:"rating" binding:"gte=0,lte=5"`
+	// Rates are prices per square metre by material, only materials in experience can have a rate
+	Rates         map[string]float64 `json:"rates" binding:"dive,keys,required,endkeys,gt=0"`
+	MinimumCharge float64            `json:"minimum_charge" binding:"gte=0"`
+	TravelRate    float64            `json:"travel_rate" binding:"gte=0"`
+	// MinArea and MaxArea limit area of jobs provider takes, MaterialAreas replace them for some materials in experience
+	MinArea       float64            `json:"min_area" binding:"gte=0"`
+	MaxArea       float64            `json:"max_area" binding:"omitempty,gtefield=MinArea"`
+	MaterialAreas map[string]JobArea `json:"material_areas" binding:"dive"`
+	// MaterialRadii and MaterialRatings replace operating radius and rating for some materials in experience
+	MaterialRadii   map[string]MaterialRadius `json:"material_radii" binding:"dive"`
+	MaterialRatings map[string]float64        `json:"material_ratings" binding:"dive,keys,required,endkeys,gte=0,lte=5"`
+	// ServiceArea is a GeoJSON Polygon or MultiPolygon replacing operating radius in matching
+	ServiceArea json.RawMessage `json:"service_area"`
+	// Branches are depots provider is also matched by
+	Branches []Branch `json:"branches" binding:"dive"`
+	// Availability are date ranges provider can start jobs in, it can start on any day out of Blackouts without them
+	Availability []DateRange `json:"availability" binding:"dive"`
+	Blackouts    []DateRange `json:"blackouts" binding:"dive"`
+	// WorkingHours are weekly hours in TimeZone customers can book appointments in, there are none without them
+	TimeZone     string         `json:"time_zone" binding:"max=64"`
+	WorkingHours []WorkingHours `json:"working_hours" binding:"dive"`
+}
+
+// ProviderPatch contains data to partially update a provider, absent fields are left untouched
+type ProviderPatch struct {
+	ExternalID      *string                    `json:"external_id" binding:"omitempty,max=64"`
+	Name            *string                    `json:"name" binding:"omitempty,min=1,max=45"`
+	Experience      *[]string                  `json:"experience" binding:"omitempty,dive,required"`
+	Address         *Address                   `json:"address"`
+	OperatingRadius *float64                   `json:"operating_radius" binding:"omitempty,gt=0"`
+	RadiusUnit      *string                    `json:"radius_unit" binding:"omitempty,oneof=m km mi"`
+	Rating          *float64                   `json:"rating" binding:"omitempty,gte=0,lte=5"`
+	Rates           *map[string]float64        `json:"rates" binding:"omitempty,dive,keys,required,endkeys,gt=0"`
+	MinimumCharge   *float64                   `json:"minimum_charge" binding:"omitempty,gte=0"`
+	TravelRate      *float64                   `json:"travel_rate" binding:"omitempty,gte=0"`
+	MinArea         *float64                   `json:"min_area" binding:"omitempty,gte=0"`
+	MaxArea         *float64                   `json:"max_area" binding:"omitempty,gte=0"`
+	MaterialAreas   *map[string]JobArea        `json:"material_areas" binding:"omitempty,dive"`
+	MaterialRadii   *map[string]MaterialRadius `json:"material_radii" binding:"omitempty,dive"`
+	MaterialRatings *map[string]float64        `json:"material_ratings" binding:"omitempty,dive,keys,required,endkeys,gte=0,lte=5"`
+	// ServiceArea set to null removes service area
+	ServiceArea json.RawMessage `json:"service_area"`
+	Branches    *[]Branch       `json:"branches" binding:"omitempty,dive"`
+	// Availability and Blackouts replace all date ranges of their kind
+	Availability *[]DateRange `json:"availability" binding:"omitempty,dive"`
+	Blackouts    *[]DateRange `json:"blackouts" binding:"omitempty,dive"`
+	// WorkingHours replace all working hours
+	TimeZone     *string         `json:"time_zone" binding:"omitempty,max=64"`
+	WorkingHours *[]WorkingHours `json:"working_hours" binding:"omitempty,dive"`
+}
+
+func fromDBProvider(dbProvider database.Provider) Provider {
+	// a paused provider past its resume time is shown active before the resume is saved
+	dbProvider.Resume(time.Now())
+	provider := Provider{
+		ID:         dbProvider.ID,
+		ExternalID: dbProvider.ExternalID,
+		Name:       dbProvider.Name,
+		Address: Address{
+			Lat:  dbProvider.Address.Lat,
+			Long: dbProvider.Address.Long,
+		},
+		OperatingRadius: dbProvider.Radius,
+		RadiusUnit:      string(dbProvider.RadiusUnit),
+		Rating:          dbProvider.Rating,
+		ReviewCount:     dbProvider.ReviewCount,
+		MinimumCharge:   dbProvider.MinimumCharge,
+		TravelRate:      dbProvider.TravelRate,
+		MinArea:         dbProvider.JobArea.Min,
+		MaxArea:         dbProvider.JobArea.Max,
+		Status:          string(dbProvider.Status),
+	}
+	if !dbProvider.ResumeAt.IsZero() {
+		resumeAt := dbProvider.ResumeAt
+		provider.ResumeAt = &resumeAt
+	}
+	for _, material := range dbProvider.Materials {
+		provider.Experience = append(provider.Experience, string(material))
+	}
+	if len(dbProvider.Rates) != 0 {
+		provider.Rates = make(map[string]float64, len(dbProvider.Rates))
+		for material, rate := range dbProvider.Rates {
+			provider.Rates[string(material)] = rate
+		}
+	}
+	if len(dbProvider.MaterialJobAreas) != 0 {
+		provider.MaterialAreas = make(map[string]JobArea, len(dbProvider.MaterialJobAreas))
+		for material, area := range dbProvider.MaterialJobAreas {
+			provider.MaterialAreas[string(material)] = JobArea{MinArea: area.Min, MaxArea: area.Max}
+		}
+	}
+	if len(dbProvider.MaterialRadii) != 0 {
+		provider.MaterialRadii = make(map[string]MaterialRadius, len(dbProvider.MaterialRadii))
+		for material, radius := range dbProvider.MaterialRadii {
+			provider.MaterialRadii[string(material)] = MaterialRadius{OperatingRadius: radius.Radius, RadiusUnit: string(radius.RadiusUnit)}
+		}
+	}
+	if len(dbProvider.MaterialRatings) != 0 {
+		provider.MaterialRatings = make(map[string]float64, len(dbProvider.MaterialRatings))
+		for material, rating := range dbProvider.MaterialRatings {
+			provider.MaterialRatings[string(material)] = rating
+		}
+	}
+	if len(dbProvider.ServiceArea) != 0 {
+		provider.ServiceArea = dbProvider.ServiceArea.GeoJSON()
+	}
+	for _, branch := range dbProvider.Branches {
+		provider.Branches = append(provider.Branches, fromDBBranch(branch))
+	}
+	provider.Availability = fromDBDateRanges(dbProvider.Availability)
+	provider.Blackouts = fromDBDateRanges(dbProvider.Blackouts)
+	provider.TimeZone = dbProvider.TimeZone
+	provider.WorkingHours = fromDBWorkingHours(dbProvider.WorkingHours)
+	return provider
+}
+
+func fromDBWorkingHours(dbHours []database.WorkingHours) []WorkingHours {
+	var hours []WorkingHours
+	for _, h := range dbHours {
+		hours = append(hours, WorkingHours{Weekday: strings.ToLower(h.Weekday.String()), Start: database.FormatClock(h.Start), End: database.FormatClock(h.End)})
+	}
+	return hours
+}
+
+func fromDBDateRanges(dbRanges []database.DateRange) []DateRange {
+	var ranges []DateRange
+	for _, r := range dbRanges {
+		ranges = append(ranges, DateRange{From: r.From.Format(database.DateLayout), To: r.To.Format(database.DateLayout)})
+	}
+	return ranges
+}
+
+func fromDBBranch(dbBranch database.Branch) Branch {
+	return Branch{
+		Name:            dbBranch.Name,
+		Address:         Address{Lat: dbBranch.Address.Lat, Long: dbBranch.Address.Long},
+		OperatingRadius: dbBranch.Radius,
+		RadiusUnit:      string(dbBranch.RadiusUnit),
+	}
+}
+
+// distanceOf expresses distance of a matched provider in unit of operating radius of its closest location
+func distanceOf(dbProvider database.Provider) *Distance {
+	unit := dbProvider.RadiusUnit
+	if dbProvider.ClosestBranch != nil {
+		unit = dbProvider.ClosestBranch.RadiusUnit
+	}
+	if !unit.Valid() {
+		unit = DefaultRadiusUnit
+	}
+	return &Distance{Value: unit.FromMeters(dbProvider.Distance), Unit: string(unit)}
+}
+
+func setExperience(dbProvider *database.Provider, experience []string) {
+	dbProvider.Materials = nil
+	for _, material := range experience {
+		dbProvider.Materials = append(dbProvider.Materials, database.FloorMaterial(material))
+	}
+}
+
+func setRates(dbProvider *database.Provider, rates map[string]float64) {
+	dbProvider.Rates = nil
+	if len(rates) == 0 {
+		return
+	}
+	dbProvider.Rates = make(map[database.FloorMaterial]float64, len(rates))
+	for material, rate := range rates {
+		dbProvider.Rates[database.FloorMaterial(material)] = rate
+	}
+}
+
+func setMaterialAreas(dbProvider *database.Provider, areas map[string]JobArea) {
+	dbProvider.MaterialJobAreas = nil
+	if len(areas) == 0 {
+		return
+	}
+	dbProvider.MaterialJobAreas = make(map[database.FloorMaterial]database.AreaRange, len(areas))
+	for material, area := range areas {
+		dbProvider.MaterialJobAreas[database.FloorMaterial(material)] = database.AreaRange{Min: area.MinArea, Max: area.MaxArea}
+	}
+}
+
+func setMaterialRadii(dbProvider *database.Provider, radii map[string]MaterialRadius) {
+	dbProvider.MaterialRadii = nil
+	if len(radii) == 0 {
+		return
+	}
+	dbProvider.MaterialRadii = make(map[database.FloorMaterial]database.MaterialRadius, len(radii))
+	for material, radius := range radii {
+		unit := database.DistanceUnit(radius.RadiusUnit)
+		if unit == "" {
+			unit = DefaultRadiusUnit
+		}
+		dbProvider.MaterialRadii[database.FloorMaterial(material)] = database.MaterialRadius{Radius: radius.OperatingRadius, RadiusUnit: unit}
+	}
+}
+
+func setMaterialRatings(dbProvider *database.Provider, ratings map[string]float64) {
+	dbProvider.MaterialRatings = nil
+	if len(ratings) == 0 {
+		return
+	}
+	dbProvider.MaterialRatings = make(map[database.FloorMaterial]float64, len(ratings))
+	for material, rating := range ratings {
+		dbProvider.MaterialRatings[database.FloorMaterial(material)] = rating
+	}
+}
+
+func setBranches(dbProvider *database.Provider, branches []Branch) {
+	dbProvider.Branches = nil
+	for _, branch := range branches {
+		dbBranch := database.Branch{
+			Name:       branch.Name,
+			Address:    database.Address{Lat: branch.Address.Lat, Long: branch.Address.Long},
+			Radius:     branch.OperatingRadius,
+			RadiusUnit: database.DistanceUnit(branch.RadiusUnit),
+		}
+		if dbBranch.RadiusUnit == "" {
+			dbBranch.RadiusUnit = DefaultRadiusUnit
+		}
+		dbProvider.Branches = append(dbProvider.Branches, dbBranch)
+	}
+}
+
+// toDBDateRanges converts date ranges of a request, format of dates is checked by binding
+func toDBDateRanges(ranges []DateRange) []database.DateRange {
+	var dbRanges []database.DateRange
+	for _, r := range ranges {
+		from, _ := time.Parse(database.DateLayout, r.From)
+		to, _ := time.Parse(database.DateLayout, r.To)
+		dbRanges = append(dbRanges, database.DateRange{From: from, To: to})
+	}
+	return dbRanges
+}
+
+// toDBWorkingHours converts working hours of a request, weekdays and format of times are checked by binding
+func toDBWorkingHours(hours []WorkingHours) []database.WorkingHours {
+	var dbHours []database.WorkingHours
+	for _, h := range hours {
+		weekday, _ := database.ParseWeekday(h.Weekday)
+		start, _ := database.ParseClock(h.Start)
+		end, _ := database.ParseClock(h.End)
+		dbHours = append(dbHours, database.WorkingHours{Weekday: weekday, Start: start, End: end})
+	}
+	return dbHours
+}
+
+// startWindow returns days a customer wants a job started in, from today on when no start date is requested.
+// until is zero when there is no limit
+func (req CustomerRequest) startWindow(today time.Time) (from, until time.Time) {
+	if req.DesiredStartDate == "" {
+		return today, time.Time{}
+	}
+	desired, _ := time.Parse(database.DateLayout, req.DesiredStartDate)
+	from, until = desired.AddDate(0, 0, -req.FlexibilityDays), desired.AddDate(0, 0, req.FlexibilityDays)
+	if from.Before(today) {
+		from = today
+	}
+	return from, until
+}
+
+// parseServiceArea reads a GeoJSON geometry of a request, absent or null geometry is no service area
+func parseServiceArea(data json.RawMessage) (database.ServiceArea, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	return database.ParseServiceArea(data)
+}
+
+// sortByPrice orders providers by estimated price keeping ranking order of equal prices, providers without
+// an estimate come last
+func sortByPrice(providers []Provider) {
+	sort.SliceStable(providers, func(i, j int) bool {
+		a, b := providers[i].EstimatedPrice, providers[j].EstimatedPrice
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+}
+
+func (req ProviderRequest) toDBProvider(id database.ID) (database.Provider, error) {
+	dbProvider := database.Provider{
+		ID:         id,
+		ExternalID: req.ExternalID,
+		Name:       req.Name,
+		Address: database.Address{
+			Lat:  req.Address.Lat,
+			Long: req.Address.Long,
+		},
+		Radius:        req.OperatingRadius,
+		RadiusUnit:    database.DistanceUnit(req.RadiusUnit),
+		Rating:        req.Rating,
+		MinimumCharge: req.MinimumCharge,
+		TravelRate:    req.TravelRate,
+		JobArea:       database.AreaRange{Min: req.MinArea, Max: req.MaxArea},
+	}
+	if dbProvider.RadiusUnit == "" {
+		dbProvider.RadiusUnit = DefaultRadiusUnit
+	}
+	setExperience(&dbProvider, req.Experience)
+	setRates(&dbProvider, req.Rates)
+	setMaterialAreas(&dbProvider, req.MaterialAreas)
+	setMaterialRadii(&dbProvider, req.MaterialRadii)
+	setMaterialRatings(&dbProvider, req.MaterialRatings)
+	setBranches(&dbProvider, req.Branches)
+	dbProvider.Availability = toDBDateRanges(req.Availability)
+	dbProvider.Blackouts = toDBDateRanges(req.Blackouts)
+	dbProvider.TimeZone = req.TimeZone
+	dbProvider.WorkingHours = toDBWorkingHours(req.WorkingHours)
+	var err error
+	dbProvider.ServiceArea, err = parseServiceArea(req.ServiceArea)
+	return dbProvider, err
+}
+
+func (patch ProviderPatch) apply(dbProvider *database.Provider) error {
+	if patch.ExternalID != nil {
+		dbProvider.ExternalID = *patch.ExternalID
+	}
+	if patch.Name != nil {
+		dbProvider.Name = *patch.Name
+	}
+	if patch.Experience != nil {
+		setExperience(dbProvider, *patch.Experience)
+	}
+	if patch.Address != nil {
+		dbProvider.Address.Lat = patch.Address.Lat
+		dbProvider.Address.Long = patch.Address.Long
+	}
+	if patch.OperatingRadius != nil {
+		dbProvider.Radius = *patch.OperatingRadius
+	}
+	if patch.RadiusUnit != nil {
+		dbProvider.RadiusUnit = database.DistanceUnit(*patch.RadiusUnit)
+	}
+	if patch.Rating != nil {
+		dbProvider.Rating = *patch.Rating
+	}
+	if patch.Rates != nil {
+		setRates(dbProvider, *patch.Rates)
+	}
+	if patch.MinimumCharge != nil {
+		dbProvider.MinimumCharge = *patch.MinimumCharge
+	}
+	if patch.TravelRate != nil {
+		dbProvider.TravelRate = *patch.TravelRate
+	}
+	if patch.MinArea != nil {
+		dbProvider.JobArea.Min = *patch.MinArea
+	}
+	if patch.MaxArea != nil {
+		dbProvider.JobArea.Max = *patch.MaxArea
+	}
+	if patch.MaterialAreas != nil {
+		setMaterialAreas(dbProvider, *patch.MaterialAreas)
+	}
+	if patch.MaterialRadii != nil {
+		setMaterialRadii(dbProvider, *patch.MaterialRadii)
+	}
+	if patch.MaterialRatings != nil {
+		setMaterialRatings(dbProvider, *patch.MaterialRatings)
+	}
+	if patch.Branches != nil {
+		setBranches(dbProvider, *patch.Branches)
+	}
+	if patch.Availability != nil {
+		dbProvider.Availability = toDBDateRanges(*patch.Availability)
+	}
+	if patch.Blackouts != nil {
+		dbProvider.Blackouts = toDBDateRanges(*patch.Blackouts)
+	}
+	if patch.TimeZone != nil {
+		dbProvider.TimeZone = *patch.TimeZone
+	}
+	if patch.WorkingHours != nil {
+		dbProvider.WorkingHours = toDBWorkingHours(*patch.WorkingHours)
+	}
+	if patch.ServiceArea != nil {
+		area, err := parseServiceArea(patch.ServiceArea)
+		if err != nil {
+			return err
+		}
+		dbProvider.ServiceArea = area
+	}
+	return nil
 }
 
-// GetProviders get a list of matching providers
+// getID reads id path parameter, name of the entity is used in error message
+func getID(ctx *gin.Context, entity string) (database.ID, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorResponse(ctx, http.StatusBadRequest, "invalid "+entity+" id", err)
+		return 0, false
+	}
+	return database.ID(id), true
+}
+
+func getProviderID(ctx *gin.Context) (database.ID, bool) {
+	return getID(ctx, "provider")
+}
+
+// GetProviders get a list of matching providers, request is saved as a lead
 func GetProviders(ctx *gin.Context) {
 	var req CustomerRequest
 	err := ctx.ShouldBindJSON(&req)
@@ -37,57 +554,240 @@ func GetProviders(ctx *gin.Context) {
 		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
 		return
 	}
-	db, exists := ctx.Get("db")
-	if !exists {
-		ErrorResponse(ctx, http.StatusInternalServerError, "storage instance is not present", nil)
+	from, until := req.startWindow(database.Day(time.Now()))
+	if !until.IsZero() && until.Before(from) {
+		ErrorResponse(ctx, http.StatusBadRequest, "desired start date is in the past", nil)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
 		return
 	}
 
-	var (
-		material database.FloorMaterial
-		location database.Address
-	)
-	switch req.Material {
-	case "wood":
-		material = database.FloorWood
-	case "carpet":
-		material = database.FloorCarpet
-	case "tile":
-		material = database.FloorTile
-	default:
-		ErrorResponse(ctx, http.StatusBadRequest, "floor material is not supported", nil)
+	if !checkMaterials(ctx, storage, req.Material) {
 		return
 	}
-	location.Lat = req.Address.Lat
-	location.Long = req.Address.Long
-	storage := db.(Storage)
-	dbProviders, err := storage.GetProviders(material, location)
+	location := database.Address{
+		Lat:  req.Address.Lat,
+		Long: req.Address.Long,
+	}
+	criteria := database.Criteria{Material: database.FloorMaterial(req.Material), Location: location, Area: req.Area, Now: time.Now().UTC()}
+	dbProviders, err := storage.GetProviders(ctx.Request.Context(), criteria)
 	if err != nil {
-		ErrorResponse(ctx, http.StatusInternalServerError, "db error", err)
+		StorageErrorResponse(ctx, err)
 		return
 	}
-	resp := []Provider{}
+	material := database.FloorMaterial(req.Material)
+	ranking := getRanking(ctx)
+	ranking.Sort(dbProviders, material)
+	resp := Matches{Providers: []Provider{}}
 	for _, dbProvider := range dbProviders {
-		provider := Provider{
-			Name: dbProvider.Name,
-			Address: Address{
-				Lat:  dbProvider.Address.Lat,
-				Long: dbProvider.Address.Long,
-			},
-			OperatingRadius: dbProvider.Radius,
-			Rating:          dbProvider.Rating,
+		provider := fromDBProvider(dbProvider)
+		provider.Distance = distanceOf(dbProvider)
+		if dbProvider.ClosestBranch != nil {
+			branch := fromDBBranch(*dbProvider.ClosestBranch)
+			provider.ClosestBranch = &branch
+		}
+		score := ranking.Score(dbProvider, material)
+		provider.RankingScore = &score
+		if price, ok := dbProvider.EstimatePrice(material, req.Area); ok {
+			provider.EstimatedPrice = &price
 		}
-		if dbProvider.Wood {
-			provider.Experience = append(provider.Experience, "wood")
+		if req.MaxBudget > 0 && (provider.EstimatedPrice == nil || *provider.EstimatedPrice > req.MaxBudget) {
+			continue
 		}
-		if dbProvider.Carpet {
-			provider.Experience = append(provider.Experience, "carpet")
+		next, ok := dbProvider.NextAvailableDay(from)
+		if !until.IsZero() && (!ok || next.After(until)) {
+			continue
 		}
-		if dbProvider.Tile {
-			provider.Experience = append(provider.Experience, "tile")
+		if ok {
+			provider.NextAvailableDate = next.Format(database.DateLayout)
 		}
-		resp = append(resp, provider)
+		resp.Providers = append(resp.Providers, provider)
+	}
+	if req.Sort == SortByPrice {
+		sortByPrice(resp.Providers)
+	}
+	lead := database.Lead{
+		Material:    material,
+		Address:     location,
+		Area:        req.Area,
+		PhoneNumber: req.PhoneNumber,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		ProviderIDs: []database.ID{},
+	}
+	for _, provider := range resp.Providers {
+		lead.ProviderIDs = append(lead.ProviderIDs, provider.ID)
+	}
+	getRouting(ctx).Offer(&lead, lead.CreatedAt)
+	resp.LeadID, err = storage.AddLead(ctx.Request.Context(), lead)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	if signer := getSigner(ctx); signer.Enabled() {
+		resp.LeadToken = signer.Sign(ScopeLead, resp.LeadID)
 	}
 
 	SuccessResponse(ctx, http.StatusOK, "list of providers", resp)
 }
+
+// AddProvider creates a new provider, it is pending until verified by admin
+func AddProvider(ctx *gin.Context) {
+	var req ProviderRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	if !checkMaterials(ctx, storage, req.Experience...) {
+		return
+	}
+
+	dbProvider, err := req.toDBProvider(0)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "invalid service area", err)
+		return
+	}
+	dbProvider.Status = database.StatusPending
+	id, err := storage.AddProvider(ctx.Request.Context(), dbProvider)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	dbProvider.ID = id
+
+	SuccessResponse(ctx, http.StatusCreated, "provider created", fromDBProvider(dbProvider))
+}
+
+// GetProvider returns a single provider
+func GetProvider(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "provider", fromDBProvider(dbProvider))
+}
+
+// UpdateProvider replaces an existing provider
+func UpdateProvider(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	var req ProviderRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	if !checkMaterials(ctx, storage, req.Experience...) {
+		return
+	}
+
+	dbProvider, err := req.toDBProvider(id)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "invalid service area", err)
+		return
+	}
+	err = storage.UpdateProvider(ctx.Request.Context(), dbProvider)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	// status and rating are kept by storage rather than taken from request, so the saved provider is returned
+	dbProvider, err = storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "provider updated", fromDBProvider(dbProvider))
+}
+
+// PatchProvider partially updates an existing provider
+func PatchProvider(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	var patch ProviderPatch
+	err := ctx.ShouldBindJSON(&patch)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	if patch.Experience != nil && !checkMaterials(ctx, storage, *patch.Experience...) {
+		return
+	}
+
+	dbProvider, err := storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	err = patch.apply(&dbProvider)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "invalid service area", err)
+		return
+	}
+	err = storage.UpdateProvider(ctx.Request.Context(), dbProvider)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	// status and rating are kept by storage rather than taken from request, so the saved provider is returned
+	dbProvider, err = storage.GetProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "provider updated", fromDBProvider(dbProvider))
+}
+
+// DeleteProvider removes a provider
+func DeleteProvider(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	err := storage.DeleteProvider(ctx.Request.Context(), id)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "provider deleted", nil)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"ah/database"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// DefaultPageLimit is number of items in a page when limit is not specified
+const DefaultPageLimit = 20
+
+// PageQuery contains pagination parameters of a list request
+type PageQuery struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+// ReviewRequest contains data of a new review, it is of the lead of the lead token
+type ReviewRequest struct {
+	Score int    `json:"score" binding:"required,min=1,max=5"`
+	Text  string `json:"text" binding:"max=2000"`
+}
+
+// Review is a customer review of a provider
+type Review struct {
+	ID         database.ID `json:"id"`
+	ProviderID database.ID `json:"provider_id"`
+	Score      int         `json:"score"`
+	Text       string      `json:"text"`
+	LeadID     database.ID `json:"lead_id,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Reviews is a page of reviews with total number of reviews
+type Reviews struct {
+	Total   int      `json:"total"`
+	Reviews []Review `json:"reviews"`
+}
+
+func fromDBReview(dbReview database.Review) Review {
+	return Review{
+		ID:         dbReview.ID,
+		ProviderID: dbReview.ProviderID,
+		Score:      dbReview.Score,
+		Text:       dbReview.Text,
+		LeadID:     dbReview.LeadID,
+		CreatedAt:  dbReview.CreatedAt,
+	}
+}
+
+func getPage(ctx *gin.Context) (database.Page, bool) {
+	var query PageQuery
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "invalid page", err)
+		return database.Page{}, false
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultPageLimit
+	}
+	return database.Page{Limit: query.Limit, Offset: query.Offset}, true
+}
+
+// AddReview adds a review of a provider by customer of a lead the provider accepted, once per lead.
+// rating of the provider is recomputed from its reviews
+func AddReview(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	var req ReviewRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbLead, err := storage.GetLead(ctx.Request.Context(), getAuthLead(ctx))
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	// only a provider who took the job is reviewed, so ratings come from its customers
+	offer, ok := dbLead.Offer(id)
+	if !ok || offer.Status != database.OfferAccepted {
+		ErrorResponse(ctx, http.StatusForbidden, "lead is not accepted by the provider", nil)
+		return
+	}
+	dbReview := database.Review{
+		ProviderID: id,
+		Score:      req.Score,
+		Text:       req.Text,
+		LeadID:     dbLead.ID,
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	dbReview.ID, err = storage.AddReview(ctx.Request.Context(), dbReview)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+
+	SuccessResponse(ctx, http.StatusCreated, "review created", fromDBReview(dbReview))
+}
+
+// GetReviews returns a page of reviews of a provider, newest first
+func GetReviews(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	page, ok := getPage(ctx)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbReviews, total, err := storage.GetReviews(ctx.Request.Context(), id, page)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	resp := Reviews{Total: total, Reviews: []Review{}}
+	for _, dbReview := range dbReviews {
+		resp.Reviews = append(resp.Reviews, fromDBReview(dbReview))
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "list of reviews", resp)
+}
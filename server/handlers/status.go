@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"ah/database"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// StatusRequest asks to move a provider to a status, providers can only pause and resume themselves
+type StatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active paused suspended"`
+	// ResumeAt is when a paused provider becomes active again, it stays paused until resumed without it
+	ResumeAt *time.Time `json:"resume_at"`
+	Reason   string     `json:"reason" binding:"max=255"`
+}
+
+// StatusChange is an audit entry of a status change of a provider
+type StatusChange struct {
+	ID         database.ID `json:"id"`
+	ProviderID database.ID `json:"provider_id"`
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	ResumeAt   *time.Time  `json:"resume_at,omitempty"`
+	Actor      string      `json:"actor"`
+	Reason     string      `json:"reason"`
+	ChangedAt  time.Time   `json:"changed_at"`
+}
+
+// StatusHistory is a page of status changes with total number of changes
+type StatusHistory struct {
+	Total   int            `json:"total"`
+	Changes []StatusChange `json:"changes"`
+}
+
+func fromDBStatusChange(dbChange database.StatusChange) StatusChange {
+	change := StatusChange{
+		ID:         dbChange.ID,
+		ProviderID: dbChange.ProviderID,
+		From:       string(dbChange.From),
+		To:         string(dbChange.To),
+		Actor:      string(dbChange.Actor),
+		Reason:     dbChange.Reason,
+		ChangedAt:  dbChange.ChangedAt,
+	}
+	if !dbChange.ResumeAt.IsZero() {
+		resumeAt := dbChange.ResumeAt
+		change.ResumeAt = &resumeAt
+	}
+	return change
+}
+
+// ChangeStatus returns a handler moving a provider to the requested status on behalf of actor
+func ChangeStatus(actor database.Actor) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, ok := getProviderID(ctx)
+		if !ok {
+			return
+		}
+		var req StatusRequest
+		err := ctx.ShouldBindJSON(&req)
+		if err != nil {
+			ErrorResponse(ctx, http.StatusBadRequest, "binding request failed", err)
+			return
+		}
+		storage, ok := getStorage(ctx)
+		if !ok {
+			return
+		}
+
+		change := database.StatusChange{
+			ProviderID: id,
+			To:         database.ProviderStatus(req.Status),
+			Actor:      actor,
+			Reason:     req.Reason,
+			ChangedAt:  time.Now().UTC().Truncate(time.Second),
+		}
+		if req.ResumeAt != nil {
+			change.ResumeAt = req.ResumeAt.UTC().Truncate(time.Second)
+		}
+		dbProvider, err := storage.ChangeProviderStatus(ctx.Request.Context(), change)
+		if err != nil {
+			StorageErrorResponse(ctx, err)
+			return
+		}
+
+		SuccessResponse(ctx, http.StatusOK, "status changed", fromDBProvider(dbProvider))
+	}
+}
+
+// GetStatusHistory returns a page of status changes of a provider, newest first
+func GetStatusHistory(ctx *gin.Context) {
+	id, ok := getProviderID(ctx)
+	if !ok {
+		return
+	}
+	page, ok := getPage(ctx)
+	if !ok {
+		return
+	}
+	storage, ok := getStorage(ctx)
+	if !ok {
+		return
+	}
+
+	dbChanges, total, err := storage.GetStatusHistory(ctx.Request.Context(), id, page)
+	if err != nil {
+		StorageErrorResponse(ctx, err)
+		return
+	}
+	resp := StatusHistory{Total: total, Changes: []StatusChange{}}
+	for _, dbChange := range dbChanges {
+		resp.Changes = append(resp.Changes, fromDBStatusChange(dbChange))
+	}
+
+	SuccessResponse(ctx, http.StatusOK, "status history", resp)
+}
@@ -1,8 +1,32 @@
 package handlers
 
-import "ah/database"
+import (
+	"ah/database"
+	"context"
+	"time"
+)
 
-// Storage database contract required for handlers
+// Storage database contract required for handlers, ctx cancels calls when request is done
 type Storage interface {
-	GetProviders(material database.FloorMaterial, location database.Address) ([]database.Provider, error)
+	GetProviders(ctx context.Context, criteria database.Criteria) ([]database.Provider, error)
+	GetProvider(ctx context.Context, id database.ID) (database.Provider, error)
+	AddProvider(ctx context.Context, p database.Provider) (database.ID, error)
+	UpdateProvider(ctx context.Context, p database.Provider) error
+	DeleteProvider(ctx context.Context, id database.ID) error
+	GetMaterials(ctx context.Context) ([]database.Material, error)
+	ImportProviders(ctx context.Context, providers []database.Provider, options database.ImportOptions) ([]database.ImportResult, error)
+	ExportProviders(ctx context.Context, f func(database.Provider) error) error
+	AddReview(ctx context.Context, r database.Review) (database.ID, error)
+	GetReviews(ctx context.Context, providerID database.ID, page database.Page) ([]database.Review, int, error)
+	AddLead(ctx context.Context, lead database.Lead) (database.ID, error)
+	GetLead(ctx context.Context, id database.ID) (database.Lead, error)
+	GetOfferedLeads(ctx context.Context, providerID database.ID, page database.Page) ([]database.Lead, int, error)
+	ApplyLeadAction(ctx context.Context, id, providerID database.ID, action database.LeadAction, now time.Time) (database.Lead, error)
+	ChangeProviderStatus(ctx context.Context, change database.StatusChange) (database.Provider, error)
+	GetStatusHistory(ctx context.Context, providerID database.ID, page database.Page) ([]database.StatusChange, int, error)
+	BookAppointment(ctx context.Context, a database.Appointment) (database.ID, error)
+	GetAppointment(ctx context.Context, id database.ID) (database.Appointment, error)
+	GetAppointments(ctx context.Context, providerID database.ID, from, to time.Time) ([]database.Appointment, error)
+	RescheduleAppointment(ctx context.Context, id database.ID, start, end, now time.Time) (database.Appointment, error)
+	CancelAppointment(ctx context.Context, id database.ID, now time.Time) (database.Appointment, error)
 }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"encoding/json"
+	"errors"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	initTest(t, nil)
+	resp := execRequest(http.MethodGet, "/health", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	Expect(resp.Body.Close()).To(BeNil())
+	response := handlers.Response{
+		Data: &handlers.Health{},
+	}
+	err = json.Unmarshal(respBody, &response)
+	Expect(err).To(BeNil())
+	Expect(*response.Data.(*handlers.Health)).To(Equal(handlers.Health{
+		Status: "ok",
+		Pool:   &database.PoolStats{MaxOpen: 20, Open: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDuration: time.Millisecond},
+	}))
+}
+
+func TestHealthStorageDown(t *testing.T) {
+	initTest(t, nil)
+	db.PingFunc = func() error {
+		return errors.New("connection refused")
+	}
+	resp := execRequest(http.MethodGet, "/health", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+	Expect(resp.Body.Close()).To(BeNil())
+}
+
+func TestMetrics(t *testing.T) {
+	initTest(t, nil)
+	resp := execRequest(http.MethodGet, "/debug/vars", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	Expect(resp.Body.Close()).To(BeNil())
+	resp = execTokenRequest(testAdminToken, http.MethodGet, "/debug/vars", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	Expect(resp.Body.Close()).To(BeNil())
+	var metrics struct {
+		DatabasePool database.PoolStats `json:"database_pool"`
+	}
+	err = json.Unmarshal(respBody, &metrics)
+	Expect(err).To(BeNil())
+	Expect(metrics.DatabasePool.InUse).To(Equal(1))
+	Expect(metrics.DatabasePool.WaitCount).To(Equal(int64(4)))
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"context"
+	"encoding/json"
+	"errors"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetProvidersSavesLead(t *testing.T) {
+	wood := []database.FloorMaterial{database.FloorWood}
+	initTest(t, []database.Provider{
+		{ID: 4, Name: "p4", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Materials: wood},
+		{ID: 9, Name: "p9", Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, ReviewCount: 100, Materials: wood},
+	})
+	var saved database.Lead
+	db.AddLeadFunc = func(lead database.Lead) (database.ID, error) {
+		saved = lead
+		return 12, nil
+	}
+	body, err := json.Marshal(defaultRequest)
+	Expect(err).To(BeNil())
+	var matches handlers.Matches
+	status := sendDataRequest(http.MethodPost, "/get_providers", string(body), &matches)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(matches.LeadID).To(Equal(database.ID(12)))
+	Expect(matches.LeadToken).To(Equal(leadToken(12)))
+	Expect(matches.Providers).To(HaveLen(2))
+	Expect(saved.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	expires := saved.CreatedAt.Add(24 * time.Hour)
+	saved.CreatedAt = time.Time{}
+	// providers are saved in order they are shown and the lead is offered to them
+	Expect(saved).To(Equal(database.Lead{
+		Material:    database.FloorWood,
+		Address:     database.Address{Lat: defaultRequest.Address.Lat, Long: defaultRequest.Address.Long},
+		Area:        defaultRequest.Area,
+		PhoneNumber: defaultRequest.PhoneNumber,
+		ProviderIDs: []database.ID{9, 4},
+		Status:      database.LeadOffered,
+		Offers: []database.LeadOffer{
+			{ProviderID: 9, Status: database.OfferPending, ExpiresAt: expires},
+			{ProviderID: 4, Status: database.OfferPending, ExpiresAt: expires},
+		},
+	}))
+
+	// a request without matches is saved too
+	initTest(t, nil)
+	db.AddLeadFunc = func(lead database.Lead) (database.ID, error) {
+		saved = lead
+		return 13, nil
+	}
+	status = sendDataRequest(http.MethodPost, "/get_providers", string(body), &matches)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(matches.LeadID).To(Equal(database.ID(13)))
+	Expect(matches.Providers).To(BeEmpty())
+	Expect(saved.ProviderIDs).To(BeEmpty())
+	Expect(saved.Offers).To(BeEmpty())
+	Expect(saved.Status).To(Equal(database.LeadExpired))
+
+	db.AddLeadFunc = func(database.Lead) (database.ID, error) {
+		return 0, context.DeadlineExceeded
+	}
+	_, status = sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusGatewayTimeout))
+}
+
+func TestGetLead(t *testing.T) {
+	initTest(t, nil)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db.GetLeadFunc = func(id database.ID) (database.Lead, error) {
+		if id != 12 {
+			return database.Lead{}, database.ErrNotFound
+		}
+		return database.Lead{ID: 12, Material: database.FloorTile, Address: database.Address{Lat: 1, Long: 2}, Area: 30, PhoneNumber: "1-800-2", CreatedAt: created, ProviderIDs: []database.ID{9, 4},
+			Status: database.LeadOffered, Offers: []database.LeadOffer{{ProviderID: 9, Status: database.OfferPending, ExpiresAt: created.Add(time.Hour)}}}, nil
+	}
+	var lead handlers.Lead
+	status := sendTokenDataRequest(testAdminToken, http.MethodGet, "/v1/admin/leads/12", "", &lead)
+	Expect(status).To(Equal(http.StatusOK))
+	// offer made long ago is shown expired
+	Expect(lead).To(Equal(handlers.Lead{ID: 12, Material: "tile", Address: handlers.Address{Lat: 1, Long: 2}, Area: 30, PhoneNumber: "1-800-2", CreatedAt: created, ProviderIDs: []database.ID{9, 4},
+		Status: "expired", Offers: []handlers.LeadOffer{{ProviderID: 9, Status: "expired", ExpiresAt: created.Add(time.Hour)}}}))
+
+	status = sendTokenDataRequest(testAdminToken, http.MethodGet, "/v1/admin/leads/13", "", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+	status = sendTokenDataRequest(testAdminToken, http.MethodGet, "/v1/admin/leads/x", "", nil)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestGetOfferedLead(t *testing.T) {
+	initTest(t, nil)
+	created := time.Now().UTC().Truncate(time.Second)
+	expires := created.Add(time.Hour)
+	db.GetLeadFunc = func(id database.ID) (database.Lead, error) {
+		if id != 12 {
+			return database.Lead{}, database.ErrNotFound
+		}
+		return database.Lead{ID: 12, Material: database.FloorTile, Address: database.Address{Lat: 1, Long: 2}, Area: 30, PhoneNumber: "1-800-2", CreatedAt: created, ProviderIDs: []database.ID{9, 4, 7},
+			Status: database.LeadAccepted, Offers: []database.LeadOffer{
+				{ProviderID: 9, Status: database.OfferAccepted, ExpiresAt: expires},
+				{ProviderID: 4, Status: database.OfferWithdrawn, ExpiresAt: expires},
+			}}, nil
+	}
+	expected := handlers.OfferedLead{ID: 12, Material: "tile", Address: handlers.Address{Lat: 1, Long: 2}, Area: 30, CreatedAt: created, Status: "accepted", ExpiresAt: expires}
+
+	// phone number is revealed only to provider who accepted the lead
+	var lead handlers.OfferedLead
+	status := sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/leads/12", "", &lead)
+	Expect(status).To(Equal(http.StatusOK))
+	accepted := expected
+	accepted.PhoneNumber, accepted.OfferStatus = "1-800-2", "accepted"
+	Expect(lead).To(Equal(accepted))
+
+	lead = handlers.OfferedLead{}
+	status = sendTokenDataRequest(providerToken(4), http.MethodGet, "/v1/leads/12", "", &lead)
+	Expect(status).To(Equal(http.StatusOK))
+	withdrawn := expected
+	withdrawn.OfferStatus = "withdrawn"
+	Expect(lead).To(Equal(withdrawn))
+
+	// lead shown to a provider without offering it is hidden
+	status = sendTokenDataRequest(providerToken(7), http.MethodGet, "/v1/leads/12", "", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+	status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/leads/13", "", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	// provider is taken from the token, a query parameter does not count
+	status = sendDataRequest(http.MethodGet, "/v1/leads/12?provider_id=9", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	forged := strings.Replace(providerToken(7), "7.", "9.", 1)
+	status = sendTokenDataRequest(forged, http.MethodGet, "/v1/leads/12", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	status = sendTokenDataRequest(testAdminToken, http.MethodGet, "/v1/leads/12", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+}
+
+func TestGetOfferedLeads(t *testing.T) {
+	initTest(t, nil)
+	created := time.Now().UTC().Truncate(time.Second)
+	expires := created.Add(time.Hour)
+	var requested database.Page
+	var requestedProvider database.ID
+	db.GetOfferedLeadsFunc = func(providerID database.ID, page database.Page) ([]database.Lead, int, error) {
+		requestedProvider, requested = providerID, page
+		return []database.Lead{
+			{ID: 12, Material: database.FloorTile, Area: 30, PhoneNumber: "1-800-2", CreatedAt: created, ProviderIDs: []database.ID{9, 4}, Status: database.LeadAccepted,
+				Offers: []database.LeadOffer{{ProviderID: 9, Status: database.OfferAccepted, ExpiresAt: expires}, {ProviderID: 4, Status: database.OfferWithdrawn, ExpiresAt: expires}}},
+			{ID: 11, Material: database.FloorWood, Area: 20, PhoneNumber: "1-800-1", CreatedAt: created.Add(-2 * time.Hour), ProviderIDs: []database.ID{9}, Status: database.LeadOffered,
+				Offers: []database.LeadOffer{{ProviderID: 9, Status: database.OfferPending, ExpiresAt: created.Add(-time.Hour)}}},
+		}, 7, nil
+	}
+
+	// leads are seen as by the provider, phone number only when accepted and unanswered offers expired
+	var leads handlers.OfferedLeads
+	status := sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/leads", "", &leads)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(requestedProvider).To(Equal(database.ID(9)))
+	Expect(requested).To(Equal(database.Page{Limit: handlers.DefaultPageLimit}))
+	Expect(leads).To(Equal(handlers.OfferedLeads{Total: 7, Leads: []handlers.OfferedLead{
+		{ID: 12, Material: "tile", Area: 30, PhoneNumber: "1-800-2", CreatedAt: created, Status: "accepted", OfferStatus: "accepted", ExpiresAt: expires},
+		{ID: 11, Material: "wood", Area: 20, CreatedAt: created.Add(-2 * time.Hour), Status: "expired", OfferStatus: "expired", ExpiresAt: created.Add(-time.Hour)},
+	}}))
+
+	status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/leads?limit=5&offset=10", "", nil)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(requested).To(Equal(database.Page{Limit: 5, Offset: 10}))
+	for _, query := range []string{"?limit=101", "?offset=-1", "?limit=x"} {
+		status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/leads"+query, "", nil)
+		Expect(status).To(Equal(http.StatusBadRequest))
+	}
+
+	// provider is taken from the token
+	status = sendDataRequest(http.MethodGet, "/v1/leads", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	status = sendTokenDataRequest(testAdminToken, http.MethodGet, "/v1/leads", "", nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+
+	db.GetOfferedLeadsFunc = func(providerID database.ID, page database.Page) ([]database.Lead, int, error) {
+		return nil, 0, errors.New("database error")
+	}
+	status = sendTokenDataRequest(providerToken(9), http.MethodGet, "/v1/leads", "", nil)
+	Expect(status).To(Equal(http.StatusInternalServerError))
+}
+
+func TestLeadAction(t *testing.T) {
+	initTest(t, nil)
+	var applied database.LeadAction
+	var now time.Time
+	db.ApplyLeadActionFunc = func(id, providerID database.ID, action database.LeadAction, at time.Time) (database.Lead, error) {
+		applied, now = action, at
+		lead := database.Lead{ID: id, PhoneNumber: "1-800-2", Status: database.LeadOffered, Offers: []database.LeadOffer{
+			{ProviderID: 9, Status: database.OfferPending, ExpiresAt: time.Now().Add(time.Hour)},
+		}}
+		err := lead.Apply(providerID, action, at)
+		return lead, err
+	}
+
+	var lead handlers.OfferedLead
+	status := sendTokenDataRequest(providerToken(9), http.MethodPost, "/v1/leads/12/accept", "", &lead)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(applied).To(Equal(database.ActionAccept))
+	Expect(now).To(BeTemporally("~", time.Now(), time.Minute))
+	Expect(lead.Status).To(Equal("accepted"))
+	Expect(lead.OfferStatus).To(Equal("accepted"))
+	Expect(lead.PhoneNumber).To(Equal("1-800-2"))
+
+	lead = handlers.OfferedLead{}
+	status = sendTokenDataRequest(providerToken(9), http.MethodPost, "/v1/leads/12/decline", "", &lead)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(applied).To(Equal(database.ActionDecline))
+	Expect(lead.Status).To(Equal("expired"))
+	Expect(lead.PhoneNumber).To(BeEmpty())
+
+	for path, action := range map[string]database.LeadAction{"contact": database.ActionContact, "win": database.ActionWin, "lose": database.ActionLose} {
+		status = sendTokenDataRequest(providerToken(9), http.MethodPost, "/v1/leads/12/"+path, "", nil)
+		Expect(status).To(Equal(http.StatusConflict))
+		Expect(applied).To(Equal(action))
+	}
+
+	status = sendTokenDataRequest(providerToken(4), http.MethodPost, "/v1/leads/12/accept", "", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+	status = sendTokenDataRequest(providerToken(9), http.MethodPost, "/v1/leads/x/accept", "", nil)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	// acting for another provider needs its token
+	applied = ""
+	status = sendDataRequest(http.MethodPost, "/v1/leads/12/accept", `{"provider_id":9}`, nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	Expect(applied).To(BeEmpty())
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	"encoding/json"
+	"errors"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestGetMaterials(t *testing.T) {
+	initTest(t, nil)
+	resp := execRequest(http.MethodGet, "/v1/materials", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	Expect(resp.Body.Close()).To(BeNil())
+	response := handlers.Response{
+		Data: &[]handlers.Material{},
+	}
+	err = json.Unmarshal(respBody, &response)
+	Expect(err).To(BeNil())
+	Expect(*response.Data.(*[]handlers.Material)).To(Equal([]handlers.Material{
+		{ID: 1, Name: "wood"},
+		{ID: 2, Name: "carpet"},
+		{ID: 3, Name: "tile"},
+	}))
+}
+
+func TestGetMaterialsDBError(t *testing.T) {
+	initTest(t, nil)
+	db.GetMaterialsFunc = func() ([]database.Material, error) {
+		return nil, errors.New("database error")
+	}
+	resp := execRequest(http.MethodGet, "/v1/materials", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"testing"
+)
+
+func TestGetProvidersPrice(t *testing.T) {
+	wood := []database.FloorMaterial{database.FloorWood}
+	initTest(t, []database.Provider{
+		{ID: 1, Name: "p1", Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, ReviewCount: 100, Materials: wood, Rates: map[database.FloorMaterial]float64{database.FloorWood: 30}},
+		{ID: 2, Name: "p2", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, ReviewCount: 100, Materials: wood},
+		// minimum charge applies to small jobs, travel is charged per kilometre
+		{ID: 3, Name: "p3", Radius: 10, RadiusUnit: database.Kilometre, Rating: 3, ReviewCount: 100, Materials: wood, Distance: 2500,
+			Rates: map[database.FloorMaterial]float64{database.FloorWood: 10}, MinimumCharge: 500, TravelRate: 2},
+	})
+	req := defaultRequest
+	req.Area = 20
+	providers, status := sendRequest(req)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(providers).To(HaveLen(3))
+	Expect(ids(providers)).To(Equal([]database.ID{1, 2, 3}))
+	Expect(*providers[0].EstimatedPrice).To(Equal(600.0))
+	Expect(providers[0].Rates).To(Equal(map[string]float64{"wood": 30}))
+	Expect(providers[1].EstimatedPrice).To(BeNil())
+	Expect(*providers[2].EstimatedPrice).To(Equal(505.0))
+
+	// providers without an estimate come last when sorted by price
+	req.Sort = handlers.SortByPrice
+	providers, status = sendRequest(req)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(ids(providers)).To(Equal([]database.ID{3, 1, 2}))
+
+	// providers above budget or without an estimate are left out
+	req.MaxBudget = 550
+	providers, status = sendRequest(req)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(ids(providers)).To(Equal([]database.ID{3}))
+
+	req.MaxBudget = -1
+	_, status = sendRequest(req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	req.MaxBudget = 0
+	req.Sort = "name"
+	_, status = sendRequest(req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestProviderRates(t *testing.T) {
+	initTest(t, nil)
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	req := defaultProviderRequest
+	req.Rates = map[string]float64{"wood": 25.5}
+	req.MinimumCharge = 300
+	req.TravelRate = 1.5
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.Rates).To(Equal(map[database.FloorMaterial]float64{database.FloorWood: 25.5}))
+	Expect(added.MinimumCharge).To(Equal(300.0))
+	Expect(added.TravelRate).To(Equal(1.5))
+	Expect(provider.Rates).To(Equal(req.Rates))
+	Expect(provider.MinimumCharge).To(Equal(300.0))
+	Expect(provider.TravelRate).To(Equal(1.5))
+
+	req.Rates = map[string]float64{"wood": 0}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	req.Rates = nil
+	req.TravelRate = -1
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	stored := added
+	stored.ID = 12
+	db.GetProviderFunc = func(database.ID) (database.Provider, error) {
+		return stored, nil
+	}
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		stored = p
+		return nil
+	}
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"rates": map[string]float64{"tile": 40}, "minimum_charge": 0})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored.Rates).To(Equal(map[database.FloorMaterial]float64{database.FloorTile: 40}))
+	Expect(stored.MinimumCharge).To(BeZero())
+	Expect(stored.TravelRate).To(Equal(1.5))
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"rates": map[string]float64{"tile": -4}})
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func ids(providers []handlers.Provider) []database.ID {
+	res := []database.ID{}
+	for _, provider := range providers {
+		res = append(res, provider.ID)
+	}
+	return res
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"ah/database"
 	"ah/server/handlers"
+	"context"
 	"encoding/json"
 	"errors"
 	jsoniter "github.com/json-iterator/go"
@@ -10,25 +11,162 @@ import (
 	"go.uber.org/zap"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 	"time"
 )
 
 type MockDB struct {
-	GetProvidersFunc func(material database.FloorMaterial, location database.Address) ([]database.Provider, error)
+	GetProvidersFunc          func(criteria database.Criteria) ([]database.Provider, error)
+	GetProviderFunc           func(id database.ID) (database.Provider, error)
+	AddProviderFunc           func(p database.Provider) (database.ID, error)
+	UpdateProviderFunc        func(p database.Provider) error
+	DeleteProviderFunc        func(id database.ID) error
+	GetMaterialsFunc          func() ([]database.Material, error)
+	ImportProvidersFunc       func(providers []database.Provider, options database.ImportOptions) ([]database.ImportResult, error)
+	ExportProvidersFunc       func(f func(database.Provider) error) error
+	AddReviewFunc             func(r database.Review) (database.ID, error)
+	GetReviewsFunc            func(providerID database.ID, page database.Page) ([]database.Review, int, error)
+	AddLeadFunc               func(lead database.Lead) (database.ID, error)
+	GetLeadFunc               func(id database.ID) (database.Lead, error)
+	GetOfferedLeadsFunc       func(providerID database.ID, page database.Page) ([]database.Lead, int, error)
+	ApplyLeadActionFunc       func(id, providerID database.ID, action database.LeadAction, now time.Time) (database.Lead, error)
+	ChangeProviderStatusFunc  func(change database.StatusChange) (database.Provider, error)
+	GetStatusHistoryFunc      func(providerID database.ID, page database.Page) ([]database.StatusChange, int, error)
+	BookAppointmentFunc       func(a database.Appointment) (database.ID, error)
+	GetAppointmentFunc        func(id database.ID) (database.Appointment, error)
+	GetAppointmentsFunc       func(providerID database.ID, from, to time.Time) ([]database.Appointment, error)
+	RescheduleAppointmentFunc func(id database.ID, start, end, now time.Time) (database.Appointment, error)
+	CancelAppointmentFunc     func(id database.ID, now time.Time) (database.Appointment, error)
+	PingFunc                  func() error
+	PoolStatsFunc             func() database.PoolStats
 }
 
-func (db MockDB) GetProviders(material database.FloorMaterial, location database.Address) ([]database.Provider, error) {
-	return db.GetProvidersFunc(material, location)
+func (db MockDB) GetProviders(_ context.Context, criteria database.Criteria) ([]database.Provider, error) {
+	return db.GetProvidersFunc(criteria)
 }
 
+func (db MockDB) GetProvider(_ context.Context, id database.ID) (database.Provider, error) {
+	return db.GetProviderFunc(id)
+}
+
+func (db MockDB) AddProvider(_ context.Context, p database.Provider) (database.ID, error) {
+	return db.AddProviderFunc(p)
+}
+
+func (db MockDB) UpdateProvider(_ context.Context, p database.Provider) error {
+	return db.UpdateProviderFunc(p)
+}
+
+func (db MockDB) DeleteProvider(_ context.Context, id database.ID) error {
+	return db.DeleteProviderFunc(id)
+}
+
+func (db MockDB) GetMaterials(context.Context) ([]database.Material, error) {
+	return db.GetMaterialsFunc()
+}
+
+func (db MockDB) ImportProviders(_ context.Context, providers []database.Provider, options database.ImportOptions) ([]database.ImportResult, error) {
+	return db.ImportProvidersFunc(providers, options)
+}
+
+func (db MockDB) ExportProviders(_ context.Context, f func(database.Provider) error) error {
+	return db.ExportProvidersFunc(f)
+}
+
+func (db MockDB) AddReview(_ context.Context, r database.Review) (database.ID, error) {
+	return db.AddReviewFunc(r)
+}
+
+func (db MockDB) GetReviews(_ context.Context, providerID database.ID, page database.Page) ([]database.Review, int, error) {
+	return db.GetReviewsFunc(providerID, page)
+}
+
+func (db MockDB) AddLead(_ context.Context, lead database.Lead) (database.ID, error) {
+	return db.AddLeadFunc(lead)
+}
+
+func (db MockDB) GetLead(_ context.Context, id database.ID) (database.Lead, error) {
+	return db.GetLeadFunc(id)
+}
+
+func (db MockDB) GetOfferedLeads(_ context.Context, providerID database.ID, page database.Page) ([]database.Lead, int, error) {
+	return db.GetOfferedLeadsFunc(providerID, page)
+}
+
+func (db MockDB) ApplyLeadAction(_ context.Context, id, providerID database.ID, action database.LeadAction, now time.Time) (database.Lead, error) {
+	return db.ApplyLeadActionFunc(id, providerID, action, now)
+}
+
+func (db MockDB) ChangeProviderStatus(_ context.Context, change database.StatusChange) (database.Provider, error) {
+	return db.ChangeProviderStatusFunc(change)
+}
+
+func (db MockDB) GetStatusHistory(_ context.Context, providerID database.ID, page database.Page) ([]database.StatusChange, int, error) {
+	return db.GetStatusHistoryFunc(providerID, page)
+}
+
+func (db MockDB) BookAppointment(_ context.Context, a database.Appointment) (database.ID, error) {
+	return db.BookAppointmentFunc(a)
+}
+
+func (db MockDB) GetAppointment(_ context.Context, id database.ID) (database.Appointment, error) {
+	return db.GetAppointmentFunc(id)
+}
+
+func (db MockDB) GetAppointments(_ context.Context, providerID database.ID, from, to time.Time) ([]database.Appointment, error) {
+	return db.GetAppointmentsFunc(providerID, from, to)
+}
+
+func (db MockDB) RescheduleAppointment(_ context.Context, id database.ID, start, end, now time.Time) (database.Appointment, error) {
+	return db.RescheduleAppointmentFunc(id, start, end, now)
+}
+
+func (db MockDB) CancelAppointment(_ context.Context, id database.ID, now time.Time) (database.Appointment, error) {
+	return db.CancelAppointmentFunc(id, now)
+}
+
+func (db MockDB) Ping(context.Context) error {
+	return db.PingFunc()
+}
+
+func (db MockDB) PoolStats() database.PoolStats {
+	return db.PoolStatsFunc()
+}
+
+// defaultRanking is ranking server uses without configuration
+var defaultRanking = database.Ranking{Method: database.RankBayesian, PriorMean: 4, PriorWeight: 10}
+
 var (
-	db             *MockDB
-	defaultRequest handlers.CustomerRequest
+	db                     *MockDB
+	defaultRequest         handlers.CustomerRequest
+	defaultProviderRequest handlers.ProviderRequest
+)
+
+const (
+	// testAdminToken is admin token of the test server
+	testAdminToken = "test-admin-token"
+	// testTokenSecret signs provider tokens of the test server
+	testTokenSecret = "test-token-secret"
 )
 
+// providerToken returns token of a provider valid for the test server
+func providerToken(id database.ID) string {
+	return handlers.NewSigner(testTokenSecret).Sign(handlers.ScopeProvider, id)
+}
+
+func leadToken(id database.ID) string {
+	return handlers.NewSigner(testTokenSecret).Sign(handlers.ScopeLead, id)
+}
+
 func TestMain(m *testing.M) {
+	if err := os.Setenv("AH_FLOORS_ADMIN_TOKEN", testAdminToken); err != nil {
+		panic(err)
+	}
+	if err := os.Setenv("AH_FLOORS_TOKEN_SECRET", testTokenSecret); err != nil {
+		panic(err)
+	}
 	db = &MockDB{}
 	s, err := NewServer(zap.NewNop(), db)
 	if err != nil {
@@ -50,11 +188,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66119,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 3.5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    2,
+			Rating:    3.5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			ID:   1,
@@ -63,11 +199,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66120,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 4.5,
-			Wood:   false,
-			Carpet: true,
-			Tile:   true,
+			Radius:    2,
+			Rating:    4.5,
+			Materials: []database.FloorMaterial{database.FloorCarpet, database.FloorTile},
 		},
 		{
 			ID:   2,
@@ -76,11 +210,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66116,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 4.5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
+			Radius:    2,
+			Rating:    4.5,
+			Materials: []database.FloorMaterial{database.FloorWood},
 		},
 		{
 			ID:   3,
@@ -89,11 +221,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66117,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 4.7,
-			Wood:   true,
-			Carpet: true,
-			Tile:   false,
+			Radius:    2,
+			Rating:    4.7,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet},
 		},
 		{
 			ID:   4,
@@ -102,11 +232,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66115,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 4.5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
+			Radius:    2,
+			Rating:    4.5,
+			Materials: []database.FloorMaterial{database.FloorWood},
 		},
 		{
 			ID:   5,
@@ -115,11 +243,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66118,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 4.1,
-			Wood:   true,
-			Carpet: false,
-			Tile:   true,
+			Radius:    2,
+			Rating:    4.1,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorTile},
 		},
 		{
 			ID:   6,
@@ -128,11 +254,9 @@ func TestGetProviders(t *testing.T) {
 				Lat:  -26.66116,
 				Long: 40.95858,
 			},
-			Radius: 2,
-			Rating: 4.8,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
+			Radius:    2,
+			Rating:    4.8,
+			Materials: []database.FloorMaterial{database.FloorWood},
 		},
 	}
 	initTest(t, dbProviders)
@@ -151,6 +275,40 @@ func TestInvalidMaterial(t *testing.T) {
 	Expect(response).To(BeEmpty())
 }
 
+func TestCatalogueMaterial(t *testing.T) {
+	dbProviders := []database.Provider{
+		{
+			Name: "p0",
+			Address: database.Address{
+				Lat:  -26.66129,
+				Long: 40.95858,
+			},
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{"laminate"},
+		},
+	}
+	initTest(t, dbProviders)
+	var requested database.FloorMaterial
+	db.GetProvidersFunc = func(criteria database.Criteria) ([]database.Provider, error) {
+		requested = criteria.Material
+		return dbProviders, nil
+	}
+	db.GetMaterialsFunc = func() ([]database.Material, error) {
+		return []database.Material{{ID: 1, Name: database.FloorWood}, {ID: 4, Name: "laminate"}}, nil
+	}
+	req := defaultRequest
+	req.Material = "laminate"
+	response, status := sendRequest(req)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(requested).To(Equal(database.FloorMaterial("laminate")))
+	Expect(response).To(Equal(convertFromDBProviders(dbProviders)))
+
+	req.Material = "tile"
+	_, status = sendRequest(req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
 func TestInvalidArea(t *testing.T) {
 	initTest(t, nil)
 	req := defaultRequest
@@ -171,7 +329,7 @@ func TestInvalidPhoneNumber(t *testing.T) {
 
 func TestDBError(t *testing.T) {
 	initTest(t, nil)
-	db.GetProvidersFunc = func(material database.FloorMaterial, location database.Address) ([]database.Provider, error) {
+	db.GetProvidersFunc = func(database.Criteria) ([]database.Provider, error) {
 		return nil, errors.New("database error")
 	}
 	req := defaultRequest
@@ -190,9 +348,6 @@ func TestGetProvidersMatching(t *testing.T) {
 			},
 			Radius: 10,
 			Rating: 5,
-			Wood:   false,
-			Carpet: false,
-			Tile:   false,
 		},
 		{
 			Name: "p1",
@@ -200,11 +355,9 @@ func TestGetProvidersMatching(t *testing.T) {
 				Lat:  -26,
 				Long: 40,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   false,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood},
 		},
 		{
 			Name: "p2",
@@ -212,11 +365,9 @@ func TestGetProvidersMatching(t *testing.T) {
 				Lat:  -26,
 				Long: 40,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: true,
-			Tile:   false,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorCarpet},
 		},
 		{
 			Name: "p3",
@@ -224,11 +375,9 @@ func TestGetProvidersMatching(t *testing.T) {
 				Lat:  -26,
 				Long: 40,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: false,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorTile},
 		},
 		{
 			Name: "p4",
@@ -236,11 +385,9 @@ func TestGetProvidersMatching(t *testing.T) {
 				Lat:  -26,
 				Long: 40,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   false,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet},
 		},
 		{
 			Name: "p5",
@@ -248,11 +395,9 @@ func TestGetProvidersMatching(t *testing.T) {
 				Lat:  -26,
 				Long: 40,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: false,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorTile},
 		},
 		{
 			Name: "p6",
@@ -260,11 +405,9 @@ func TestGetProvidersMatching(t *testing.T) {
 				Lat:  -26,
 				Long: 40,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   false,
-			Carpet: true,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorCarpet, database.FloorTile},
 		},
 	}
 	initTest(t, dbProviders)
@@ -282,11 +425,9 @@ func TestGetProvidersOutOfRadius(t *testing.T) {
 				Lat:  -26.66119,
 				Long: 40.95858,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			Name: "p1",
@@ -294,11 +435,9 @@ func TestGetProvidersOutOfRadius(t *testing.T) {
 				Lat:  -26.66129,
 				Long: 40.95858,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			Name: "p2",
@@ -306,11 +445,9 @@ func TestGetProvidersOutOfRadius(t *testing.T) {
 				Lat:  -26.66119,
 				Long: 40.95868,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			Name: "p3",
@@ -318,11 +455,9 @@ func TestGetProvidersOutOfRadius(t *testing.T) {
 				Lat:  -26.66129,
 				Long: 40.95868,
 			},
-			Radius: 10,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    10,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 	}
 	initTest(t, dbProviders)
@@ -340,11 +475,9 @@ func TestGetProvidersOrder(t *testing.T) {
 				Lat:  -26.66129,
 				Long: 40.95858,
 			},
-			Radius: 100,
-			Rating: 3.5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    100,
+			Rating:    3.5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			Name: "p1",
@@ -352,11 +485,9 @@ func TestGetProvidersOrder(t *testing.T) {
 				Lat:  -26.66139,
 				Long: 40.95858,
 			},
-			Radius: 100,
-			Rating: 3.5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    100,
+			Rating:    3.5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			Name: "p2",
@@ -364,11 +495,9 @@ func TestGetProvidersOrder(t *testing.T) {
 				Lat:  -26.66139,
 				Long: 40.95878,
 			},
-			Radius: 100,
-			Rating: 5,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    100,
+			Rating:    5,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 		{
 			Name: "p3",
@@ -376,11 +505,9 @@ func TestGetProvidersOrder(t *testing.T) {
 				Lat:  -26.66119,
 				Long: 40.95858,
 			},
-			Radius: 10,
-			Rating: 3.0,
-			Wood:   true,
-			Carpet: true,
-			Tile:   true,
+			Radius:    10,
+			Rating:    3.0,
+			Materials: []database.FloorMaterial{database.FloorWood, database.FloorCarpet, database.FloorTile},
 		},
 	}
 	initTest(t, dbProviders)
@@ -390,6 +517,489 @@ func TestGetProvidersOrder(t *testing.T) {
 	Expect(response).To(Equal(convertFromDBProviders(dbProviders)))
 }
 
+func TestGetProvidersDistance(t *testing.T) {
+	wood := []database.FloorMaterial{database.FloorWood}
+	dbProviders := []database.Provider{
+		{ID: 1, Name: "p1", Address: database.Address{Lat: -26, Long: 40}, Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, Materials: wood, Distance: 2300},
+		{ID: 2, Name: "p2", Address: database.Address{Lat: -26, Long: 40}, Radius: 10, RadiusUnit: database.Mile, Rating: 5, Materials: wood, Distance: 3218.688},
+		{ID: 3, Name: "p3", Address: database.Address{Lat: -26, Long: 40}, Radius: 500, RadiusUnit: database.Metre, Rating: 5, Materials: wood, Distance: 120},
+	}
+	initTest(t, dbProviders)
+	response, status := sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(response).To(HaveLen(3))
+	Expect(*response[0].Distance).To(Equal(handlers.Distance{Value: 2.3, Unit: "km"}))
+	Expect(*response[1].Distance).To(Equal(handlers.Distance{Value: 2, Unit: "mi"}))
+	Expect(*response[2].Distance).To(Equal(handlers.Distance{Value: 120, Unit: "m"}))
+}
+
+func TestGetProvidersRanking(t *testing.T) {
+	wood := []database.FloorMaterial{database.FloorWood}
+	// storage orders by raw rating
+	dbProviders := []database.Provider{
+		{ID: 1, Name: "newcomer", Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, ReviewCount: 1, Materials: wood},
+		{ID: 2, Name: "unreviewed", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4.9, Materials: wood},
+		{ID: 3, Name: "veteran", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4.8, ReviewCount: 400, Materials: wood},
+	}
+	initTest(t, dbProviders)
+	response, status := sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(response).To(HaveLen(3))
+	Expect(response[0].Name).To(Equal("veteran"))
+	Expect(response[0].Rating).To(Equal(4.8))
+	Expect(response[0].ReviewCount).To(Equal(400))
+	Expect(*response[0].RankingScore).To(BeNumerically("~", 4.78, 0.01))
+	Expect(response[1].Name).To(Equal("newcomer"))
+	Expect(*response[1].RankingScore).To(BeNumerically("~", 4.09, 0.01))
+	Expect(response[2].Name).To(Equal("unreviewed"))
+	Expect(*response[2].RankingScore).To(Equal(4.0))
+
+	// a material rating is not backed by provider reviews, so it is ranked as unreviewed
+	rated := database.Provider{ID: 4, Name: "rated", Radius: 10, RadiusUnit: database.Kilometre, Rating: 5, ReviewCount: 400, Materials: wood,
+		MaterialRatings: map[database.FloorMaterial]float64{database.FloorWood: 5}}
+	veteran := database.Provider{ID: 3, Name: "veteran", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4.8, ReviewCount: 400, Materials: wood}
+	initTest(t, []database.Provider{rated, veteran})
+	response, status = sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(response).To(HaveLen(2))
+	Expect(response[0].Name).To(Equal("veteran"))
+	Expect(response[1].Name).To(Equal("rated"))
+	Expect(response[1].ReviewCount).To(Equal(400))
+	Expect(*response[1].RankingScore).To(Equal(4.0))
+}
+
+func TestAddProvider(t *testing.T) {
+	initTest(t, nil)
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	req := defaultProviderRequest
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added).To(Equal(database.Provider{
+		Name:       "p0",
+		Address:    database.Address{Lat: -26.66119, Long: 40.95858},
+		Radius:     10,
+		RadiusUnit: database.Kilometre,
+		Rating:     4.5,
+		Materials:  []database.FloorMaterial{database.FloorWood, database.FloorTile},
+		Status:     database.StatusPending,
+	}))
+	Expect(provider).To(Equal(handlers.Provider{
+		ID:              12,
+		Name:            "p0",
+		Experience:      []string{"wood", "tile"},
+		Address:         handlers.Address{Lat: -26.66119, Long: 40.95858},
+		OperatingRadius: 10,
+		RadiusUnit:      "km",
+		Rating:          4.5,
+		Status:          "pending",
+	}))
+
+	req.RadiusUnit = "mi"
+	provider, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.RadiusUnit).To(Equal(database.Mile))
+	Expect(provider.RadiusUnit).To(Equal("mi"))
+}
+
+func TestProviderJobArea(t *testing.T) {
+	initTest(t, nil)
+	var criteria database.Criteria
+	db.GetProvidersFunc = func(c database.Criteria) ([]database.Provider, error) {
+		criteria = c
+		return nil, nil
+	}
+	_, status := sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(criteria.Area).To(Equal(defaultRequest.Area))
+
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	req := defaultProviderRequest
+	req.MinArea, req.MaxArea = 50, 1000
+	req.MaterialAreas = map[string]handlers.JobArea{"tile": {MinArea: 10}}
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.JobArea).To(Equal(database.AreaRange{Min: 50, Max: 1000}))
+	Expect(added.MaterialJobAreas).To(Equal(map[database.FloorMaterial]database.AreaRange{database.FloorTile: {Min: 10}}))
+	Expect(provider.MinArea).To(Equal(50.0))
+	Expect(provider.MaxArea).To(Equal(1000.0))
+	Expect(provider.MaterialAreas).To(Equal(req.MaterialAreas))
+
+	req.MaxArea = 20
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	req.MaxArea = 0
+	req.MaterialAreas = map[string]handlers.JobArea{"tile": {MinArea: 10, MaxArea: 5}}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestProviderServiceArea(t *testing.T) {
+	initTest(t, nil)
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	square := []database.Address{{Lat: 10, Long: 10}, {Lat: 10, Long: 11}, {Lat: 11, Long: 11}, {Lat: 11, Long: 10}, {Lat: 10, Long: 10}}
+	req := defaultProviderRequest
+	req.ServiceArea = json.RawMessage(`{"type":"Polygon","coordinates":[[[10,10],[11,10],[11,11],[10,11],[10,10]]]}`)
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.ServiceArea).To(Equal(database.ServiceArea{{square}}))
+	// service area is always returned as a multipolygon
+	Expect(provider.ServiceArea).To(MatchJSON(`{"type":"MultiPolygon","coordinates":[[[[10,10],[11,10],[11,11],[10,11],[10,10]]]]}`))
+
+	for _, area := range []string{`{"type":"Point","coordinates":[10,10]}`, `{"type":"Polygon","coordinates":[[[10,10],[11,10],[11,11]]]}`, `{"type":"Polygon","coordinates":[[[10,10],[11,10],[11,11],[10,11]]]}`, `[]`} {
+		req.ServiceArea = json.RawMessage(area)
+		_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+		Expect(status).To(Equal(http.StatusBadRequest), area)
+	}
+
+	stored := added
+	stored.ID = 12
+	db.GetProviderFunc = func(database.ID) (database.Provider, error) {
+		return stored, nil
+	}
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		stored = p
+		return nil
+	}
+	// absent service area is left untouched and null removes it
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"name": "p1"})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored.ServiceArea).To(Equal(database.ServiceArea{{square}}))
+	provider, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"service_area": nil})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored.ServiceArea).To(BeNil())
+	Expect(provider.ServiceArea).To(BeNil())
+}
+
+func TestProviderBranches(t *testing.T) {
+	north := database.Branch{Name: "north", Address: database.Address{Lat: 1, Long: 0}, Radius: 500, RadiusUnit: database.Metre}
+	initTest(t, []database.Provider{
+		{ID: 1, Name: "p1", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Branches: []database.Branch{north}, ClosestBranch: &north, Distance: 250},
+	})
+	providers, status := sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(providers).To(HaveLen(1))
+	// distance is in unit of the closest branch
+	Expect(providers[0].ClosestBranch).To(Equal(&handlers.Branch{Name: "north", Address: handlers.Address{Lat: 1, Long: 0}, OperatingRadius: 500, RadiusUnit: "m"}))
+	Expect(*providers[0].Distance).To(Equal(handlers.Distance{Value: 250, Unit: "m"}))
+
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	req := defaultProviderRequest
+	req.Branches = []handlers.Branch{{Name: "north", Address: handlers.Address{Lat: 1, Long: 1}, OperatingRadius: 20}}
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.Branches).To(Equal([]database.Branch{{Name: "north", Address: database.Address{Lat: 1, Long: 1}, Radius: 20, RadiusUnit: database.Kilometre}}))
+	Expect(provider.Branches).To(HaveLen(1))
+	Expect(provider.Branches[0].RadiusUnit).To(Equal("km"))
+
+	req.Branches = []handlers.Branch{{Name: "north", Address: handlers.Address{Lat: 1, Long: 1}}}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	stored := added
+	stored.ID = 12
+	db.GetProviderFunc = func(database.ID) (database.Provider, error) {
+		return stored, nil
+	}
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		stored = p
+		return nil
+	}
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"branches": []interface{}{}})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored.Branches).To(BeEmpty())
+}
+
+func TestProviderMaterialRadiusRating(t *testing.T) {
+	initTest(t, nil)
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	req := defaultProviderRequest
+	req.MaterialRadii = map[string]handlers.MaterialRadius{"wood": {OperatingRadius: 25}}
+	req.MaterialRatings = map[string]float64{"wood": 3.5}
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.MaterialRadii).To(Equal(map[database.FloorMaterial]database.MaterialRadius{database.FloorWood: {Radius: 25, RadiusUnit: database.Kilometre}}))
+	Expect(added.MaterialRatings).To(Equal(map[database.FloorMaterial]float64{database.FloorWood: 3.5}))
+	Expect(provider.MaterialRadii).To(Equal(map[string]handlers.MaterialRadius{"wood": {OperatingRadius: 25, RadiusUnit: "km"}}))
+	Expect(provider.MaterialRatings).To(Equal(req.MaterialRatings))
+
+	req.MaterialRadii = map[string]handlers.MaterialRadius{"wood": {OperatingRadius: 0}}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	req.MaterialRadii = nil
+	req.MaterialRatings = map[string]float64{"wood": 5.5}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	stored := added
+	stored.ID = 12
+	db.GetProviderFunc = func(database.ID) (database.Provider, error) {
+		return stored, nil
+	}
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		stored = p
+		return nil
+	}
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"material_ratings": map[string]float64{}})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored.MaterialRatings).To(BeEmpty())
+	Expect(stored.MaterialRadii).To(HaveLen(1))
+}
+
+func TestProviderAvailability(t *testing.T) {
+	today := database.Day(time.Now())
+	day := func(d int) time.Time {
+		return today.AddDate(0, 0, d)
+	}
+	date := func(d int) string {
+		return day(d).Format(database.DateLayout)
+	}
+	initTest(t, []database.Provider{
+		{ID: 1, Name: "any", Radius: 10, RadiusUnit: database.Kilometre, Rating: 5},
+		{ID: 2, Name: "later", Radius: 10, RadiusUnit: database.Kilometre, Rating: 4, Availability: []database.DateRange{{From: day(10), To: day(20)}}},
+		{ID: 3, Name: "booked", Radius: 10, RadiusUnit: database.Kilometre, Rating: 3, Blackouts: []database.DateRange{{From: day(0), To: day(30)}}},
+	})
+	names := func(req handlers.CustomerRequest) map[string]string {
+		providers, status := sendRequest(req)
+		Expect(status).To(Equal(http.StatusOK))
+		res := map[string]string{}
+		for _, p := range providers {
+			res[p.Name] = p.NextAvailableDate
+		}
+		return res
+	}
+	// without a desired start every provider is listed with its next free date from today
+	Expect(names(defaultRequest)).To(Equal(map[string]string{"any": date(0), "later": date(10), "booked": date(31)}))
+
+	req := defaultRequest
+	req.DesiredStartDate = date(7)
+	Expect(names(req)).To(Equal(map[string]string{"any": date(7)}))
+	req.FlexibilityDays = 3
+	Expect(names(req)).To(Equal(map[string]string{"any": date(4), "later": date(10)}))
+	req.DesiredStartDate, req.FlexibilityDays = date(40), 10
+	Expect(names(req)).To(Equal(map[string]string{"any": date(30), "booked": date(31)}))
+
+	// a desired start already out of reach or not a date is rejected
+	req.DesiredStartDate, req.FlexibilityDays = date(-5), 2
+	_, status := sendRequest(req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	req.DesiredStartDate = "next week"
+	_, status = sendRequest(req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	var added database.Provider
+	db.AddProviderFunc = func(p database.Provider) (database.ID, error) {
+		added = p
+		return 12, nil
+	}
+	providerReq := defaultProviderRequest
+	providerReq.Availability = []handlers.DateRange{{From: "2024-07-01", To: "2024-07-31"}}
+	providerReq.Blackouts = []handlers.DateRange{{From: "2024-07-14", To: "2024-07-14"}}
+	provider, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", providerReq)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.Availability).To(Equal([]database.DateRange{{From: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC)}}))
+	Expect(added.Blackouts).To(Equal([]database.DateRange{{From: time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC)}}))
+	Expect(provider.Availability).To(Equal(providerReq.Availability))
+	Expect(provider.Blackouts).To(Equal(providerReq.Blackouts))
+
+	providerReq.Blackouts = []handlers.DateRange{{From: "2024-07-14"}}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", providerReq)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	stored := added
+	stored.ID = 12
+	db.GetProviderFunc = func(database.ID) (database.Provider, error) {
+		return stored, nil
+	}
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		stored = p
+		return nil
+	}
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/12", map[string]interface{}{"blackouts": []interface{}{}})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored.Blackouts).To(BeEmpty())
+	Expect(stored.Availability).To(HaveLen(1))
+}
+
+func TestAddProviderInvalid(t *testing.T) {
+	initTest(t, nil)
+	req := defaultProviderRequest
+	req.Experience = []string{"wood", "marble"}
+	_, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	req = defaultProviderRequest
+	req.OperatingRadius = 0
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+
+	req = defaultProviderRequest
+	req.RadiusUnit = "ft"
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", req)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestAddProviderStorageErrors(t *testing.T) {
+	initTest(t, nil)
+	db.AddProviderFunc = func(database.Provider) (database.ID, error) {
+		return 0, database.ErrDuplicateEntry
+	}
+	_, status := sendProviderRequest(http.MethodPost, "/v1/admin/providers", defaultProviderRequest)
+	Expect(status).To(Equal(http.StatusConflict))
+
+	db.AddProviderFunc = func(database.Provider) (database.ID, error) {
+		return 0, database.ErrInvalid
+	}
+	_, status = sendProviderRequest(http.MethodPost, "/v1/admin/providers", defaultProviderRequest)
+	Expect(status).To(Equal(http.StatusUnprocessableEntity))
+}
+
+func TestStorageTimeout(t *testing.T) {
+	initTest(t, nil)
+	db.GetProvidersFunc = func(database.Criteria) ([]database.Provider, error) {
+		return nil, context.DeadlineExceeded
+	}
+	_, status := sendRequest(defaultRequest)
+	Expect(status).To(Equal(http.StatusGatewayTimeout))
+
+	db.GetProviderFunc = func(database.ID) (database.Provider, error) {
+		return database.Provider{}, context.Canceled
+	}
+	_, status = sendProviderRequest(http.MethodGet, "/v1/providers/3", nil)
+	Expect(status).To(Equal(http.StatusServiceUnavailable))
+}
+
+func TestGetProvider(t *testing.T) {
+	initTest(t, nil)
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		if id != 3 {
+			return database.Provider{}, database.ErrNotFound
+		}
+		return database.Provider{ID: 3, Name: "p3", Address: database.Address{Lat: -26, Long: 40}, Radius: 5, RadiusUnit: database.Kilometre, Rating: 3, Materials: []database.FloorMaterial{database.FloorCarpet}}, nil
+	}
+	provider, status := sendProviderRequest(http.MethodGet, "/v1/providers/3", nil)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(provider).To(Equal(handlers.Provider{
+		ID:              3,
+		Name:            "p3",
+		Experience:      []string{"carpet"},
+		Address:         handlers.Address{Lat: -26, Long: 40},
+		OperatingRadius: 5,
+		RadiusUnit:      "km",
+		Rating:          3,
+	}))
+
+	_, status = sendProviderRequest(http.MethodGet, "/v1/providers/4", nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	_, status = sendProviderRequest(http.MethodGet, "/v1/providers/abc", nil)
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestUpdateProvider(t *testing.T) {
+	initTest(t, nil)
+	var updated database.Provider
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		if p.ID != 7 {
+			return database.ErrNotFound
+		}
+		updated = p
+		return nil
+	}
+	// storage keeps status and rating of reviewed providers, response is the saved provider
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		saved := updated
+		saved.Status = database.StatusActive
+		saved.Rating = 3.9
+		saved.ReviewCount = 12
+		return saved, nil
+	}
+	req := defaultProviderRequest
+	req.Experience = []string{"carpet"}
+	provider, status := sendProviderRequest(http.MethodPut, "/v1/admin/providers/7", req)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(updated.ID).To(Equal(database.ID(7)))
+	Expect(updated.Materials).To(Equal([]database.FloorMaterial{database.FloorCarpet}))
+	Expect(provider.ID).To(Equal(database.ID(7)))
+	Expect(provider.Experience).To(Equal([]string{"carpet"}))
+	Expect(provider.Status).To(Equal("active"))
+	Expect(provider.Rating).To(Equal(3.9))
+	Expect(provider.ReviewCount).To(Equal(12))
+
+	_, status = sendProviderRequest(http.MethodPut, "/v1/admin/providers/8", req)
+	Expect(status).To(Equal(http.StatusNotFound))
+}
+
+func TestPatchProvider(t *testing.T) {
+	initTest(t, nil)
+	stored := database.Provider{ID: 5, Name: "p5", Address: database.Address{Lat: -26, Long: 40}, Radius: 5, RadiusUnit: database.Kilometre, Rating: 3, Materials: []database.FloorMaterial{database.FloorWood}}
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		if id != stored.ID {
+			return database.Provider{}, database.ErrNotFound
+		}
+		return stored, nil
+	}
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		stored = p
+		return nil
+	}
+	provider, status := sendProviderRequest(http.MethodPatch, "/v1/admin/providers/5", map[string]interface{}{"rating": 4.2, "experience": []string{"wood", "tile"}, "radius_unit": "m"})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(stored).To(Equal(database.Provider{ID: 5, Name: "p5", Address: database.Address{Lat: -26, Long: 40}, Radius: 5, RadiusUnit: database.Metre, Rating: 4.2, Materials: []database.FloorMaterial{database.FloorWood, database.FloorTile}}))
+	Expect(provider.Rating).To(Equal(4.2))
+
+	// rating of a reviewed provider is recomputed by storage and returned as saved
+	db.UpdateProviderFunc = func(p database.Provider) error {
+		p.Rating = 3.5
+		p.ReviewCount = 2
+		stored = p
+		return nil
+	}
+	provider, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/5", map[string]interface{}{"rating": 5})
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(provider.Rating).To(Equal(3.5))
+	Expect(provider.ReviewCount).To(Equal(2))
+
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/6", map[string]interface{}{"rating": 4.2})
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	_, status = sendProviderRequest(http.MethodPatch, "/v1/admin/providers/5", map[string]interface{}{"rating": 7})
+	Expect(status).To(Equal(http.StatusBadRequest))
+}
+
+func TestDeleteProvider(t *testing.T) {
+	initTest(t, nil)
+	db.DeleteProviderFunc = func(id database.ID) error {
+		if id != 2 {
+			return database.ErrNotFound
+		}
+		return nil
+	}
+	resp := execTokenRequest(testAdminToken, http.MethodDelete, "/v1/admin/providers/2", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	resp = execTokenRequest(testAdminToken, http.MethodDelete, "/v1/admin/providers/3", "")
+	Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+}
+
 func sendRequest(request handlers.CustomerRequest) ([]handlers.Provider, int) {
 	body, err := jsoniter.Marshal(request)
 	Expect(err).To(BeNil())
@@ -401,24 +1011,73 @@ func sendRequest(request handlers.CustomerRequest) ([]handlers.Provider, int) {
 	respBody, err := ioutil.ReadAll(resp.Body)
 	Expect(err).To(BeNil())
 	response := handlers.Response{
-		Data: &[]handlers.Provider{},
+		Data: &handlers.Matches{},
 	}
 
 	err = json.Unmarshal(respBody, &response)
 	Expect(err).To(BeNil())
 	err = resp.Body.Close()
 	Expect(err).To(BeNil())
-	providersInResponse := *response.Data.(*[]handlers.Provider)
+	providersInResponse := response.Data.(*handlers.Matches).Providers
 	return providersInResponse, resp.StatusCode
 }
 
+func sendProviderRequest(method string, path string, request interface{}) (handlers.Provider, int) {
+	body := ""
+	if request != nil {
+		b, err := json.Marshal(request)
+		Expect(err).To(BeNil())
+		body = string(b)
+	}
+	resp := execTokenRequest(testAdminToken, method, path, body)
+	if resp.StatusCode/100 != 2 {
+		return handlers.Provider{}, resp.StatusCode
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	response := handlers.Response{
+		Data: &handlers.Provider{},
+	}
+	err = json.Unmarshal(respBody, &response)
+	Expect(err).To(BeNil())
+	err = resp.Body.Close()
+	Expect(err).To(BeNil())
+	return *response.Data.(*handlers.Provider), resp.StatusCode
+}
+
+func sendDataRequest(method string, path string, body string, data interface{}) int {
+	return sendTokenDataRequest("", method, path, body, data)
+}
+
+// sendTokenDataRequest is sendDataRequest with a bearer token
+func sendTokenDataRequest(token string, method string, path string, body string, data interface{}) int {
+	resp := execTokenRequest(token, method, path, body)
+	respBody, err := ioutil.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	Expect(resp.Body.Close()).To(BeNil())
+	response := handlers.Response{
+		Data: data,
+	}
+	err = json.Unmarshal(respBody, &response)
+	Expect(err).To(BeNil())
+	return resp.StatusCode
+}
+
 func execRequest(method string, path string, body string) *http.Response {
+	return execTokenRequest("", method, path, body)
+}
+
+// execTokenRequest sends a request with a bearer token, no Authorization header is sent for an empty token
+func execTokenRequest(token string, method string, path string, body string) *http.Response {
 	url := generateURL(path)
 	reqBody := strings.NewReader(body)
 	req, err := http.NewRequest(method, url, reqBody)
 	Expect(err).To(BeNil())
 	req.Close = true
 	req.Header.Add("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
 	client := http.Client{Timeout: 0}
 	resp, err := client.Do(req)
 	Expect(err).To(BeNil())
@@ -441,29 +1100,59 @@ func initTest(t *testing.T, dbProviders []database.Provider) {
 		Area:        1000,
 		PhoneNumber: "1-800-234673",
 	}
-	db.GetProvidersFunc = func(database.FloorMaterial, database.Address) ([]database.Provider, error) {
+	defaultProviderRequest = handlers.ProviderRequest{
+		Name:            "p0",
+		Experience:      []string{"wood", "tile"},
+		Address:         handlers.Address{Lat: -26.66119, Long: 40.95858},
+		OperatingRadius: 10,
+		Rating:          4.5,
+	}
+	db.GetProvidersFunc = func(database.Criteria) ([]database.Provider, error) {
 		return dbProviders, nil
 	}
+	db.GetMaterialsFunc = func() ([]database.Material, error) {
+		return []database.Material{
+			{ID: 1, Name: database.FloorWood},
+			{ID: 2, Name: database.FloorCarpet},
+			{ID: 3, Name: database.FloorTile},
+		}, nil
+	}
+	db.AddLeadFunc = func(database.Lead) (database.ID, error) {
+		return 1, nil
+	}
+	db.PingFunc = func() error {
+		return nil
+	}
+	db.PoolStatsFunc = func() database.PoolStats {
+		return database.PoolStats{MaxOpen: 20, Open: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDuration: time.Millisecond}
+	}
 }
 
 func convertFromDBProviders(dbProviders []database.Provider) []handlers.Provider {
 	res := []handlers.Provider{}
 	for _, dbProvider := range dbProviders {
 		provider := handlers.Provider{
+			ID:              dbProvider.ID,
 			Name:            dbProvider.Name,
 			Experience:      nil,
 			Address:         handlers.Address{Lat: dbProvider.Address.Lat, Long: dbProvider.Address.Long},
 			OperatingRadius: dbProvider.Radius,
+			RadiusUnit:      string(dbProvider.RadiusUnit),
 			Rating:          dbProvider.Rating,
+			ReviewCount:     dbProvider.ReviewCount,
 		}
-		if dbProvider.Wood {
-			provider.Experience = append(provider.Experience, "wood")
+		for _, material := range dbProvider.Materials {
+			provider.Experience = append(provider.Experience, string(material))
 		}
-		if dbProvider.Carpet {
-			provider.Experience = append(provider.Experience, "carpet")
+		score := defaultRanking.Score(dbProvider, database.FloorMaterial(defaultRequest.Material))
+		provider.RankingScore = &score
+		unit := dbProvider.RadiusUnit
+		if unit == "" {
+			unit = handlers.DefaultRadiusUnit
 		}
-		if dbProvider.Tile {
-			provider.Experience = append(provider.Experience, "tile")
+		provider.Distance = &handlers.Distance{Value: unit.FromMeters(dbProvider.Distance), Unit: string(unit)}
+		if next, ok := dbProvider.NextAvailableDay(time.Now()); ok {
+			provider.NextAvailableDate = next.Format(database.DateLayout)
 		}
 		res = append(res, provider)
 	}
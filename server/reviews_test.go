@@ -0,0 +1,88 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAddReview(t *testing.T) {
+	initTest(t, nil)
+	db.GetLeadFunc = func(id database.ID) (database.Lead, error) {
+		if id != 7 {
+			return database.Lead{}, database.ErrNotFound
+		}
+		return database.Lead{ID: 7, ProviderIDs: []database.ID{5, 6, 8}, Status: database.LeadAccepted,
+			Offers: []database.LeadOffer{{ProviderID: 5, Status: database.OfferAccepted}, {ProviderID: 6, Status: database.OfferWithdrawn}}}, nil
+	}
+	var added database.Review
+	db.AddReviewFunc = func(r database.Review) (database.ID, error) {
+		added = r
+		return 3, nil
+	}
+	var review handlers.Review
+	status := sendTokenDataRequest(leadToken(7), http.MethodPost, "/v1/providers/5/reviews", `{"score":4,"text":"good"}`, &review)
+	Expect(status).To(Equal(http.StatusCreated))
+	Expect(added.ProviderID).To(Equal(database.ID(5)))
+	Expect(added.LeadID).To(Equal(database.ID(7)))
+	Expect(added.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	Expect(review).To(Equal(handlers.Review{ID: 3, ProviderID: 5, Score: 4, Text: "good", LeadID: 7, CreatedAt: added.CreatedAt}))
+
+	for _, body := range []string{`{"score":0}`, `{"score":6}`, `{"text":"no score"}`} {
+		status = sendTokenDataRequest(leadToken(7), http.MethodPost, "/v1/providers/5/reviews", body, nil)
+		Expect(status).To(Equal(http.StatusBadRequest), body)
+	}
+
+	// reviews are added only by customer of a lead the provider accepted
+	for _, token := range []string{"", providerToken(5), leadToken(7) + "0"} {
+		status = sendTokenDataRequest(token, http.MethodPost, "/v1/providers/5/reviews", `{"score":5}`, nil)
+		Expect(status).To(Equal(http.StatusUnauthorized), token)
+	}
+	for _, provider := range []string{"6", "8", "9"} {
+		status = sendTokenDataRequest(leadToken(7), http.MethodPost, "/v1/providers/"+provider+"/reviews", `{"score":5}`, nil)
+		Expect(status).To(Equal(http.StatusForbidden), provider)
+	}
+	status = sendTokenDataRequest(leadToken(8), http.MethodPost, "/v1/providers/5/reviews", `{"score":5}`, nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	db.AddReviewFunc = func(database.Review) (database.ID, error) {
+		return 0, database.ErrDuplicateEntry
+	}
+	status = sendTokenDataRequest(leadToken(7), http.MethodPost, "/v1/providers/5/reviews", `{"score":4}`, nil)
+	Expect(status).To(Equal(http.StatusConflict))
+	db.AddReviewFunc = func(database.Review) (database.ID, error) {
+		return 0, database.ErrNotFound
+	}
+	status = sendTokenDataRequest(leadToken(7), http.MethodPost, "/v1/providers/5/reviews", `{"score":4}`, nil)
+	Expect(status).To(Equal(http.StatusNotFound))
+}
+
+func TestGetReviews(t *testing.T) {
+	initTest(t, nil)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var requested database.Page
+	db.GetReviewsFunc = func(providerID database.ID, page database.Page) ([]database.Review, int, error) {
+		requested = page
+		return []database.Review{{ID: 2, ProviderID: providerID, Score: 5, CreatedAt: created}}, 11, nil
+	}
+	var reviews handlers.Reviews
+	status := sendDataRequest(http.MethodGet, "/v1/providers/5/reviews", "", &reviews)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(requested).To(Equal(database.Page{Limit: handlers.DefaultPageLimit}))
+	Expect(reviews).To(Equal(handlers.Reviews{
+		Total:   11,
+		Reviews: []handlers.Review{{ID: 2, ProviderID: 5, Score: 5, CreatedAt: created}},
+	}))
+
+	status = sendDataRequest(http.MethodGet, "/v1/providers/5/reviews?limit=5&offset=10", "", &reviews)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(requested).To(Equal(database.Page{Limit: 5, Offset: 10}))
+
+	for _, query := range []string{"?limit=101", "?offset=-1", "?limit=x"} {
+		status = sendDataRequest(http.MethodGet, "/v1/providers/5/reviews"+query, "", nil)
+		Expect(status).To(Equal(http.StatusBadRequest), query)
+	}
+}
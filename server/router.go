@@ -1,14 +1,16 @@
 package server
 
 import (
+	"ah/database"
 	"ah/logger"
 	"ah/server/handlers"
+	"expvar"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
 )
 
-func newRouter(accessLogger *zap.Logger, storage interface{}) *gin.Engine {
+func newRouter(accessLogger *zap.Logger, storage interface{}, config Config) *gin.Engine {
 	handleRecovery := func(c *gin.Context, err interface{}) {
 		handlers.ErrorResponse(c, http.StatusInternalServerError, err.(string), nil)
 		c.Abort()
@@ -36,7 +38,47 @@ func newRouter(accessLogger *zap.Logger, storage interface{}) *gin.Engine {
 	})
 	router.Use(func(ctx *gin.Context) {
 		ctx.Set("db", storage)
+		ctx.Set("ranking", config.ranking())
+		ctx.Set("routing", config.routing())
+		ctx.Set("scheduling", config.scheduling())
+		ctx.Set("signer", config.signer())
 	})
 	router.POST("get_providers", handlers.GetProviders)
+	router.GET("health", handlers.GetHealth)
+	// expvar publishes command line and memory stats besides pool usage, so it is for admins only
+	router.GET("debug/vars", handlers.AdminAuth(config.AdminToken), gin.WrapH(expvar.Handler()))
+
+	v1 := router.Group("/v1")
+	v1.GET("materials", handlers.GetMaterials)
+	v1.GET("providers/export", handlers.AdminAuth(config.AdminToken), handlers.ExportProviders)
+	v1.GET("providers/:id", handlers.GetProvider)
+	v1.POST("providers/:id/reviews", handlers.LeadAuth, handlers.AddReview)
+	v1.GET("providers/:id/reviews", handlers.GetReviews)
+	v1.POST("providers/:id/status", handlers.ProviderAuth, handlers.OwnProvider, handlers.ChangeStatus(database.ActorProvider))
+	v1.GET("providers/:id/slots", handlers.GetSlots)
+	v1.GET("providers/:id/appointments", handlers.ProviderAuth, handlers.OwnProvider, handlers.GetProviderAppointments)
+	v1.GET("leads", handlers.ProviderAuth, handlers.GetOfferedLeads)
+	v1.GET("leads/:id", handlers.ProviderAuth, handlers.GetOfferedLead)
+	v1.POST("leads/:id/accept", handlers.ProviderAuth, handlers.LeadAction(database.ActionAccept))
+	v1.POST("leads/:id/decline", handlers.ProviderAuth, handlers.LeadAction(database.ActionDecline))
+	v1.POST("leads/:id/contact", handlers.ProviderAuth, handlers.LeadAction(database.ActionContact))
+	v1.POST("leads/:id/win", handlers.ProviderAuth, handlers.LeadAction(database.ActionWin))
+	v1.POST("leads/:id/lose", handlers.ProviderAuth, handlers.LeadAction(database.ActionLose))
+	v1.POST("appointments", handlers.LeadAuth, handlers.BookAppointment)
+	v1.GET("appointments/:id", handlers.LeadAuth, handlers.GetAppointment)
+	v1.GET("appointments/:id/ics", handlers.GetAppointmentCalendar)
+	v1.POST("appointments/:id/reschedule", handlers.LeadAuth, handlers.RescheduleAppointment)
+	v1.POST("appointments/:id/cancel", handlers.LeadAuth, handlers.CancelAppointment)
+
+	admin := v1.Group("/admin", handlers.AdminAuth(config.AdminToken))
+	admin.POST("providers", handlers.AddProvider)
+	admin.PUT("providers/:id", handlers.UpdateProvider)
+	admin.PATCH("providers/:id", handlers.PatchProvider)
+	admin.DELETE("providers/:id", handlers.DeleteProvider)
+	admin.POST("providers/import", handlers.ImportProviders)
+	admin.GET("leads/:id", handlers.GetLead)
+	admin.POST("providers/:id/token", handlers.IssueProviderToken)
+	admin.POST("providers/:id/status", handlers.ChangeStatus(database.ActorAdmin))
+	admin.GET("providers/:id/status_history", handlers.GetStatusHistory)
 	return router
 }
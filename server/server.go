@@ -1,6 +1,8 @@
 package server
 
 import (
+	"ah/server/handlers"
+	"expvar"
 	"go.uber.org/zap"
 	"net/http"
 	"time"
@@ -24,7 +26,21 @@ func NewServer(accessLogger *zap.Logger, storage interface{}) (*Server, error) {
 		return nil, err
 	}
 
-	router := newRouter(accessLogger, storage)
+	err = config.ranking().Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = config.routing().Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = config.scheduling().Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	router := newRouter(accessLogger, storage, config)
+	publishMetrics(storage)
 
 	server := &http.Server{
 		Addr:           config.ListenAddress,
@@ -41,6 +57,17 @@ func NewServer(accessLogger *zap.Logger, storage interface{}) (*Server, error) {
 	}, nil
 }
 
+// publishMetrics exposes storage metrics through expvar, served on /debug/vars
+func publishMetrics(storage interface{}) {
+	checker, ok := storage.(handlers.HealthChecker)
+	if !ok || expvar.Get("database_pool") != nil {
+		return
+	}
+	expvar.Publish("database_pool", expvar.Func(func() interface{} {
+		return checker.PoolStats()
+	}))
+}
+
 // ListenAndServe listens and serves a server
 func (s *Server) ListenAndServe() error {
 	return s.httpServer.ListenAndServe()
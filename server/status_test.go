@@ -0,0 +1,92 @@
+package server
+
+import (
+	"ah/database"
+	"ah/server/handlers"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChangeStatus(t *testing.T) {
+	initTest(t, nil)
+	var changed database.StatusChange
+	db.ChangeProviderStatusFunc = func(change database.StatusChange) (database.Provider, error) {
+		changed = change
+		return database.Provider{ID: change.ProviderID, Name: "p1", Status: change.To, ResumeAt: change.ResumeAt}, nil
+	}
+	resumeAt := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(time.Second)
+	var provider handlers.Provider
+	status := sendTokenDataRequest(providerToken(5), http.MethodPost, "/v1/providers/5/status", `{"status":"paused","reason":"holidays","resume_at":"`+resumeAt.Format(time.RFC3339)+`"}`, &provider)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(changed.ProviderID).To(Equal(database.ID(5)))
+	Expect(changed.To).To(Equal(database.StatusPaused))
+	Expect(changed.Actor).To(Equal(database.ActorProvider))
+	Expect(changed.Reason).To(Equal("holidays"))
+	Expect(changed.ResumeAt).To(Equal(resumeAt))
+	Expect(changed.ChangedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	Expect(provider.Status).To(Equal("paused"))
+	Expect(*provider.ResumeAt).To(Equal(resumeAt))
+
+	// admin changes are audited as admin ones
+	provider = handlers.Provider{}
+	status = sendTokenDataRequest(testAdminToken, http.MethodPost, "/v1/admin/providers/5/status", `{"status":"suspended","reason":"fraud"}`, &provider)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(changed.Actor).To(Equal(database.ActorAdmin))
+	Expect(changed.ResumeAt.IsZero()).To(BeTrue())
+	Expect(provider.Status).To(Equal("suspended"))
+	Expect(provider.ResumeAt).To(BeNil())
+
+	for _, body := range []string{`{}`, `{"status":"pending"}`, `{"status":"active","resume_at":"tomorrow"}`} {
+		status = sendTokenDataRequest(providerToken(5), http.MethodPost, "/v1/providers/5/status", body, nil)
+		Expect(status).To(Equal(http.StatusBadRequest), body)
+	}
+
+	db.ChangeProviderStatusFunc = func(database.StatusChange) (database.Provider, error) {
+		return database.Provider{}, database.ErrConflict
+	}
+	status = sendTokenDataRequest(providerToken(5), http.MethodPost, "/v1/providers/5/status", `{"status":"active"}`, nil)
+	Expect(status).To(Equal(http.StatusConflict))
+
+	// providers change only their own status
+	changed = database.StatusChange{}
+	status = sendDataRequest(http.MethodPost, "/v1/providers/5/status", `{"status":"active"}`, nil)
+	Expect(status).To(Equal(http.StatusUnauthorized))
+	status = sendTokenDataRequest(providerToken(6), http.MethodPost, "/v1/providers/5/status", `{"status":"active"}`, nil)
+	Expect(status).To(Equal(http.StatusForbidden))
+	status = sendTokenDataRequest(providerToken(6), http.MethodPost, "/v1/providers/x/status", `{"status":"active"}`, nil)
+	Expect(status).To(Equal(http.StatusBadRequest))
+	Expect(changed).To(Equal(database.StatusChange{}))
+}
+
+func TestGetStatusHistory(t *testing.T) {
+	initTest(t, nil)
+	changedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var requested database.Page
+	db.GetStatusHistoryFunc = func(providerID database.ID, page database.Page) ([]database.StatusChange, int, error) {
+		requested = page
+		return []database.StatusChange{{ID: 2, ProviderID: providerID, From: database.StatusPending, To: database.StatusActive, Actor: database.ActorAdmin, Reason: "verified", ChangedAt: changedAt}}, 3, nil
+	}
+	var history handlers.StatusHistory
+	status := sendTokenDataRequest(testAdminToken, http.MethodGet, "/v1/admin/providers/5/status_history?limit=1", "", &history)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(requested).To(Equal(database.Page{Limit: 1}))
+	Expect(history).To(Equal(handlers.StatusHistory{
+		Total:   3,
+		Changes: []handlers.StatusChange{{ID: 2, ProviderID: 5, From: "pending", To: "active", Actor: "admin", Reason: "verified", ChangedAt: changedAt}},
+	}))
+}
+
+func TestProviderResumed(t *testing.T) {
+	initTest(t, nil)
+	// a provider past its resume time is shown active
+	db.GetProviderFunc = func(id database.ID) (database.Provider, error) {
+		return database.Provider{ID: id, Name: "p1", Status: database.StatusPaused, ResumeAt: time.Now().Add(-time.Hour)}, nil
+	}
+	var provider handlers.Provider
+	status := sendDataRequest(http.MethodGet, "/v1/providers/5", "", &provider)
+	Expect(status).To(Equal(http.StatusOK))
+	Expect(provider.Status).To(Equal("active"))
+	Expect(provider.ResumeAt).To(BeNil())
+}